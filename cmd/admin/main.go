@@ -3,13 +3,21 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
+	"strings"
+	"time"
 
+	"github.com/dgrijalva/jwt-go"
+	"github.com/ivorscott/devpie-client-backend-go/internal/identity"
+	"github.com/ivorscott/devpie-client-backend-go/internal/mid"
+	"github.com/ivorscott/devpie-client-backend-go/internal/platform/auth"
 	"github.com/ivorscott/devpie-client-backend-go/internal/platform/conf"
 	"github.com/ivorscott/devpie-client-backend-go/internal/platform/database"
 	"github.com/ivorscott/devpie-client-backend-go/internal/schema"
+	"github.com/ivorscott/devpie-client-backend-go/internal/user"
 	"github.com/pkg/errors"
 )
 
@@ -26,6 +34,13 @@ func run() error {
 	// Configuration
 
 	var cfg struct {
+		Web struct {
+			Address      string        `conf:"default:localhost:4000"`
+			AuthDomain   string        `conf:"default:none,noprint"`
+			AuthAudience string        `conf:"default:none,noprint"`
+			AuthDevKey   string        `conf:"default:dev-auth.pem,noprint"`
+			AuthDevTTL   time.Duration `conf:"default:24h"`
+		}
 		DB struct {
 			User       string `conf:"default:postgres"`
 			Password   string `conf:"default:postgres,noprint"`
@@ -83,8 +98,59 @@ func run() error {
 		}
 		fmt.Println("Seed data complete")
 		return nil
+
+	case "token":
+
+		if cfg.Args.Num(1) == "" {
+			return errors.New("hint: token <subject> [user_id]")
+		}
+
+		if err := token(repo, cfg.Web.AuthDevKey, cfg.Web.AuthDomain, cfg.Web.Address, cfg.Web.AuthAudience,
+			cfg.Args.Num(1), cfg.Args.Num(2), cfg.Web.AuthDevTTL); err != nil {
+			return errors.Wrap(err, "minting token")
+		}
+		return nil
+	}
+
+	fmt.Println("commands: migrate|seed <filename>|token <subject> [user_id]")
+	return nil
+}
+
+// token prints an access token of the dev auth mode for the subject sub. The
+// token holds the id of the user created for sub unless uid is given.
+func token(repo *database.Repository, keyFile, domain, address, audience, sub, uid string, ttl time.Duration) error {
+	key, err := auth.LoadKey(keyFile)
+	if err != nil {
+		return err
+	}
+
+	// Match the issuer of the API, which uses its address without a domain.
+	if domain == "none" {
+		domain = address
+	}
+	issuer := auth.NewIssuer(key, identity.Auth0Config{Domain: domain}.Issuer(), audience)
+
+	if uid == "" {
+		us, err := user.RetrieveMeBySubject(context.Background(), repo, sub)
+		switch err {
+		case nil:
+			uid = us.ID
+		case user.ErrNotFound:
+		default:
+			return errors.Wrapf(err, "looking for user %q", sub)
+		}
+	}
+
+	claims := jwt.MapClaims{"sub": sub, "scope": strings.Join(mid.Scopes, " ")}
+	if uid != "" {
+		claims[identity.ClaimUserID] = uid
+	}
+
+	s, err := issuer.Sign(claims, ttl)
+	if err != nil {
+		return err
 	}
 
-	fmt.Println("commands: migrate|seed <filename>")
+	fmt.Println(s)
 	return nil
 }
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"context"
+	"github.com/ivorscott/devpie-client-backend-go/internal/mid"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/ivorscott/devpie-client-backend-go/internal/activity"
+	"github.com/ivorscott/devpie-client-backend-go/internal/platform/database"
+	"github.com/ivorscott/devpie-client-backend-go/internal/platform/web"
+	"github.com/pkg/errors"
+)
+
+// Activity holds the application state needed by the handler methods.
+type Activity struct {
+	repo *database.Repository
+	log  *log.Logger
+	auth *mid.Auth
+}
+
+// List gets a page of the activity of a project, newest first.
+func (a *Activity) List(w http.ResponseWriter, r *http.Request) error {
+	pid := chi.URLParam(r, "pid")
+
+	pr, err := web.ParsePage(r)
+	if err != nil {
+		return err
+	}
+
+	list, err := activity.List(r.Context(), a.repo, pid, database.Page{Limit: pr.Limit + 1, After: pr.After})
+	if err != nil {
+		switch err {
+		case database.ErrInvalidPage:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return errors.Wrapf(err, "listing activity of project %q", pid)
+		}
+	}
+
+	return web.Respond(r.Context(), w, web.NewPage(pr, list, func(i int) []interface{} { return list[i].Key() }), http.StatusOK)
+}
+
+// ListTask gets a page of the history of a task, newest first.
+func (a *Activity) ListTask(w http.ResponseWriter, r *http.Request) error {
+	pid := chi.URLParam(r, "pid")
+	tid := chi.URLParam(r, "tid")
+
+	pr, err := web.ParsePage(r)
+	if err != nil {
+		return err
+	}
+
+	list, err := activity.ListTask(r.Context(), a.repo, pid, tid, database.Page{Limit: pr.Limit + 1, After: pr.After})
+	if err != nil {
+		switch err {
+		case activity.ErrInvalidID, database.ErrInvalidPage:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return errors.Wrapf(err, "listing activity of task %q", tid)
+		}
+	}
+
+	return web.Respond(r.Context(), w, web.NewPage(pr, list, func(i int) []interface{} { return list[i].Key() }), http.StatusOK)
+}
+
+// record adds a change made by the caller to the activity log. ctx is the
+// unit of work of the change, the change and its event are committed together
+// or not at all.
+func record(ctx context.Context, r *http.Request, rec activity.Recorder, a *mid.Auth, ne activity.NewEvent) error {
+	ne.ActorID = a.GetUserById(r)
+
+	if _, err := rec.Record(ctx, ne, time.Now()); err != nil {
+		return errors.Wrapf(err, "recording %s activity", ne.Action)
+	}
+	return nil
+}
@@ -7,23 +7,29 @@ import (
 	"time"
 
 	"github.com/go-chi/chi"
+	"github.com/ivorscott/devpie-client-backend-go/internal/activity"
 	"github.com/ivorscott/devpie-client-backend-go/internal/column"
 	"github.com/ivorscott/devpie-client-backend-go/internal/platform/database"
 	"github.com/ivorscott/devpie-client-backend-go/internal/platform/web"
+	"github.com/ivorscott/devpie-client-backend-go/internal/project"
 	"github.com/pkg/errors"
 )
 
 // Columns holds the application state needed by the handler methods.
 type Columns struct {
-	repo *database.Repository
-	log  *log.Logger
-	auth0 *mid.Auth0
+	unit     database.Unit
+	columns  column.Store
+	projects project.Store
+	activity activity.Recorder
+	log      *log.Logger
+	auth     *mid.Auth
 }
 
 // List gets all column
 func (c *Columns) List(w http.ResponseWriter, r *http.Request) error {
 	pid := chi.URLParam(r, "pid")
-	list, err := column.List(r.Context(), c.repo, pid)
+
+	list, err := c.columns.List(r.Context(), pid)
 	if err != nil {
 		return err
 	}
@@ -34,9 +40,9 @@ func (c *Columns) List(w http.ResponseWriter, r *http.Request) error {
 // Retrieve a single Column
 func (c *Columns) Retrieve(w http.ResponseWriter, r *http.Request) error {
 	pid := chi.URLParam(r, "pid")
-	id := chi.URLParam(r, "id")
+	cid := chi.URLParam(r, "cid")
 
-	col, err := column.Retrieve(r.Context(), c.repo, id, pid)
+	col, err := c.columns.Retrieve(r.Context(), pid, cid)
 	if err != nil {
 		switch err {
 		case column.ErrNotFound:
@@ -44,7 +50,7 @@ func (c *Columns) Retrieve(w http.ResponseWriter, r *http.Request) error {
 		case column.ErrInvalidID:
 			return web.NewRequestError(err, http.StatusBadRequest)
 		default:
-			return errors.Wrapf(err, "looking for columns %q", id)
+			return errors.Wrapf(err, "looking for columns %q", cid)
 		}
 	}
 
@@ -53,16 +59,42 @@ func (c *Columns) Retrieve(w http.ResponseWriter, r *http.Request) error {
 
 // Create a new Column
 func (c *Columns) Create(w http.ResponseWriter, r *http.Request) error {
+	pid := chi.URLParam(r, "pid")
+
 	var nc column.NewColumn
 	if err := web.Decode(r, &nc); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
 		return err
 	}
+	nc.ProjectID = pid
 
-	col, err := column.Create(r.Context(), c.repo, nc, time.Now())
+	ctx, err := c.unit.Begin(r.Context())
 	if err != nil {
 		return err
 	}
+	defer c.unit.Rollback(ctx)
+
+	col, err := c.columns.Create(ctx, nc, time.Now())
+	if err != nil {
+		switch err {
+		case column.ErrInvalidID:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return errors.Wrapf(err, "creating column %v", nc)
+		}
+	}
+
+	if err := record(ctx, r, c.activity, c.auth, activity.NewEvent{
+		ProjectID: pid,
+		ColumnID:  &col.ID,
+		Action:    activity.ColumnCreated,
+		After:     col,
+	}); err != nil {
+		return err
+	}
+
+	if err := c.unit.Commit(ctx); err != nil {
+		return errors.Wrapf(err, "creating column %v", nc)
+	}
 
 	return web.Respond(r.Context(), w, col, http.StatusCreated)
 }
@@ -71,39 +103,162 @@ func (c *Columns) Create(w http.ResponseWriter, r *http.Request) error {
 // of the column is part of the request URL.
 func (c *Columns) Update(w http.ResponseWriter, r *http.Request) error {
 	pid := chi.URLParam(r, "pid")
-	id := chi.URLParam(r, "id")
+	cid := chi.URLParam(r, "cid")
 
 	var update column.UpdateColumn
 	if err := web.Decode(r, &update); err != nil {
 		return errors.Wrap(err, "decoding column update")
 	}
 
-	if err := column.Update(r.Context(), c.repo, pid, id, update); err != nil {
+	versions, err := web.IfMatch(r)
+	if err != nil {
+		return err
+	}
+
+	ctx, err := c.unit.Begin(r.Context())
+	if err != nil {
+		return err
+	}
+	defer c.unit.Rollback(ctx)
+
+	before, err := c.columns.Update(ctx, pid, cid, update, versions)
+	if err != nil {
 		switch err {
 		case column.ErrNotFound:
 			return web.NewRequestError(err, http.StatusNotFound)
 		case column.ErrInvalidID:
 			return web.NewRequestError(err, http.StatusBadRequest)
+		case column.ErrConflict:
+			return web.NewRequestError(err, http.StatusPreconditionFailed)
 		default:
-			return errors.Wrapf(err, "updating column %q", id)
+			return errors.Wrapf(err, "updating column %q", cid)
 		}
 	}
 
+	after, err := c.columns.Retrieve(ctx, pid, cid)
+	if err != nil {
+		return errors.Wrapf(err, "looking for updated column %q", cid)
+	}
+
+	if err := record(ctx, r, c.activity, c.auth, activity.NewEvent{
+		ProjectID: pid,
+		ColumnID:  &after.ID,
+		Action:    activity.ColumnUpdated,
+		Before:    before,
+		After:     after,
+	}); err != nil {
+		return err
+	}
+
+	if err := c.unit.Commit(ctx); err != nil {
+		return errors.Wrapf(err, "updating column %q", cid)
+	}
+
 	return web.Respond(r.Context(), w, nil, http.StatusNoContent)
 }
 
 // Delete removes a single column identified by an ID in the request URL.
+// A column with tasks requires a policy: ?tasks=delete moves them to the trash
+// and ?tasks=move&to={cid} appends them to another column. The only column of
+// a project can't be deleted.
 func (c *Columns) Delete(w http.ResponseWriter, r *http.Request) error {
-	id := chi.URLParam(r, "id")
+	pid := chi.URLParam(r, "pid")
+	cid := chi.URLParam(r, "cid")
+
+	dc := column.DeleteColumn{
+		Tasks: r.URL.Query().Get("tasks"),
+		To:    r.URL.Query().Get("to"),
+	}
+
+	versions, err := web.IfMatch(r)
+	if err != nil {
+		return err
+	}
+
+	ctx, err := c.unit.Begin(r.Context())
+	if err != nil {
+		return err
+	}
+	defer c.unit.Rollback(ctx)
 
-	if err := column.Delete(r.Context(), c.repo, id); err != nil {
+	before, err := c.columns.Delete(ctx, pid, cid, dc, versions, time.Now())
+	if err != nil {
 		switch err {
-		case column.ErrInvalidID:
+		case column.ErrNotFound:
+			return web.NewRequestError(err, http.StatusNotFound)
+		case column.ErrInvalidID, column.ErrInvalidPolicy, column.ErrInvalidTarget, column.ErrLastColumn:
 			return web.NewRequestError(err, http.StatusBadRequest)
+		case column.ErrNotEmpty:
+			return web.NewRequestError(err, http.StatusConflict)
+		case column.ErrConflict:
+			return web.NewRequestError(err, http.StatusPreconditionFailed)
 		default:
-			return errors.Wrapf(err, "deleting column %q", id)
+			return errors.Wrapf(err, "deleting column %q", cid)
 		}
 	}
 
+	if err := record(ctx, r, c.activity, c.auth, activity.NewEvent{
+		ProjectID: pid,
+		ColumnID:  &before.ID,
+		Action:    activity.ColumnDeleted,
+		Before:    before,
+	}); err != nil {
+		return err
+	}
+
+	if err := c.unit.Commit(ctx); err != nil {
+		return errors.Wrapf(err, "deleting column %q", cid)
+	}
+
+	return web.Respond(r.Context(), w, nil, http.StatusNoContent)
+}
+
+// Reorder replaces the column order of the project in the request URL.
+func (c *Columns) Reorder(w http.ResponseWriter, r *http.Request) error {
+	pid := chi.URLParam(r, "pid")
+
+	var co project.NewColumnOrder
+	if err := web.Decode(r, &co); err != nil {
+		return err
+	}
+
+	versions, err := web.IfMatch(r)
+	if err != nil {
+		return err
+	}
+
+	ctx, err := c.unit.Begin(r.Context())
+	if err != nil {
+		return err
+	}
+	defer c.unit.Rollback(ctx)
+
+	before, err := c.projects.ReorderColumns(ctx, pid, co.ColumnOrder, versions)
+	if err != nil {
+		switch err {
+		case project.ErrNotFound:
+			return web.NewRequestError(err, http.StatusNotFound)
+		case project.ErrInvalidID, project.ErrInvalidColumnOrder:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		case project.ErrConflict:
+			return web.NewRequestError(err, http.StatusPreconditionFailed)
+		default:
+			return errors.Wrapf(err, "reordering columns of project %q", pid)
+		}
+	}
+
+	if err := record(ctx, r, c.activity, c.auth, activity.NewEvent{
+		ProjectID: pid,
+		Action:    activity.ColumnsReordered,
+		Before:    project.NewColumnOrder{ColumnOrder: before.ColumnOrder},
+		After:     project.NewColumnOrder{ColumnOrder: co.ColumnOrder},
+	}); err != nil {
+		return err
+	}
+
+	if err := c.unit.Commit(ctx); err != nil {
+		return errors.Wrapf(err, "reordering columns of project %q", pid)
+	}
+
 	return web.Respond(r.Context(), w, nil, http.StatusNoContent)
 }
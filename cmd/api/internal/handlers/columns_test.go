@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/ivorscott/devpie-client-backend-go/internal/activity"
+	"github.com/ivorscott/devpie-client-backend-go/internal/member"
+	"github.com/ivorscott/devpie-client-backend-go/internal/project"
+)
+
+func (s *stores) columnHandlers() *Columns {
+	return &Columns{unit: noUnit{}, columns: s.columns, projects: s.projects, activity: s.activity, log: s.log, auth: s.auth}
+}
+
+func TestColumnsDelete(t *testing.T) {
+	s := newStores()
+	h := s.columnHandlers()
+	p, cs := s.board(t, "To Do", "Done")
+	tk := s.addTasks(t, cs[0], "unfinished")[0]
+
+	rt := route{http.MethodDelete, "/projects/{pid}/columns/{cid}", h.Delete, member.RoleEditor}
+	target := "/projects/" + p.ID + "/columns/" + cs[0].ID
+
+	tests := []struct {
+		name   string
+		query  string
+		status int
+	}{
+		{"without policy", "", http.StatusConflict},
+		{"unknown policy", "?tasks=keep", http.StatusBadRequest},
+		{"move to itself", "?tasks=move&to=" + cs[0].ID, http.StatusBadRequest},
+		{"move", "?tasks=move&to=" + cs[1].ID, http.StatusNoContent},
+		{"again", "", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		rec := s.serve(t, rt, testUser, target+tt.query, nil, nil)
+		if rec.Code != tt.status {
+			t.Fatalf("%s: got %d, want %d: %s", tt.name, rec.Code, tt.status, rec.Body)
+		}
+	}
+
+	got, err := s.tasks.Retrieve(context.Background(), p.ID, tk.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ColumnID != cs[1].ID {
+		t.Errorf("task column: got %s, want %s", got.ColumnID, cs[1].ID)
+	}
+	if a := s.lastAction(); a != activity.ColumnDeleted {
+		t.Errorf("activity: got %q, want %q", a, activity.ColumnDeleted)
+	}
+}
+
+func TestColumnsDeleteTasks(t *testing.T) {
+	s := newStores()
+	h := s.columnHandlers()
+	p, cs := s.board(t, "To Do", "Done")
+	ts := s.addTasks(t, cs[0], "first", "second")
+	s.addTasks(t, cs[1], "done")
+
+	rt := route{http.MethodDelete, "/projects/{pid}/columns/{cid}", h.Delete, member.RoleEditor}
+
+	rec := s.serve(t, rt, testUser, "/projects/"+p.ID+"/columns/"+cs[0].ID+"?tasks=delete", nil, nil)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("delete: got %d, want %d: %s", rec.Code, http.StatusNoContent, rec.Body)
+	}
+
+	// The tasks are in the trash, to be restored to the remaining column.
+	trash, err := s.tasks.ListTrash(context.Background(), p.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trash) != len(ts) {
+		t.Fatalf("trash: got %d tasks, want %d", len(trash), len(ts))
+	}
+	for _, tk := range trash {
+		if tk.ColumnID != cs[1].ID {
+			t.Errorf("task %q: got column %s, want %s", tk.Title, tk.ColumnID, cs[1].ID)
+		}
+	}
+
+	rec = s.serve(t, rt, testUser, "/projects/"+p.ID+"/columns/"+cs[1].ID+"?tasks=delete", nil, nil)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("delete last column: got %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if _, err := s.columns.Retrieve(context.Background(), p.ID, cs[1].ID); err != nil {
+		t.Errorf("last column: %v", err)
+	}
+}
+
+func TestColumnsReorder(t *testing.T) {
+	s := newStores()
+	h := s.columnHandlers()
+	p, cs := s.board(t, "To Do", "Done")
+
+	rt := route{http.MethodPut, "/projects/{pid}/columns/order", h.Reorder, member.RoleEditor}
+	target := "/projects/" + p.ID + "/columns/order"
+
+	partial := project.NewColumnOrder{ColumnOrder: []string{cs[1].ColumnName}}
+	rec := s.serve(t, rt, testUser, target, partial, nil)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("partial order: got %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	order := project.NewColumnOrder{ColumnOrder: []string{cs[1].ColumnName, cs[0].ColumnName}}
+	rec = s.serve(t, rt, testUser, target, order, ifMatch(p.Version))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("status: got %d, want %d: %s", rec.Code, http.StatusNoContent, rec.Body)
+	}
+
+	rec = s.serve(t, rt, testUser, target, order, ifMatch(p.Version))
+	if rec.Code != http.StatusPreconditionFailed {
+		t.Fatalf("stale version: got %d, want %d", rec.Code, http.StatusPreconditionFailed)
+	}
+
+	got, err := s.projects.Retrieve(context.Background(), p.ID, testUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equalIDs(got.ColumnOrder, order.ColumnOrder) {
+		t.Errorf("column order: got %v, want %v", got.ColumnOrder, order.ColumnOrder)
+	}
+}
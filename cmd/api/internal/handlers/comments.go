@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"github.com/ivorscott/devpie-client-backend-go/internal/mid"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/ivorscott/devpie-client-backend-go/internal/comment"
+	"github.com/ivorscott/devpie-client-backend-go/internal/platform/database"
+	"github.com/ivorscott/devpie-client-backend-go/internal/platform/web"
+	"github.com/pkg/errors"
+)
+
+// Comments holds the application state needed by the handler methods.
+type Comments struct {
+	repo *database.Repository
+	log  *log.Logger
+	auth *mid.Auth
+}
+
+// List gets all comments of a task
+func (c *Comments) List(w http.ResponseWriter, r *http.Request) error {
+	pid := chi.URLParam(r, "pid")
+	tid := chi.URLParam(r, "tid")
+
+	list, err := comment.List(r.Context(), c.repo, pid, tid)
+	if err != nil {
+		switch err {
+		case comment.ErrTaskNotFound:
+			return web.NewRequestError(err, http.StatusNotFound)
+		case comment.ErrInvalidID:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return errors.Wrapf(err, "listing comments of task %q", tid)
+		}
+	}
+
+	return web.Respond(r.Context(), w, list, http.StatusOK)
+}
+
+// Create a new Comment, or a reply when a parent id is given
+func (c *Comments) Create(w http.ResponseWriter, r *http.Request) error {
+	pid := chi.URLParam(r, "pid")
+	tid := chi.URLParam(r, "tid")
+	m := mid.CurrentMember(r.Context())
+
+	var nc comment.NewComment
+	if err := web.Decode(r, &nc); err != nil {
+		return err
+	}
+
+	cm, err := comment.Create(r.Context(), c.repo, nc, pid, tid, m.UserID, time.Now())
+	if err != nil {
+		switch err {
+		case comment.ErrTaskNotFound:
+			return web.NewRequestError(err, http.StatusNotFound)
+		case comment.ErrInvalidID, comment.ErrParentNotFound:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return errors.Wrapf(err, "creating comment on task %q", tid)
+		}
+	}
+
+	return web.Respond(r.Context(), w, cm, http.StatusCreated)
+}
+
+// Update decodes the body of a request to edit an existing comment. The ID
+// of the comment is part of the request URL.
+func (c *Comments) Update(w http.ResponseWriter, r *http.Request) error {
+	pid := chi.URLParam(r, "pid")
+	tid := chi.URLParam(r, "tid")
+	cid := chi.URLParam(r, "cmid")
+
+	var uc comment.UpdateComment
+	if err := web.Decode(r, &uc); err != nil {
+		return errors.Wrap(err, "decoding comment update")
+	}
+
+	if err := comment.Update(r.Context(), c.repo, pid, tid, cid, mid.CurrentMember(r.Context()), uc, time.Now()); err != nil {
+		switch err {
+		case comment.ErrNotFound, comment.ErrTaskNotFound:
+			return web.NewRequestError(err, http.StatusNotFound)
+		case comment.ErrInvalidID:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		case comment.ErrForbidden:
+			return web.NewRequestError(err, http.StatusForbidden)
+		default:
+			return errors.Wrapf(err, "updating comment %q", cid)
+		}
+	}
+
+	return web.Respond(r.Context(), w, nil, http.StatusNoContent)
+}
+
+// Delete removes the content of a single comment identified by an ID in the
+// request URL.
+func (c *Comments) Delete(w http.ResponseWriter, r *http.Request) error {
+	pid := chi.URLParam(r, "pid")
+	tid := chi.URLParam(r, "tid")
+	cid := chi.URLParam(r, "cmid")
+
+	if err := comment.Delete(r.Context(), c.repo, pid, tid, cid, mid.CurrentMember(r.Context()), time.Now()); err != nil {
+		switch err {
+		case comment.ErrNotFound, comment.ErrTaskNotFound:
+			return web.NewRequestError(err, http.StatusNotFound)
+		case comment.ErrInvalidID:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		case comment.ErrForbidden:
+			return web.NewRequestError(err, http.StatusForbidden)
+		default:
+			return errors.Wrapf(err, "deleting comment %q", cid)
+		}
+	}
+
+	return web.Respond(r.Context(), w, nil, http.StatusNoContent)
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/ivorscott/devpie-client-backend-go/internal/identity"
+	"github.com/ivorscott/devpie-client-backend-go/internal/mid"
+	"github.com/ivorscott/devpie-client-backend-go/internal/platform/auth"
+	"github.com/ivorscott/devpie-client-backend-go/internal/platform/web"
+	"github.com/ivorscott/devpie-client-backend-go/internal/user"
+	"github.com/pkg/errors"
+)
+
+// devTokenTTL is how long the tokens of the development auth mode are valid.
+const devTokenTTL = 24 * time.Hour
+
+// Dev serves the identity provider endpoints of the development auth mode.
+// It is only routed when the API runs in that mode.
+type Dev struct {
+	users  user.Store
+	issuer *auth.Issuer
+	log    *log.Logger
+}
+
+// NewToken is what we require from clients minting a development token.
+type NewToken struct {
+	Subject string                 `json:"subject" validate:"required"`
+	UserID  string                 `json:"userId"`
+	Scope   string                 `json:"scope"`
+	Claims  map[string]interface{} `json:"claims"`
+}
+
+// Token is a minted access token, shaped like the token response of an
+// OAuth authorization server.
+type Token struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// JWKS publishes the key verifying development tokens.
+func (d *Dev) JWKS(w http.ResponseWriter, r *http.Request) error {
+	return web.Respond(r.Context(), w, d.issuer.JWKS(), http.StatusOK)
+}
+
+// Token mints an access token for a subject. The token grants every scope
+// but admin unless scopes are asked for. Without a user id, the id of the
+// user already created for the subject is used, if any.
+func (d *Dev) Token(w http.ResponseWriter, r *http.Request) error {
+	var nt NewToken
+	if err := web.Decode(r, &nt); err != nil {
+		return err
+	}
+
+	claims := jwt.MapClaims{}
+	for k, v := range nt.Claims {
+		claims[k] = v
+	}
+
+	claims["sub"] = nt.Subject
+
+	claims["scope"] = strings.Join(mid.Scopes, " ")
+	if nt.Scope != "" {
+		claims["scope"] = nt.Scope
+	}
+
+	if nt.UserID == "" {
+		us, err := d.users.RetrieveMeBySubject(r.Context(), nt.Subject)
+		switch err {
+		case nil:
+			nt.UserID = us.ID
+		case user.ErrNotFound, user.ErrInvalidID:
+		default:
+			return errors.Wrapf(err, "looking for user %q", nt.Subject)
+		}
+	}
+	if nt.UserID != "" {
+		claims[identity.ClaimUserID] = nt.UserID
+	}
+
+	s, err := d.issuer.Sign(claims, devTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	t := Token{AccessToken: s, TokenType: "Bearer", ExpiresIn: int(devTokenTTL.Seconds())}
+
+	return web.Respond(r.Context(), w, t, http.StatusOK)
+}
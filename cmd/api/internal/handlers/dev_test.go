@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/ivorscott/devpie-client-backend-go/internal/identity"
+	"github.com/ivorscott/devpie-client-backend-go/internal/mid"
+	"github.com/ivorscott/devpie-client-backend-go/internal/platform/auth"
+	"github.com/ivorscott/devpie-client-backend-go/internal/user"
+)
+
+// devIssuer returns an Issuer of the development auth mode.
+func devIssuer(t *testing.T) *auth.Issuer {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return auth.NewIssuer(key, "https://localhost:4000/", "https://api.devpie.test")
+}
+
+func TestDevToken(t *testing.T) {
+	s := newStores()
+	issuer := devIssuer(t)
+	h := &Dev{users: s.users, issuer: issuer, log: s.log}
+
+	u, err := s.users.Create(context.Background(), user.NewUser{Email: "jane@example.com"}, "dev|jane", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rt := route{method: http.MethodPost, pattern: "/dev/token", handler: h.Token}
+	verifier := auth.NewVerifier(issuer.Keys(), issuer.Audience, issuer.Name)
+	all := strings.Join(mid.Scopes, " ")
+
+	tests := []struct {
+		name   string
+		body   NewToken
+		status int
+		uid    interface{}
+		scope  string
+	}{
+		{"created user", NewToken{Subject: "dev|jane"}, http.StatusOK, u.ID, all},
+		{"new user", NewToken{Subject: "dev|john"}, http.StatusOK, nil, all},
+		{"given user", NewToken{Subject: "dev|jane", UserID: testUser, Scope: mid.ScopeAdmin}, http.StatusOK, testUser, mid.ScopeAdmin},
+		{"custom claims", NewToken{Subject: "dev|jane", Claims: map[string]interface{}{"sub": "dev|john", "email": "jane@example.com"}}, http.StatusOK, u.ID, all},
+		{"no subject", NewToken{}, http.StatusBadRequest, nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := s.serve(t, rt, testUser, "/dev/token", tt.body, nil)
+			if rec.Code != tt.status {
+				t.Fatalf("status: got %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+
+			var got Token
+			decode(t, rec, &got)
+
+			token, err := verifier.Verify(context.Background(), got.AccessToken)
+			if err != nil {
+				t.Fatalf("verifying token: %v", err)
+			}
+
+			claims := token.Claims.(jwt.MapClaims)
+			if claims["sub"] != tt.body.Subject {
+				t.Errorf("sub: got %v, want %v", claims["sub"], tt.body.Subject)
+			}
+			if claims[identity.ClaimUserID] != tt.uid {
+				t.Errorf("user id: got %v, want %v", claims[identity.ClaimUserID], tt.uid)
+			}
+			if claims["scope"] != tt.scope {
+				t.Errorf("scope: got %v, want %v", claims["scope"], tt.scope)
+			}
+			for k, v := range tt.body.Claims {
+				if k != "sub" && claims[k] != v {
+					t.Errorf("%s: got %v, want %v", k, claims[k], v)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/ivorscott/devpie-client-backend-go/internal/mid"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/ivorscott/devpie-client-backend-go/internal/activity"
+	"github.com/ivorscott/devpie-client-backend-go/internal/platform/database"
+	"github.com/ivorscott/devpie-client-backend-go/internal/platform/hub"
+	"github.com/ivorscott/devpie-client-backend-go/internal/platform/web"
+	"github.com/ivorscott/devpie-client-backend-go/internal/project"
+	"github.com/pkg/errors"
+)
+
+// pingInterval keeps idle event streams open through proxies.
+const pingInterval = 30 * time.Second
+
+// replayLimit bounds the events replayed to a client resuming a stream.
+const replayLimit = 500
+
+// Events holds the application state needed by the handler methods.
+type Events struct {
+	repo *database.Repository
+	log  *log.Logger
+	auth *mid.Auth
+	hub  *hub.Hub
+}
+
+// Stream sends the activity of a project to the client as server-sent
+// events, named after the action and carrying the activity event as data. A
+// client reconnecting with a Last-Event-ID header first receives the events
+// it missed. The stream ends when the access token expires or the caller is
+// no longer a member of the project, the client reconnects to go on.
+func (e *Events) Stream(w http.ResponseWriter, r *http.Request) error {
+	pid := chi.URLParam(r, "pid")
+	uid := e.auth.GetUserById(r)
+
+	// Subscribe before replaying so no event falls in between.
+	msgs, unsubscribe := e.hub.Subscribe(pid)
+	defer unsubscribe()
+
+	var missed []activity.Event
+	if last := r.Header.Get("Last-Event-ID"); last != "" {
+		var err error
+		missed, err = activity.ListSince(r.Context(), e.repo, pid, last, replayLimit)
+		if err != nil && err != activity.ErrInvalidID {
+			return errors.Wrapf(err, "replaying events of project %q", pid)
+		}
+	}
+
+	s, err := web.NewStream(w, r)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	sent := make(map[string]bool, len(missed))
+	for _, ev := range missed {
+		data, err := json.Marshal(ev)
+		if err != nil {
+			return errors.Wrap(err, "encoding event")
+		}
+		if err := s.Send(ev.ID, ev.Action, data); err != nil {
+			return nil
+		}
+		sent[ev.ID] = true
+	}
+
+	var expired <-chan time.Time
+	if exp, ok := e.auth.Expires(r); ok {
+		timer := time.NewTimer(time.Until(exp))
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	ping := time.NewTicker(pingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-s.Done():
+			return nil
+		case <-expired:
+			return nil
+		case <-ping.C:
+			// Members removed since the stream started lose it. On other
+			// errors too, the reconnecting client finds out.
+			if _, err := project.Retrieve(r.Context(), e.repo, pid, uid); err != nil {
+				return nil
+			}
+			if err := s.Ping(); err != nil {
+				return nil
+			}
+		case msg, ok := <-msgs:
+			// The hub dropped the subscription, the client reconnects and resumes.
+			if !ok {
+				return nil
+			}
+			if sent[msg.ID] {
+				continue
+			}
+			if err := s.Send(msg.ID, msg.Name, msg.Data); err != nil {
+				return nil
+			}
+		}
+	}
+}
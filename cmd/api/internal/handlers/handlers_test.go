@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/go-chi/chi"
+	"github.com/ivorscott/devpie-client-backend-go/internal/activity"
+	"github.com/ivorscott/devpie-client-backend-go/internal/column"
+	"github.com/ivorscott/devpie-client-backend-go/internal/member"
+	"github.com/ivorscott/devpie-client-backend-go/internal/mid"
+	"github.com/ivorscott/devpie-client-backend-go/internal/platform/auth"
+	"github.com/ivorscott/devpie-client-backend-go/internal/platform/web"
+	"github.com/ivorscott/devpie-client-backend-go/internal/project"
+	"github.com/ivorscott/devpie-client-backend-go/internal/task"
+	"github.com/ivorscott/devpie-client-backend-go/internal/user"
+	"github.com/pkg/errors"
+)
+
+// testUser is the id of the user making the test requests.
+const testUser = "5cf37266-3473-4006-984f-9325122678b7"
+
+// stores are the in-memory stores the handlers under test share.
+type stores struct {
+	projects *project.Memory
+	columns  *column.Memory
+	tasks    *task.Memory
+	users    *user.Memory
+	activity *activity.Memory
+	log      *log.Logger
+	provider *provider
+	auth     *mid.Auth
+}
+
+func newStores() *stores {
+	tasks := task.NewMemory()
+	columns := column.NewMemory(tasks)
+
+	p := &provider{synced: map[string]string{}}
+
+	return &stores{
+		projects: project.NewMemory(columns),
+		columns:  columns,
+		tasks:    tasks,
+		users:    user.NewMemory(),
+		activity: activity.NewMemory(),
+		log:      log.New(ioutil.Discard, "", 0),
+		provider: p,
+		auth:     &mid.Auth{Provider: p},
+	}
+}
+
+// noUnit stands in for the units of work of the repository, the in-memory
+// stores have none.
+type noUnit struct{}
+
+func (noUnit) Begin(ctx context.Context) (context.Context, error) { return ctx, nil }
+func (noUnit) Commit(ctx context.Context) error                   { return nil }
+func (noUnit) Rollback(ctx context.Context) error                 { return nil }
+
+// provider stands in for the identity provider, recording the users synced
+// to it. Tokens are attached by authenticate instead of being verified.
+type provider struct {
+	synced map[string]string
+	err    error
+}
+
+func (p *provider) Verify(ctx context.Context, token string) (*jwt.Token, error) {
+	return nil, auth.ErrInvalidToken
+}
+
+func (p *provider) SyncUser(ctx context.Context, subject, userID string) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.synced[subject] = userID
+	return nil
+}
+
+func (p *provider) UnlinkUser(ctx context.Context, subject string) error {
+	if p.err != nil {
+		return p.err
+	}
+	delete(p.synced, subject)
+	return nil
+}
+
+// route describes how a handler is mounted. Handlers of project scoped
+// routes are given the project of the {pid} URL parameter, with the caller
+// having role in it, the way mid.Project does.
+type route struct {
+	method  string
+	pattern string
+	handler web.Handler
+	role    member.Role
+}
+
+// serve sends a request to target through the route, authenticated as uid.
+// A non nil body is sent as JSON, header is added to the request.
+func (s *stores) serve(t *testing.T, rt route, uid, target string, body interface{}, header http.Header) *httptest.ResponseRecorder {
+	t.Helper()
+
+	app := web.NewApp(nil, s.log, authenticate(uid), mid.Errors(s.log))
+
+	h := rt.handler
+	if rt.role != "" {
+		h = s.inProject(rt.role)(h)
+	}
+	app.Handle(rt.method, rt.pattern, h)
+
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = bytes.NewReader(b)
+	}
+
+	req := httptest.NewRequest(rt.method, target, r)
+	for k, vs := range header {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, req)
+
+	return rec
+}
+
+// authenticate stands in for Auth.Authenticate, attaching the token of a
+// user with the id uid to every request.
+func authenticate(uid string) web.Middleware {
+	return func(after web.Handler) web.Handler {
+		return func(w http.ResponseWriter, r *http.Request) error {
+			token := &jwt.Token{Claims: jwt.MapClaims{
+				"sub": "auth0|" + uid,
+				"https://client.devpie.io/claims/user_id": uid,
+			}}
+			return after(w, r.WithContext(context.WithValue(r.Context(), "user", token)))
+		}
+	}
+}
+
+// inProject stands in for mid.Project. The project of the request must
+// belong to the caller, who is given role in it.
+func (s *stores) inProject(role member.Role) web.Middleware {
+	return func(after web.Handler) web.Handler {
+		return func(w http.ResponseWriter, r *http.Request) error {
+			pid := chi.URLParam(r, "pid")
+			uid := s.auth.GetUserById(r)
+
+			p, err := s.projects.Retrieve(r.Context(), pid, uid)
+			if err != nil {
+				return web.NewRequestError(err, http.StatusNotFound)
+			}
+
+			m := &member.Member{ProjectID: pid, UserID: uid, Role: role, Accepted: true}
+			ctx := context.WithValue(r.Context(), mid.KeyProject, &mid.ProjectValues{Project: p, Member: m})
+
+			return after(w, r.WithContext(ctx))
+		}
+	}
+}
+
+// board creates a project of the test user with columns titled titles.
+func (s *stores) board(t *testing.T, titles ...string) (*project.Project, []*column.Column) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	p, err := s.projects.Create(ctx, project.NewProject{Name: "board"}, testUser, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cs []*column.Column
+	for _, title := range titles {
+		c, err := s.columns.Create(ctx, column.NewColumn{Title: title, ProjectID: p.ID}, time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		cs = append(cs, c)
+	}
+
+	return p, cs
+}
+
+// addTasks adds tasks with the given titles to the end of a column.
+func (s *stores) addTasks(t *testing.T, c *column.Column, titles ...string) []*task.Task {
+	t.Helper()
+
+	var ts []*task.Task
+	for _, title := range titles {
+		tk, err := s.tasks.Create(context.Background(), task.NewTask{Title: title}, c.ProjectID, c.ID, time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		ts = append(ts, tk)
+	}
+
+	return ts
+}
+
+// decode unmarshals the body of a response into v.
+func decode(t *testing.T, rec *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatal(errors.Wrapf(err, "decoding %s", rec.Body.String()))
+	}
+}
+
+// ifMatch returns the header making a request conditional on version.
+func ifMatch(version int) http.Header {
+	return http.Header{"If-Match": []string{web.ETag(version)}}
+}
+
+// lastAction returns the action of the most recent activity event.
+func (s *stores) lastAction() string {
+	es := s.activity.Events()
+	if len(es) == 0 {
+		return ""
+	}
+	return es[len(es)-1].Action
+}
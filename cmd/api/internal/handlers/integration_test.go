@@ -0,0 +1,880 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/google/uuid"
+	"github.com/ivorscott/devpie-client-backend-go/internal/identity"
+	"github.com/ivorscott/devpie-client-backend-go/internal/mid"
+	"github.com/ivorscott/devpie-client-backend-go/internal/platform/auth"
+	"github.com/ivorscott/devpie-client-backend-go/internal/platform/hub"
+	"github.com/ivorscott/devpie-client-backend-go/internal/platform/web"
+	"github.com/ivorscott/devpie-client-backend-go/internal/tests"
+	"github.com/ivorscott/devpie-client-backend-go/internal/user"
+	"github.com/pkg/errors"
+)
+
+const (
+	testAudience = "https://api.devpie.test"
+	testDomain   = "devpie.test"
+)
+
+// testScopes are granted to the tokens of the tests unless they say otherwise.
+var testScopes = strings.Join([]string{
+	mid.ScopeReadUsers, mid.ScopeWriteUsers,
+	mid.ScopeReadProjects, mid.ScopeWriteProjects,
+	mid.ScopeReadTasks, mid.ScopeWriteTasks,
+}, " ")
+
+// api is an API server backed by a test database. Its requests are
+// authenticated with tokens signed by key.
+type api struct {
+	t   *testing.T
+	srv *httptest.Server
+	key *rsa.PrivateKey
+}
+
+func newAPI(t *testing.T) *api {
+	t.Helper()
+
+	repo := tests.NewDatabase(t)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	provider := identity.NewOIDC(auth.StaticKeys{"": &key.PublicKey}, "https://"+testDomain+"/", testAudience, identity.DefaultClaims)
+	authn := &mid.Auth{Provider: provider, Users: user.NewPostgres(repo)}
+
+	shutdown := make(chan os.Signal, 1)
+	logger := log.New(ioutil.Discard, "", 0)
+	srv := httptest.NewServer(API(shutdown, repo, hub.New(), logger, "http://localhost:3000", authn))
+	t.Cleanup(srv.Close)
+
+	return &api{t: t, srv: srv, key: key}
+}
+
+// token returns an access token of the user uid, signed with key. The claims
+// of the token can be changed with edit.
+func (a *api) token(uid string, key *rsa.PrivateKey, edit func(jwt.MapClaims)) string {
+	a.t.Helper()
+
+	claims := jwt.MapClaims{
+		"iss":                "https://" + testDomain + "/",
+		"aud":                testAudience,
+		"sub":                "auth0|" + uid,
+		"exp":                time.Now().Add(time.Hour).Unix(),
+		"scope":              testScopes,
+		identity.ClaimUserID: uid,
+	}
+	if edit != nil {
+		edit(claims)
+	}
+
+	s, err := jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(key)
+	if err != nil {
+		a.t.Fatal(err)
+	}
+
+	return s
+}
+
+// do sends a request authenticated with token, which is left out when empty.
+// A string body is sent as is, any other non nil body as JSON.
+func (a *api) do(token, method, path string, body interface{}, header http.Header) (*http.Response, []byte) {
+	a.t.Helper()
+
+	var r io.Reader
+	switch b := body.(type) {
+	case nil:
+	case string:
+		r = strings.NewReader(b)
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			a.t.Fatal(err)
+		}
+		r = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, a.srv.URL+path, r)
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	for k, vs := range header {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+
+	resp, err := a.srv.Client().Do(req)
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		a.t.Fatal(err)
+	}
+
+	return resp, data
+}
+
+// expect sends a request as the user uid and fails the test unless the
+// response has the given status. It returns the response.
+func (a *api) expect(uid, method, path string, body interface{}, header http.Header, status int) (*http.Response, []byte) {
+	a.t.Helper()
+
+	resp, data := a.do(a.token(uid, a.key, nil), method, path, body, header)
+	if resp.StatusCode != status {
+		a.t.Fatalf("%s %s: got %d, want %d: %s", method, path, resp.StatusCode, status, data)
+	}
+
+	return resp, data
+}
+
+// unmarshal decodes a response body into v.
+func unmarshal(t *testing.T, data []byte, v interface{}) {
+	t.Helper()
+
+	if err := json.Unmarshal(data, v); err != nil {
+		t.Fatal(errors.Wrapf(err, "decoding %s", data))
+	}
+}
+
+type resource struct {
+	ID          string   `json:"id"`
+	ColumnName  string   `json:"columnName"`
+	ColumnOrder []string `json:"columnOrder"`
+	TaskIDs     []string `json:"taskIds"`
+}
+
+func TestAPIAuthentication(t *testing.T) {
+	a := newAPI(t)
+
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokens := []struct {
+		name  string
+		token string
+	}{
+		{"missing", ""},
+		{"malformed", "not-a-token"},
+		{"foreign key", a.token(tests.OwnerID, other, nil)},
+		{"expired", a.token(tests.OwnerID, a.key, func(c jwt.MapClaims) {
+			c["exp"] = time.Now().Add(-time.Hour).Unix()
+		})},
+		{"audience", a.token(tests.OwnerID, a.key, func(c jwt.MapClaims) { c["aud"] = "https://elsewhere" })},
+		{"issuer", a.token(tests.OwnerID, a.key, func(c jwt.MapClaims) { c["iss"] = "https://elsewhere/" })},
+	}
+
+	for _, tt := range tokens {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, data := a.do(tt.token, http.MethodGet, "/v1/projects", nil, nil)
+			if resp.StatusCode != http.StatusUnauthorized {
+				t.Errorf("status: got %d, want %d: %s", resp.StatusCode, http.StatusUnauthorized, data)
+			}
+		})
+	}
+
+	// The health check is public.
+	resp, data := a.do("", http.MethodGet, "/v1/health", nil, nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("health: got %d, want %d: %s", resp.StatusCode, http.StatusOK, data)
+	}
+}
+
+func TestAPIScopes(t *testing.T) {
+	a := newAPI(t)
+
+	readOnly := a.token(tests.OwnerID, a.key, func(c jwt.MapClaims) { c["scope"] = mid.ScopeReadProjects })
+	admin := a.token(tests.OwnerID, a.key, func(c jwt.MapClaims) {
+		delete(c, "scope")
+		c["permissions"] = []string{mid.ScopeAdmin}
+	})
+
+	resp, data := a.do(readOnly, http.MethodGet, "/v1/projects", nil, nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("read: got %d, want %d: %s", resp.StatusCode, http.StatusOK, data)
+	}
+
+	resp, data = a.do(readOnly, http.MethodPost, "/v1/projects", map[string]string{"name": "Board"}, nil)
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("write: got %d, want %d: %s", resp.StatusCode, http.StatusForbidden, data)
+	}
+	var er web.ErrorResponse
+	unmarshal(t, data, &er)
+	if !strings.Contains(er.Error, mid.ScopeWriteProjects) {
+		t.Errorf("error: got %q, want it to name %s", er.Error, mid.ScopeWriteProjects)
+	}
+
+	resp, data = a.do(admin, http.MethodPost, "/v1/projects", map[string]string{"name": "Board"}, nil)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("admin: got %d, want %d: %s", resp.StatusCode, http.StatusCreated, data)
+	}
+}
+
+func TestAPIUsers(t *testing.T) {
+	a := newAPI(t)
+
+	_, data := a.expect(tests.OwnerID, http.MethodGet, "/v1/users/me", nil, nil, http.StatusOK)
+	var me resource
+	unmarshal(t, data, &me)
+	if me.ID != tests.OwnerID {
+		t.Errorf("me: got %q, want %q", me.ID, tests.OwnerID)
+	}
+
+	// Without the user id claim the user is found by the token subject.
+	noClaim := a.token(tests.EditorID, a.key, func(c jwt.MapClaims) {
+		delete(c, identity.ClaimUserID)
+	})
+	resp, data := a.do(noClaim, http.MethodGet, "/v1/users/me", nil, nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("me by subject: got %d, want %d: %s", resp.StatusCode, http.StatusOK, data)
+	}
+
+	a.expect("not-a-uuid", http.MethodGet, "/v1/users/me", nil, nil, http.StatusBadRequest)
+	a.expect(uuid.New().String(), http.MethodGet, "/v1/users/me", nil, nil, http.StatusNotFound)
+	a.expect(tests.OwnerID, http.MethodPost, "/v1/users", "{", nil, http.StatusBadRequest)
+
+	// A new user signs up with a token of the provider lacking the claim,
+	// later requests find the user by subject.
+	sub := "oidc|" + uuid.New().String()
+	newUser := a.token("", a.key, func(c jwt.MapClaims) {
+		c["sub"] = sub
+		delete(c, identity.ClaimUserID)
+	})
+	resp, data = a.do(newUser, http.MethodPost, "/v1/users", map[string]string{"email": "new@devpie.io"}, nil)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("sign up: got %d, want %d: %s", resp.StatusCode, http.StatusCreated, data)
+	}
+	var created struct {
+		ID         string `json:"id"`
+		LinkStatus string `json:"linkStatus"`
+	}
+	unmarshal(t, data, &created)
+	if created.LinkStatus != user.LinkLinked {
+		t.Errorf("sign up: link status %q, want %q", created.LinkStatus, user.LinkLinked)
+	}
+
+	// Signing up again is harmless.
+	resp, data = a.do(newUser, http.MethodPost, "/v1/users", map[string]string{"email": "new@devpie.io"}, nil)
+	var again resource
+	unmarshal(t, data, &again)
+	if resp.StatusCode != http.StatusOK || again.ID != created.ID {
+		t.Errorf("sign up again: got %d %q, want %d %q", resp.StatusCode, again.ID, http.StatusOK, created.ID)
+	}
+
+	resp, data = a.do(newUser, http.MethodPost, "/v1/projects", map[string]string{"name": "Mine"}, nil)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("project of new user: got %d, want %d: %s", resp.StatusCode, http.StatusCreated, data)
+	}
+	resp, data = a.do(newUser, http.MethodGet, "/v1/users/me", nil, nil)
+	unmarshal(t, data, &me)
+	if resp.StatusCode != http.StatusOK || me.ID != created.ID {
+		t.Errorf("new user: got %d %q, want %d %q", resp.StatusCode, me.ID, http.StatusOK, created.ID)
+	}
+}
+
+func TestAPIAccount(t *testing.T) {
+	a := newAPI(t)
+
+	a.expect(tests.OwnerID, http.MethodPatch, "/v1/users/me", map[string]string{"firstName": "Liv"}, nil, http.StatusOK)
+	a.expect(tests.OwnerID, http.MethodPatch, "/v1/users/me", map[string]string{"picture": "not a url"}, nil, http.StatusBadRequest)
+
+	// The owner shares one project with the editor and keeps another.
+	_, data := a.expect(tests.OwnerID, http.MethodPost, "/v1/projects", map[string]string{"name": "Shared"}, nil, http.StatusCreated)
+	var shared resource
+	unmarshal(t, data, &shared)
+	base := "/v1/projects/" + shared.ID
+	a.expect(tests.OwnerID, http.MethodPost, "/v1/projects", map[string]string{"name": "Solo"}, nil, http.StatusCreated)
+
+	a.expect(tests.OwnerID, http.MethodPost, base+"/members", map[string]string{"email": tests.EditorEmail, "role": "editor"}, nil, http.StatusCreated)
+	a.expect(tests.EditorID, http.MethodPost, base+"/members/accept", nil, nil, http.StatusNoContent)
+
+	_, data = a.expect(tests.OwnerID, http.MethodGet, base+"/columns", nil, nil, http.StatusOK)
+	var cols []resource
+	unmarshal(t, data, &cols)
+	_, data = a.expect(tests.OwnerID, http.MethodPost, base+"/columns/"+cols[0].ID+"/tasks", map[string]string{"title": "mine", "assigneeId": tests.OwnerID}, nil, http.StatusCreated)
+	var task resource
+	unmarshal(t, data, &task)
+	comments := base + "/tasks/" + task.ID + "/comments"
+	a.expect(tests.OwnerID, http.MethodPost, comments, map[string]string{"content": "Mine"}, nil, http.StatusCreated)
+
+	resp, data := a.expect(tests.OwnerID, http.MethodGet, "/v1/users/me/export", nil, nil, http.StatusOK)
+	if cd := resp.Header.Get("Content-Disposition"); !strings.HasPrefix(cd, "attachment;") {
+		t.Errorf("content disposition: got %q, want an attachment", cd)
+	}
+	var archive struct {
+		User struct {
+			FirstName string `json:"firstName"`
+		} `json:"user"`
+		Projects []struct {
+			Name  string            `json:"name"`
+			Tasks []json.RawMessage `json:"tasks"`
+		} `json:"projects"`
+		Comments []json.RawMessage `json:"comments"`
+	}
+	unmarshal(t, data, &archive)
+	if archive.User.FirstName != "Liv" || len(archive.Projects) != 2 || len(archive.Comments) != 1 {
+		t.Errorf("export: got %s", data)
+	}
+	if p := archive.Projects[0]; p.Name != "Shared" || len(p.Tasks) != 1 {
+		t.Errorf("exported project: got %+v, want Shared with its task", p)
+	}
+
+	a.expect(tests.OwnerID, http.MethodDelete, "/v1/users/me?projects=keep", nil, nil, http.StatusBadRequest)
+	a.expect(tests.OwnerID, http.MethodDelete, "/v1/users/me", nil, nil, http.StatusNoContent)
+	a.expect(tests.OwnerID, http.MethodGet, "/v1/users/me", nil, nil, http.StatusNotFound)
+	a.expect(tests.OwnerID, http.MethodDelete, "/v1/users/me", nil, nil, http.StatusNotFound)
+
+	// The editor owns the shared project now, the other one is gone.
+	_, data = a.expect(tests.EditorID, http.MethodGet, "/v1/projects", nil, nil, http.StatusOK)
+	var projects []resource
+	unmarshal(t, data, &projects)
+	if len(projects) != 1 || projects[0].ID != shared.ID {
+		t.Fatalf("projects: got %+v, want the shared one", projects)
+	}
+	a.expect(tests.EditorID, http.MethodPost, base+"/archive", nil, nil, http.StatusNoContent)
+
+	_, data = a.expect(tests.EditorID, http.MethodGet, comments, nil, nil, http.StatusOK)
+	var left []struct {
+		AuthorID *string `json:"authorId"`
+		Content  string  `json:"content"`
+	}
+	unmarshal(t, data, &left)
+	if len(left) != 1 || left[0].AuthorID != nil || left[0].Content != "Mine" {
+		t.Errorf("comments: got %s, want the comment without author", data)
+	}
+}
+
+func TestAPITemplates(t *testing.T) {
+	a := newAPI(t)
+
+	_, data := a.expect(tests.OwnerID, http.MethodGet, "/v1/templates", nil, nil, http.StatusOK)
+	var list []resource
+	unmarshal(t, data, &list)
+	if len(list) == 0 {
+		t.Fatal("templates: got none, want the builtin ones")
+	}
+
+	nt := map[string]interface{}{
+		"name":    "Sprint",
+		"columns": []map[string]interface{}{{"title": "Backlog"}, {"title": "Shipped"}},
+	}
+	_, data = a.expect(tests.OwnerID, http.MethodPost, "/v1/templates", nt, nil, http.StatusCreated)
+	var tm resource
+	unmarshal(t, data, &tm)
+
+	_, data = a.expect(tests.OwnerID, http.MethodPost, "/v1/projects", map[string]string{"name": "Sprint 1", "templateId": tm.ID}, nil, http.StatusCreated)
+	var p resource
+	unmarshal(t, data, &p)
+	if len(p.ColumnOrder) != 2 {
+		t.Errorf("columns of project from template: got %d, want 2", len(p.ColumnOrder))
+	}
+
+	a.expect(tests.OwnerID, http.MethodPost, "/v1/templates", map[string]string{"name": "Empty"}, nil, http.StatusBadRequest)
+	a.expect(tests.OwnerID, http.MethodPost, "/v1/projects", map[string]string{"name": "x", "templateId": "nope"}, nil, http.StatusBadRequest)
+	a.expect(tests.OwnerID, http.MethodPost, "/v1/projects", map[string]string{"name": "x", "templateId": uuid.New().String()}, nil, http.StatusNotFound)
+
+	// Templates of other users aren't visible.
+	a.expect(tests.StrangerID, http.MethodPost, "/v1/projects", map[string]string{"name": "x", "templateId": tm.ID}, nil, http.StatusNotFound)
+
+	a.expect(tests.OwnerID, http.MethodPost, "/v1/projects/"+p.ID+"/template", map[string]interface{}{"name": "Copy", "includeTasks": true}, nil, http.StatusCreated)
+	a.expect(tests.OwnerID, http.MethodPost, "/v1/projects/"+p.ID+"/template", map[string]interface{}{}, nil, http.StatusBadRequest)
+}
+
+func TestAPIProjects(t *testing.T) {
+	a := newAPI(t)
+
+	_, data := a.expect(tests.OwnerID, http.MethodPost, "/v1/projects", map[string]string{"name": "Board"}, nil, http.StatusCreated)
+	var p resource
+	unmarshal(t, data, &p)
+	if len(p.ColumnOrder) != 4 {
+		t.Errorf("columns of default template: got %d, want 4", len(p.ColumnOrder))
+	}
+	base := "/v1/projects/" + p.ID
+
+	a.expect(tests.OwnerID, http.MethodPost, "/v1/projects", map[string]string{}, nil, http.StatusBadRequest)
+
+	_, data = a.expect(tests.OwnerID, http.MethodGet, "/v1/projects", nil, nil, http.StatusOK)
+	var list []resource
+	unmarshal(t, data, &list)
+	if len(list) != 1 || list[0].ID != p.ID {
+		t.Errorf("projects: got %+v", list)
+	}
+
+	a.expect(tests.OwnerID, http.MethodGet, "/v2/projects?limit=1", nil, nil, http.StatusOK)
+	a.expect(tests.OwnerID, http.MethodGet, "/v2/projects?limit=0", nil, nil, http.StatusBadRequest)
+
+	resp, _ := a.expect(tests.OwnerID, http.MethodGet, base, nil, nil, http.StatusOK)
+	etag := resp.Header.Get("ETag")
+
+	a.expect(tests.OwnerID, http.MethodGet, "/v1/projects/not-a-uuid", nil, nil, http.StatusBadRequest)
+	a.expect(tests.OwnerID, http.MethodGet, "/v1/projects/"+uuid.New().String(), nil, nil, http.StatusNotFound)
+	a.expect(tests.StrangerID, http.MethodGet, base, nil, nil, http.StatusNotFound)
+
+	update := map[string]interface{}{"name": "Renamed"}
+	a.expect(tests.OwnerID, http.MethodPut, base, update, http.Header{"If-Match": {`"99"`}}, http.StatusPreconditionFailed)
+	a.expect(tests.OwnerID, http.MethodPut, base, update, http.Header{"If-Match": {etag}}, http.StatusNoContent)
+	a.expect(tests.OwnerID, http.MethodPut, base, map[string]interface{}{}, nil, http.StatusBadRequest)
+
+	a.expect(tests.OwnerID, http.MethodPost, base+"/archive", nil, nil, http.StatusNoContent)
+	_, data = a.expect(tests.OwnerID, http.MethodGet, "/v1/projects?archived=true", nil, nil, http.StatusOK)
+	unmarshal(t, data, &list)
+	if len(list) != 1 {
+		t.Errorf("archived projects: got %d, want 1", len(list))
+	}
+	a.expect(tests.OwnerID, http.MethodPost, base+"/restore", nil, nil, http.StatusNoContent)
+
+	resp, _ = a.expect(tests.OwnerID, http.MethodGet, base, nil, nil, http.StatusOK)
+	a.expect(tests.OwnerID, http.MethodDelete, base, nil, http.Header{"If-Match": {`"99"`}}, http.StatusPreconditionFailed)
+	a.expect(tests.OwnerID, http.MethodDelete, base, nil, http.Header{"If-Match": {resp.Header.Get("ETag")}}, http.StatusNoContent)
+	a.expect(tests.OwnerID, http.MethodGet, base, nil, nil, http.StatusNotFound)
+
+	_, data = a.expect(tests.OwnerID, http.MethodGet, "/v1/projects/trash", nil, nil, http.StatusOK)
+	unmarshal(t, data, &list)
+	if len(list) != 1 || list[0].ID != p.ID {
+		t.Errorf("trash: got %+v", list)
+	}
+
+	a.expect(tests.StrangerID, http.MethodPost, base+"/restore", nil, nil, http.StatusNotFound)
+	a.expect(tests.OwnerID, http.MethodPost, "/v1/projects/not-a-uuid/restore", nil, nil, http.StatusBadRequest)
+	a.expect(tests.OwnerID, http.MethodPost, base+"/restore", nil, nil, http.StatusNoContent)
+	a.expect(tests.OwnerID, http.MethodGet, base, nil, nil, http.StatusOK)
+}
+
+func TestAPIMembers(t *testing.T) {
+	a := newAPI(t)
+
+	_, data := a.expect(tests.OwnerID, http.MethodPost, "/v1/projects", map[string]string{"name": "Team"}, nil, http.StatusCreated)
+	var p resource
+	unmarshal(t, data, &p)
+	base := "/v1/projects/" + p.ID
+
+	invite := map[string]string{"email": tests.EditorEmail, "role": "editor"}
+	a.expect(tests.OwnerID, http.MethodPost, base+"/members", invite, nil, http.StatusCreated)
+	a.expect(tests.OwnerID, http.MethodPost, base+"/members", invite, nil, http.StatusConflict)
+	a.expect(tests.OwnerID, http.MethodPost, base+"/members", map[string]string{"email": "nobody@devpie.io", "role": "viewer"}, nil, http.StatusNotFound)
+	a.expect(tests.OwnerID, http.MethodPost, base+"/members", map[string]string{"email": tests.EditorEmail, "role": "owner"}, nil, http.StatusBadRequest)
+
+	_, data = a.expect(tests.EditorID, http.MethodGet, "/v1/users/me/invites", nil, nil, http.StatusOK)
+	var invites []json.RawMessage
+	unmarshal(t, data, &invites)
+	if len(invites) != 1 {
+		t.Errorf("invites: got %d, want 1", len(invites))
+	}
+
+	// Pending members can't see the project yet, but can decline the invite.
+	a.expect(tests.EditorID, http.MethodGet, base, nil, nil, http.StatusNotFound)
+	a.expect(tests.EditorID, http.MethodDelete, base+"/members/"+tests.OwnerID, nil, nil, http.StatusNotFound)
+	a.expect(tests.EditorID, http.MethodDelete, base+"/members/"+tests.EditorID, nil, nil, http.StatusNoContent)
+	_, data = a.expect(tests.EditorID, http.MethodGet, "/v1/users/me/invites", nil, nil, http.StatusOK)
+	unmarshal(t, data, &invites)
+	if len(invites) != 0 {
+		t.Errorf("invites after declining: got %d, want 0", len(invites))
+	}
+	a.expect(tests.OwnerID, http.MethodPost, base+"/members", invite, nil, http.StatusCreated)
+
+	a.expect(tests.StrangerID, http.MethodPost, base+"/members/accept", nil, nil, http.StatusNotFound)
+	a.expect(tests.EditorID, http.MethodPost, "/v1/projects/not-a-uuid/members/accept", nil, nil, http.StatusBadRequest)
+	a.expect(tests.EditorID, http.MethodPost, base+"/members/accept", nil, nil, http.StatusNoContent)
+	a.expect(tests.EditorID, http.MethodGet, base, nil, nil, http.StatusOK)
+
+	_, data = a.expect(tests.EditorID, http.MethodGet, base+"/members", nil, nil, http.StatusOK)
+	var members []json.RawMessage
+	unmarshal(t, data, &members)
+	if len(members) != 2 {
+		t.Errorf("members: got %d, want 2", len(members))
+	}
+
+	// Owner only routes.
+	a.expect(tests.EditorID, http.MethodPost, base+"/members", invite, nil, http.StatusForbidden)
+	a.expect(tests.EditorID, http.MethodPost, base+"/archive", nil, nil, http.StatusForbidden)
+	a.expect(tests.EditorID, http.MethodDelete, base, nil, nil, http.StatusForbidden)
+	a.expect(tests.EditorID, http.MethodDelete, base+"/members/"+tests.OwnerID, nil, nil, http.StatusForbidden)
+
+	a.expect(tests.OwnerID, http.MethodDelete, base+"/members/"+tests.OwnerID, nil, nil, http.StatusBadRequest)
+	a.expect(tests.OwnerID, http.MethodDelete, base+"/members/not-a-uuid", nil, nil, http.StatusBadRequest)
+	a.expect(tests.OwnerID, http.MethodDelete, base+"/members/"+tests.StrangerID, nil, nil, http.StatusNotFound)
+	a.expect(tests.StrangerID, http.MethodDelete, base+"/members/"+tests.EditorID, nil, nil, http.StatusNotFound)
+
+	// Removed members are unassigned from the tasks of the project.
+	_, data = a.expect(tests.OwnerID, http.MethodGet, base+"/columns", nil, nil, http.StatusOK)
+	var cols []resource
+	unmarshal(t, data, &cols)
+	a.expect(tests.OwnerID, http.MethodPost, base+"/columns/"+cols[0].ID+"/tasks", map[string]string{"title": "theirs", "assigneeId": tests.EditorID}, nil, http.StatusCreated)
+
+	a.expect(tests.OwnerID, http.MethodDelete, base+"/members/"+tests.EditorID, nil, nil, http.StatusNoContent)
+	a.expect(tests.EditorID, http.MethodGet, base, nil, nil, http.StatusNotFound)
+
+	_, data = a.expect(tests.OwnerID, http.MethodGet, base+"/tasks?assignee="+tests.EditorID, nil, nil, http.StatusOK)
+	var ts []resource
+	unmarshal(t, data, &ts)
+	if len(ts) != 0 {
+		t.Errorf("tasks of removed member: got %d, want 0", len(ts))
+	}
+}
+
+func TestAPIBoard(t *testing.T) {
+	a := newAPI(t)
+
+	_, data := a.expect(tests.OwnerID, http.MethodPost, "/v1/projects", map[string]string{"name": "Board"}, nil, http.StatusCreated)
+	var p resource
+	unmarshal(t, data, &p)
+	base := "/v1/projects/" + p.ID
+
+	// The owner invites an editor, who does the work below.
+	a.expect(tests.OwnerID, http.MethodPost, base+"/members", map[string]string{"email": tests.EditorEmail, "role": "editor"}, nil, http.StatusCreated)
+	a.expect(tests.EditorID, http.MethodPost, base+"/members/accept", nil, nil, http.StatusNoContent)
+	editor := tests.EditorID
+
+	// Columns.
+	_, data = a.expect(editor, http.MethodGet, base+"/columns", nil, nil, http.StatusOK)
+	var cols []resource
+	unmarshal(t, data, &cols)
+	if len(cols) != 4 {
+		t.Fatalf("columns: got %d, want 4", len(cols))
+	}
+	todo := cols[0]
+
+	_, data = a.expect(editor, http.MethodPost, base+"/columns", map[string]string{"title": "Blocked"}, nil, http.StatusCreated)
+	var blocked resource
+	unmarshal(t, data, &blocked)
+	a.expect(editor, http.MethodPost, base+"/columns", map[string]string{}, nil, http.StatusBadRequest)
+
+	resp, _ := a.expect(editor, http.MethodGet, base+"/columns/"+blocked.ID, nil, nil, http.StatusOK)
+	a.expect(editor, http.MethodGet, base+"/columns/not-a-uuid", nil, nil, http.StatusBadRequest)
+	a.expect(editor, http.MethodGet, base+"/columns/"+uuid.New().String(), nil, nil, http.StatusNotFound)
+	a.expect(tests.StrangerID, http.MethodGet, base+"/columns", nil, nil, http.StatusNotFound)
+
+	rename := map[string]string{"title": "Stuck"}
+	a.expect(editor, http.MethodPatch, base+"/columns/"+blocked.ID, rename, http.Header{"If-Match": {`"99"`}}, http.StatusPreconditionFailed)
+	a.expect(editor, http.MethodPatch, base+"/columns/"+blocked.ID, rename, http.Header{"If-Match": {resp.Header.Get("ETag")}}, http.StatusNoContent)
+	a.expect(editor, http.MethodPatch, base+"/columns/"+uuid.New().String(), rename, nil, http.StatusNotFound)
+
+	resp, data = a.expect(editor, http.MethodGet, base, nil, nil, http.StatusOK)
+	unmarshal(t, data, &p)
+	reversed := make([]string, len(p.ColumnOrder))
+	for i, name := range p.ColumnOrder {
+		reversed[len(reversed)-1-i] = name
+	}
+	a.expect(editor, http.MethodPut, base+"/columns/order", map[string][]string{"columnOrder": reversed[1:]}, nil, http.StatusBadRequest)
+	a.expect(editor, http.MethodPut, base+"/columns/order", map[string][]string{"columnOrder": reversed}, http.Header{"If-Match": {resp.Header.Get("ETag")}}, http.StatusNoContent)
+
+	// Columns are listed in the column order of the project.
+	_, data = a.expect(editor, http.MethodGet, base+"/columns", nil, nil, http.StatusOK)
+	unmarshal(t, data, &cols)
+	for i, c := range cols {
+		if i >= len(reversed) || c.ColumnName != reversed[i] {
+			t.Errorf("columns after reorder: column %d is %q, want order %v", i, c.ColumnName, reversed)
+			break
+		}
+	}
+
+	// Labels.
+	bug := map[string]string{"name": "bug", "color": "#ff0000"}
+	_, data = a.expect(editor, http.MethodPost, base+"/labels", bug, nil, http.StatusCreated)
+	var lb resource
+	unmarshal(t, data, &lb)
+	a.expect(editor, http.MethodPost, base+"/labels", bug, nil, http.StatusConflict)
+	a.expect(editor, http.MethodPost, base+"/labels", map[string]string{"name": "red", "color": "red"}, nil, http.StatusBadRequest)
+	a.expect(editor, http.MethodGet, base+"/labels", nil, nil, http.StatusOK)
+	a.expect(editor, http.MethodPatch, base+"/labels/"+lb.ID, map[string]string{"color": "#00ff00"}, nil, http.StatusNoContent)
+	a.expect(editor, http.MethodPatch, base+"/labels/not-a-uuid", map[string]string{"color": "#00ff00"}, nil, http.StatusBadRequest)
+	a.expect(editor, http.MethodPatch, base+"/labels/"+uuid.New().String(), map[string]string{"color": "#00ff00"}, nil, http.StatusNotFound)
+
+	// Tasks.
+	tasksOf := base + "/columns/" + todo.ID + "/tasks"
+	resp, data = a.expect(editor, http.MethodPost, tasksOf, map[string]interface{}{"title": "first", "labelIds": []string{lb.ID}}, nil, http.StatusCreated)
+	var first resource
+	unmarshal(t, data, &first)
+	firstTag := resp.Header.Get("ETag")
+
+	_, data = a.expect(editor, http.MethodPost, tasksOf, map[string]string{"title": "second", "dueDate": "2020-06-01T10:00:00+02:00"}, nil, http.StatusCreated)
+	var second resource
+	unmarshal(t, data, &second)
+
+	a.expect(editor, http.MethodPost, tasksOf, map[string]string{}, nil, http.StatusBadRequest)
+	a.expect(editor, http.MethodPost, base+"/columns/"+uuid.New().String()+"/tasks", map[string]string{"title": "lost"}, nil, http.StatusNotFound)
+
+	_, data = a.expect(editor, http.MethodGet, base+"/tasks", nil, nil, http.StatusOK)
+	var ts []resource
+	unmarshal(t, data, &ts)
+	if len(ts) != 2 {
+		t.Errorf("tasks: got %d, want 2", len(ts))
+	}
+	a.expect(editor, http.MethodGet, base+"/tasks?q=second", nil, nil, http.StatusOK)
+	for query, want := range map[string]int{"dueBefore=2020-06-01T09:00:00Z": 1, "dueAfter=2020-06-01T09:00:00Z": 0} {
+		_, data = a.expect(editor, http.MethodGet, base+"/tasks?"+query, nil, nil, http.StatusOK)
+		unmarshal(t, data, &ts)
+		if len(ts) != want {
+			t.Errorf("tasks %s: got %d, want %d", query, len(ts), want)
+		}
+	}
+	a.expect(editor, http.MethodGet, base+"/tasks?sort=color", nil, nil, http.StatusBadRequest)
+	a.expect(editor, http.MethodGet, "/v2/projects/"+p.ID+"/tasks?limit=1", nil, nil, http.StatusOK)
+
+	retitle := map[string]string{"title": "renamed"}
+	a.expect(editor, http.MethodPatch, base+"/tasks/"+first.ID, retitle, http.Header{"If-Match": {`"99"`}}, http.StatusPreconditionFailed)
+	a.expect(editor, http.MethodPatch, base+"/tasks/"+first.ID, retitle, http.Header{"If-Match": {firstTag}}, http.StatusNoContent)
+	a.expect(editor, http.MethodPatch, base+"/tasks/not-a-uuid", retitle, nil, http.StatusBadRequest)
+	a.expect(editor, http.MethodPatch, base+"/tasks/"+uuid.New().String(), retitle, nil, http.StatusNotFound)
+
+	move := map[string]interface{}{"from": todo.ID, "to": blocked.ID, "index": 0, "taskIds": []string{first.ID}}
+	a.expect(editor, http.MethodPatch, base+"/tasks/"+second.ID+"/move", move, nil, http.StatusConflict)
+	move["taskIds"] = []string{}
+	a.expect(editor, http.MethodPatch, base+"/tasks/"+second.ID+"/move", move, nil, http.StatusNoContent)
+
+	// Comments.
+	comments := base + "/tasks/" + first.ID + "/comments"
+	_, data = a.expect(editor, http.MethodPost, comments, map[string]string{"content": "On it"}, nil, http.StatusCreated)
+	var cm resource
+	unmarshal(t, data, &cm)
+	a.expect(editor, http.MethodPost, comments, map[string]string{}, nil, http.StatusBadRequest)
+	a.expect(editor, http.MethodPost, base+"/tasks/"+uuid.New().String()+"/comments", map[string]string{"content": "?"}, nil, http.StatusNotFound)
+	a.expect(editor, http.MethodGet, comments, nil, nil, http.StatusOK)
+	a.expect(editor, http.MethodGet, base+"/tasks/not-a-uuid/comments", nil, nil, http.StatusBadRequest)
+	a.expect(tests.OwnerID, http.MethodPatch, comments+"/"+cm.ID, map[string]string{"content": "Mine now"}, nil, http.StatusForbidden)
+	a.expect(editor, http.MethodPatch, comments+"/"+cm.ID, map[string]string{"content": "Done"}, nil, http.StatusNoContent)
+	a.expect(editor, http.MethodPatch, comments+"/"+uuid.New().String(), map[string]string{"content": "?"}, nil, http.StatusNotFound)
+	a.expect(tests.OwnerID, http.MethodDelete, comments+"/"+cm.ID, nil, nil, http.StatusForbidden)
+	a.expect(editor, http.MethodDelete, comments+"/"+cm.ID, nil, nil, http.StatusNoContent)
+	a.expect(editor, http.MethodDelete, comments+"/"+cm.ID, nil, nil, http.StatusNotFound)
+
+	// Activity.
+	_, data = a.expect(editor, http.MethodGet, base+"/activity", nil, nil, http.StatusOK)
+	var page struct {
+		Data []resource `json:"data"`
+	}
+	unmarshal(t, data, &page)
+	if len(page.Data) == 0 {
+		t.Error("activity: got no events")
+	}
+	a.expect(editor, http.MethodGet, base+"/tasks/"+first.ID+"/activity", nil, nil, http.StatusOK)
+	a.expect(editor, http.MethodGet, base+"/tasks/not-a-uuid/activity", nil, nil, http.StatusBadRequest)
+
+	// Archive, trash and restore.
+	a.expect(editor, http.MethodPost, base+"/tasks/"+first.ID+"/archive", nil, nil, http.StatusNoContent)
+	a.expect(editor, http.MethodPost, base+"/tasks/"+first.ID+"/restore", nil, nil, http.StatusNoContent)
+	a.expect(editor, http.MethodPost, base+"/tasks/"+uuid.New().String()+"/archive", nil, nil, http.StatusNotFound)
+
+	a.expect(editor, http.MethodDelete, tasksOf+"/"+first.ID, nil, nil, http.StatusNoContent)
+	a.expect(editor, http.MethodDelete, tasksOf+"/"+first.ID, nil, nil, http.StatusNotFound)
+	a.expect(editor, http.MethodDelete, tasksOf+"/not-a-uuid", nil, nil, http.StatusBadRequest)
+
+	_, data = a.expect(editor, http.MethodGet, base+"/trash", nil, nil, http.StatusOK)
+	unmarshal(t, data, &ts)
+	if len(ts) != 1 || ts[0].ID != first.ID {
+		t.Errorf("trash: got %+v", ts)
+	}
+	a.expect(editor, http.MethodPost, base+"/tasks/"+first.ID+"/restore", nil, nil, http.StatusNoContent)
+	a.expect(editor, http.MethodPost, base+"/tasks/"+first.ID+"/restore", nil, nil, http.StatusNotFound)
+
+	// A column holding tasks needs a policy to be deleted.
+	col := base + "/columns/" + blocked.ID
+	a.expect(editor, http.MethodDelete, col, nil, nil, http.StatusConflict)
+	a.expect(editor, http.MethodDelete, col+"?tasks=shred", nil, nil, http.StatusBadRequest)
+	a.expect(editor, http.MethodDelete, col+"?tasks=move&to="+blocked.ID, nil, nil, http.StatusBadRequest)
+	a.expect(editor, http.MethodDelete, col+"?tasks=move&to="+todo.ID, nil, nil, http.StatusNoContent)
+
+	_, data = a.expect(editor, http.MethodGet, base+"/columns/"+todo.ID, nil, nil, http.StatusOK)
+	var got resource
+	unmarshal(t, data, &got)
+	if len(got.TaskIDs) != 2 {
+		t.Errorf("tasks of column after move: got %d, want 2", len(got.TaskIDs))
+	}
+
+	// Deleted with their column, tasks go to the trash.
+	a.expect(editor, http.MethodDelete, base+"/columns/"+todo.ID+"?tasks=delete", nil, nil, http.StatusNoContent)
+	_, data = a.expect(editor, http.MethodGet, base+"/trash", nil, nil, http.StatusOK)
+	unmarshal(t, data, &ts)
+	if len(ts) != 2 {
+		t.Errorf("trash after deleting column: got %d tasks, want 2", len(ts))
+	}
+
+	a.expect(editor, http.MethodDelete, base+"/labels/"+lb.ID, nil, nil, http.StatusNoContent)
+	a.expect(editor, http.MethodDelete, base+"/labels/"+lb.ID, nil, nil, http.StatusNotFound)
+}
+
+// Concurrent moves on one board are applied one after the other: moves
+// expecting the same destination order all but one fail, moves expecting
+// nothing all land, each once.
+func TestAPIConcurrentMoves(t *testing.T) {
+	a := newAPI(t)
+
+	_, data := a.expect(tests.OwnerID, http.MethodPost, "/v1/projects", map[string]string{"name": "Race"}, nil, http.StatusCreated)
+	var p resource
+	unmarshal(t, data, &p)
+	base := "/v1/projects/" + p.ID
+
+	_, data = a.expect(tests.OwnerID, http.MethodGet, base+"/columns", nil, nil, http.StatusOK)
+	var cols []resource
+	unmarshal(t, data, &cols)
+	from, to := cols[0], cols[1]
+
+	const n = 8
+	tids := make([]string, n)
+	for i := range tids {
+		_, data = a.expect(tests.OwnerID, http.MethodPost, base+"/columns/"+from.ID+"/tasks", map[string]string{"title": "racer"}, nil, http.StatusCreated)
+		var ts resource
+		unmarshal(t, data, &ts)
+		tids[i] = ts.ID
+	}
+
+	// move moves every task to the top of the destination column at once and
+	// returns the number of moves that were applied.
+	move := func(taskIds []string) int {
+		token := a.token(tests.OwnerID, a.key, nil)
+		statuses := make(chan int, n)
+
+		var wg sync.WaitGroup
+		for _, tid := range tids {
+			wg.Add(1)
+			go func(tid string) {
+				defer wg.Done()
+				mt := map[string]interface{}{"from": from.ID, "to": to.ID, "index": 0, "taskIds": taskIds}
+				resp, _ := a.do(token, http.MethodPatch, base+"/tasks/"+tid+"/move", mt, nil)
+				statuses <- resp.StatusCode
+			}(tid)
+		}
+		wg.Wait()
+		close(statuses)
+
+		moved := 0
+		for status := range statuses {
+			switch status {
+			case http.StatusNoContent:
+				moved++
+			case http.StatusConflict, http.StatusNotFound:
+			default:
+				t.Errorf("move: got status %d", status)
+			}
+		}
+		return moved
+	}
+
+	// column returns the ids of the tasks of a column, failing on duplicates.
+	column := func(cid string) []string {
+		_, data := a.expect(tests.OwnerID, http.MethodGet, base+"/columns/"+cid, nil, nil, http.StatusOK)
+		var c resource
+		unmarshal(t, data, &c)
+		seen := make(map[string]bool)
+		for _, id := range c.TaskIDs {
+			if seen[id] {
+				t.Errorf("column %s: task %s listed twice", cid, id)
+			}
+			seen[id] = true
+		}
+		return c.TaskIDs
+	}
+
+	if moved := move([]string{}); moved != 1 {
+		t.Errorf("moves expecting an empty column: got %d applied, want 1", moved)
+	}
+	if got := len(column(to.ID)); got != 1 {
+		t.Errorf("destination after first race: got %d tasks, want 1", got)
+	}
+
+	// The task already moved is in the destination, its move fails.
+	if moved := move(nil); moved != n-1 {
+		t.Errorf("moves expecting nothing: got %d applied, want %d", moved, n-1)
+	}
+	if got := len(column(to.ID)); got != n {
+		t.Errorf("destination after second race: got %d tasks, want %d", got, n)
+	}
+	if got := len(column(from.ID)); got != 0 {
+		t.Errorf("source after second race: got %d tasks, want 0", got)
+	}
+}
+
+func TestAPIEvents(t *testing.T) {
+	a := newAPI(t)
+
+	_, data := a.expect(tests.OwnerID, http.MethodPost, "/v1/projects", map[string]string{"name": "Live"}, nil, http.StatusCreated)
+	var p resource
+	unmarshal(t, data, &p)
+
+	// EventSource clients pass the token as a query parameter.
+	token := a.token(tests.OwnerID, a.key, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequest(http.MethodGet, a.srv.URL+"/v1/projects/"+p.ID+"/events?access_token="+token, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := a.srv.Client().Do(req.WithContext(ctx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status: got %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("content type: got %q, want text/event-stream", ct)
+	}
+
+	a.expect(tests.StrangerID, http.MethodGet, "/v1/projects/"+p.ID+"/events", nil, nil, http.StatusNotFound)
+
+	// Other routes only take the token from the Authorization header.
+	req, err = http.NewRequest(http.MethodGet, a.srv.URL+"/v1/projects?access_token="+token, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err = a.srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("query token on projects: got %d, want %d", resp.StatusCode, http.StatusUnauthorized)
+	}
+
+	// The stream ends when its access token expires.
+	expiring := a.token(tests.OwnerID, a.key, func(c jwt.MapClaims) {
+		c["exp"] = time.Now().Add(2 * time.Second).Unix()
+	})
+	req, err = http.NewRequest(http.MethodGet, a.srv.URL+"/v1/projects/"+p.ID+"/events?access_token="+expiring, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err = a.srv.Client().Do(req.WithContext(ctx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if _, err := ioutil.ReadAll(resp.Body); err != nil {
+		t.Errorf("stream of expiring token: %v, want it closed at expiry", err)
+	}
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"github.com/ivorscott/devpie-client-backend-go/internal/mid"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/ivorscott/devpie-client-backend-go/internal/label"
+	"github.com/ivorscott/devpie-client-backend-go/internal/platform/database"
+	"github.com/ivorscott/devpie-client-backend-go/internal/platform/web"
+	"github.com/pkg/errors"
+)
+
+// Labels holds the application state needed by the handler methods.
+type Labels struct {
+	repo *database.Repository
+	log  *log.Logger
+	auth *mid.Auth
+}
+
+// List gets all labels of a project
+func (l *Labels) List(w http.ResponseWriter, r *http.Request) error {
+	pid := chi.URLParam(r, "pid")
+
+	list, err := label.List(r.Context(), l.repo, pid)
+	if err != nil {
+		return err
+	}
+
+	return web.Respond(r.Context(), w, list, http.StatusOK)
+}
+
+// Create a new Label
+func (l *Labels) Create(w http.ResponseWriter, r *http.Request) error {
+	pid := chi.URLParam(r, "pid")
+
+	var nl label.NewLabel
+	if err := web.Decode(r, &nl); err != nil {
+		return err
+	}
+
+	lb, err := label.Create(r.Context(), l.repo, nl, pid, time.Now())
+	if err != nil {
+		switch err {
+		case label.ErrDuplicate:
+			return web.NewRequestError(err, http.StatusConflict)
+		default:
+			return errors.Wrapf(err, "creating label %v", nl)
+		}
+	}
+
+	return web.Respond(r.Context(), w, lb, http.StatusCreated)
+}
+
+// Update decodes the body of a request to update an existing label. The ID
+// of the label is part of the request URL.
+func (l *Labels) Update(w http.ResponseWriter, r *http.Request) error {
+	pid := chi.URLParam(r, "pid")
+	lid := chi.URLParam(r, "lid")
+
+	var ul label.UpdateLabel
+	if err := web.Decode(r, &ul); err != nil {
+		return errors.Wrap(err, "decoding label update")
+	}
+
+	if err := label.Update(r.Context(), l.repo, pid, lid, ul); err != nil {
+		switch err {
+		case label.ErrNotFound:
+			return web.NewRequestError(err, http.StatusNotFound)
+		case label.ErrInvalidID:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		case label.ErrDuplicate:
+			return web.NewRequestError(err, http.StatusConflict)
+		default:
+			return errors.Wrapf(err, "updating label %q", lid)
+		}
+	}
+
+	return web.Respond(r.Context(), w, nil, http.StatusNoContent)
+}
+
+// Delete removes a single label identified by an ID in the request URL.
+func (l *Labels) Delete(w http.ResponseWriter, r *http.Request) error {
+	pid := chi.URLParam(r, "pid")
+	lid := chi.URLParam(r, "lid")
+
+	if err := label.Delete(r.Context(), l.repo, pid, lid); err != nil {
+		switch err {
+		case label.ErrNotFound:
+			return web.NewRequestError(err, http.StatusNotFound)
+		case label.ErrInvalidID:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return errors.Wrapf(err, "deleting label %q", lid)
+		}
+	}
+
+	return web.Respond(r.Context(), w, nil, http.StatusNoContent)
+}
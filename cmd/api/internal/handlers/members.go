@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/ivorscott/devpie-client-backend-go/internal/member"
+	"github.com/ivorscott/devpie-client-backend-go/internal/mid"
+	"github.com/ivorscott/devpie-client-backend-go/internal/platform/database"
+	"github.com/ivorscott/devpie-client-backend-go/internal/platform/web"
+	"github.com/ivorscott/devpie-client-backend-go/internal/project"
+	"github.com/ivorscott/devpie-client-backend-go/internal/task"
+	"github.com/ivorscott/devpie-client-backend-go/internal/user"
+	"github.com/pkg/errors"
+)
+
+// Members holds the application state needed by the handler methods.
+type Members struct {
+	repo  *database.Repository
+	users user.Store
+	log   *log.Logger
+	auth  *mid.Auth
+}
+
+// List gets all members of a project, including pending invites
+func (m *Members) List(w http.ResponseWriter, r *http.Request) error {
+	pid := chi.URLParam(r, "pid")
+
+	list, err := member.List(r.Context(), m.repo, pid)
+	if err != nil {
+		return err
+	}
+
+	return web.Respond(r.Context(), w, list, http.StatusOK)
+}
+
+// ListInvites gets the pending project invites of the authenticated user
+func (m *Members) ListInvites(w http.ResponseWriter, r *http.Request) error {
+	uid := m.auth.GetUserById(r)
+
+	list, err := member.ListInvites(r.Context(), m.repo, uid)
+	if err != nil {
+		return err
+	}
+
+	return web.Respond(r.Context(), w, list, http.StatusOK)
+}
+
+// Invite adds a pending member to a project. Only the owner can invite.
+func (m *Members) Invite(w http.ResponseWriter, r *http.Request) error {
+	pid := chi.URLParam(r, "pid")
+	uid := m.auth.GetUserById(r)
+
+	var ni member.NewInvite
+	if err := web.Decode(r, &ni); err != nil {
+		return err
+	}
+
+	us, err := m.users.RetrieveByEmail(r.Context(), ni.Email)
+	if err != nil {
+		switch err {
+		case user.ErrNotFound:
+			return web.NewRequestError(err, http.StatusNotFound)
+		default:
+			return errors.Wrapf(err, "looking for user %q", ni.Email)
+		}
+	}
+
+	mb, err := member.Create(r.Context(), m.repo, pid, us.ID, ni.Role, &uid, time.Now())
+	if err != nil {
+		switch err {
+		case member.ErrAlreadyMember:
+			return web.NewRequestError(err, http.StatusConflict)
+		default:
+			return errors.Wrapf(err, "inviting user %q", us.ID)
+		}
+	}
+
+	return web.Respond(r.Context(), w, mb, http.StatusCreated)
+}
+
+// Accept accepts the authenticated user's pending invite to a project.
+func (m *Members) Accept(w http.ResponseWriter, r *http.Request) error {
+	pid := chi.URLParam(r, "pid")
+	uid := m.auth.GetUserById(r)
+
+	if err := member.Accept(r.Context(), m.repo, pid, uid); err != nil {
+		switch err {
+		case member.ErrNotFound:
+			return web.NewRequestError(err, http.StatusNotFound)
+		case member.ErrInvalidID:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return errors.Wrapf(err, "accepting invite to project %q", pid)
+		}
+	}
+
+	return web.Respond(r.Context(), w, nil, http.StatusNoContent)
+}
+
+// Remove deletes a member from a project and unassigns them from its tasks.
+// The owner can remove anyone but themselves, every other member can only
+// remove themselves. Pending members remove themselves to decline an invite.
+func (m *Members) Remove(w http.ResponseWriter, r *http.Request) error {
+	pid := chi.URLParam(r, "pid")
+	target := chi.URLParam(r, "uid")
+	uid := m.auth.GetUserById(r)
+
+	if target != uid {
+		caller, err := member.Retrieve(r.Context(), m.repo, pid, uid)
+		if err != nil {
+			switch err {
+			case member.ErrNotFound:
+				// Access turns strangers away.
+			case member.ErrInvalidID:
+				return web.NewRequestError(project.ErrInvalidID, http.StatusBadRequest)
+			default:
+				return errors.Wrapf(err, "looking for member %q of project %q", uid, pid)
+			}
+		}
+
+		switch err := member.Access(caller, member.RoleOwner); err {
+		case nil:
+		case member.ErrNotFound:
+			return web.NewRequestError(project.ErrNotFound, http.StatusNotFound)
+		default:
+			return web.NewRequestError(err, http.StatusForbidden)
+		}
+	}
+
+	ctx, err := m.repo.Begin(r.Context())
+	if err != nil {
+		return err
+	}
+	defer m.repo.Rollback(ctx)
+
+	if err := member.Delete(ctx, m.repo, pid, target); err != nil {
+		switch err {
+		case member.ErrNotFound:
+			return web.NewRequestError(err, http.StatusNotFound)
+		case member.ErrInvalidID, member.ErrOwnerRemoval:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return errors.Wrapf(err, "removing member %q", target)
+		}
+	}
+
+	if err := task.UnassignMember(ctx, m.repo, pid, target); err != nil {
+		return errors.Wrapf(err, "removing member %q", target)
+	}
+
+	if err := m.repo.Commit(ctx); err != nil {
+		return errors.Wrapf(err, "removing member %q", target)
+	}
+
+	return web.Respond(r.Context(), w, nil, http.StatusNoContent)
+}
@@ -1,11 +1,13 @@
 package handlers
 
 import (
-	"fmt"
+	"github.com/ivorscott/devpie-client-backend-go/internal/activity"
 	"github.com/ivorscott/devpie-client-backend-go/internal/column"
+	"github.com/ivorscott/devpie-client-backend-go/internal/member"
 	"github.com/ivorscott/devpie-client-backend-go/internal/mid"
 	"github.com/ivorscott/devpie-client-backend-go/internal/project"
 	"github.com/ivorscott/devpie-client-backend-go/internal/task"
+	"github.com/ivorscott/devpie-client-backend-go/internal/template"
 	"log"
 	"net/http"
 	"time"
@@ -18,16 +20,23 @@ import (
 
 // Project holds the application state needed by the handler methods.
 type Projects struct {
-	repo  *database.Repository
-	log   *log.Logger
-	auth0 *mid.Auth0
+	unit     database.Unit
+	repo     *database.Repository
+	projects project.Store
+	columns  column.Store
+	tasks    task.Store
+	activity activity.Recorder
+	log      *log.Logger
+	auth     *mid.Auth
 }
 
 // List gets all Project
 func (p *Projects) List(w http.ResponseWriter, r *http.Request) error {
-	id := p.auth0.GetUserById(r)
+	id := p.auth.GetUserById(r)
 
-	list, err := project.List(r.Context(), p.repo, id)
+	archived := r.URL.Query().Get("archived") == "true"
+
+	list, err := p.projects.List(r.Context(), id, archived, database.Page{})
 	if err != nil {
 		return err
 	}
@@ -35,29 +44,38 @@ func (p *Projects) List(w http.ResponseWriter, r *http.Request) error {
 	return web.Respond(r.Context(), w, list, http.StatusOK)
 }
 
-// Retrieve a single Project
-func (p *Projects) Retrieve(w http.ResponseWriter, r *http.Request) error {
-	pid := chi.URLParam(r, "pid")
-	uid := p.auth0.GetUserById(r)
+// ListPage gets a page of the projects of the user, see web.ParsePage.
+func (p *Projects) ListPage(w http.ResponseWriter, r *http.Request) error {
+	id := p.auth.GetUserById(r)
+
+	pr, err := web.ParsePage(r)
+	if err != nil {
+		return err
+	}
+
+	archived := r.URL.Query().Get("archived") == "true"
 
-	pr, err := project.Retrieve(r.Context(), p.repo, pid, uid)
+	list, err := p.projects.List(r.Context(), id, archived, database.Page{Limit: pr.Limit + 1, After: pr.After})
 	if err != nil {
 		switch err {
-		case project.ErrNotFound:
-			return web.NewRequestError(err, http.StatusNotFound)
-		case project.ErrInvalidID:
+		case database.ErrInvalidPage:
 			return web.NewRequestError(err, http.StatusBadRequest)
 		default:
-			return errors.Wrapf(err, "looking for projects %q", pid)
+			return err
 		}
 	}
 
-	return web.Respond(r.Context(), w, pr, http.StatusOK)
+	return web.Respond(r.Context(), w, web.NewPage(pr, list, func(i int) []interface{} { return list[i].Key() }), http.StatusOK)
+}
+
+// Retrieve a single Project
+func (p *Projects) Retrieve(w http.ResponseWriter, r *http.Request) error {
+	return web.Respond(r.Context(), w, mid.CurrentProject(r.Context()), http.StatusOK)
 }
 
 // Create a new Project
 func (p *Projects) Create(w http.ResponseWriter, r *http.Request) error {
-	uid := p.auth0.GetUserById(r)
+	uid := p.auth.GetUserById(r)
 
 	var np project.NewProject
 	if err := web.Decode(r, &np); err != nil {
@@ -65,23 +83,71 @@ func (p *Projects) Create(w http.ResponseWriter, r *http.Request) error {
 		return err
 	}
 
-	pr, err := project.Create(r.Context(), p.repo, np, uid, time.Now())
+	tid := template.DefaultID
+	if np.TemplateID != nil {
+		tid = *np.TemplateID
+	}
+
+	// The project, its owner and the template's columns and starter tasks
+	// are created together or not at all.
+	ctx, err := p.unit.Begin(r.Context())
 	if err != nil {
 		return err
 	}
+	defer p.unit.Rollback(ctx)
 
-	// create default columns for project
-	titles := [4]string{"To Do", "In Progress", "Review", "Done"}
-	for i, title := range titles {
-		nt := column.NewColumn{
-			ProjectID:  pr.ID,
-			Title:      title,
-			ColumnName: fmt.Sprintf(`column-%d`, i+1),
+	tm, err := template.Retrieve(ctx, p.repo, tid, uid)
+	if err != nil {
+		switch err {
+		case template.ErrNotFound:
+			return web.NewRequestError(err, http.StatusNotFound)
+		case template.ErrInvalidID:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return errors.Wrapf(err, "looking for template %q", tid)
 		}
-		_, err := column.Create(r.Context(), p.repo, nt, time.Now())
+	}
+
+	pr, err := p.projects.Create(ctx, np, uid, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if _, err := member.Create(ctx, p.repo, pr.ID, uid, member.RoleOwner, nil, time.Now()); err != nil {
+		return err
+	}
+
+	// create the template's columns and starter tasks for project
+	for _, tc := range tm.Columns {
+		nc := column.NewColumn{
+			ProjectID: pr.ID,
+			Title:     tc.Title,
+		}
+		c, err := p.columns.Create(ctx, nc, time.Now())
 		if err != nil {
 			return err
 		}
+		pr.ColumnOrder = append(pr.ColumnOrder, c.ColumnName)
+		pr.Version++
+
+		for _, tt := range tc.Tasks {
+			nt := task.NewTask{Title: tt.Title, Content: tt.Content}
+			if _, err := p.tasks.Create(ctx, nt, pr.ID, c.ID, time.Now()); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := record(ctx, r, p.activity, p.auth, activity.NewEvent{
+		ProjectID: pr.ID,
+		Action:    activity.ProjectCreated,
+		After:     pr,
+	}); err != nil {
+		return err
+	}
+
+	if err := p.unit.Commit(ctx); err != nil {
+		return errors.Wrapf(err, "creating project %q", np.Name)
 	}
 
 	return web.Respond(r.Context(), w, pr, http.StatusCreated)
@@ -91,53 +157,197 @@ func (p *Projects) Create(w http.ResponseWriter, r *http.Request) error {
 // of the project is part of the request URL.
 func (p *Projects) Update(w http.ResponseWriter, r *http.Request) error {
 	pid := chi.URLParam(r, "pid")
-	uid := p.auth0.GetUserById(r)
+	uid := p.auth.GetUserById(r)
 
 	var update project.UpdateProject
 	if err := web.Decode(r, &update); err != nil {
 		return errors.Wrap(err, "decoding project update")
 	}
 
-	if err := project.Update(r.Context(), p.repo, pid, update, uid); err != nil {
+	versions, err := web.IfMatch(r)
+	if err != nil {
+		return err
+	}
+
+	ctx, err := p.unit.Begin(r.Context())
+	if err != nil {
+		return err
+	}
+	defer p.unit.Rollback(ctx)
+
+	before, err := p.projects.Update(ctx, pid, update, uid, versions)
+	if err != nil {
 		switch err {
 		case project.ErrNotFound:
 			return web.NewRequestError(err, http.StatusNotFound)
-		case project.ErrInvalidID:
+		case project.ErrInvalidID, project.ErrInvalidColumnOrder:
 			return web.NewRequestError(err, http.StatusBadRequest)
+		case project.ErrConflict:
+			return web.NewRequestError(err, http.StatusPreconditionFailed)
 		default:
 			return errors.Wrapf(err, "updating project %q", pid)
 		}
 	}
 
+	after, err := p.projects.Retrieve(ctx, pid, uid)
+	if err != nil {
+		return errors.Wrapf(err, "looking for updated project %q", pid)
+	}
+
+	if err := record(ctx, r, p.activity, p.auth, activity.NewEvent{
+		ProjectID: pid,
+		Action:    activity.ProjectUpdated,
+		Before:    before,
+		After:     after,
+	}); err != nil {
+		return err
+	}
+
+	if err := p.unit.Commit(ctx); err != nil {
+		return errors.Wrapf(err, "updating project %q", pid)
+	}
+
 	return web.Respond(r.Context(), w, nil, http.StatusNoContent)
 }
 
-// Delete removes a single Project identified by an ID in the request URL.
+// Delete moves a single Project identified by an ID in the request URL to
+// the trash. Only the project owner can delete it.
 func (p *Projects) Delete(w http.ResponseWriter, r *http.Request) error {
 	pid := chi.URLParam(r, "pid")
-	uid := p.auth0.GetUserById(r)
 
-	pr, err := project.Retrieve(r.Context(), p.repo, pid, uid)
+	versions, err := web.IfMatch(r)
 	if err != nil {
 		return err
 	}
-	if pr != nil {
-		if err := task.DeleteAll(r.Context(), p.repo, pid); err != nil {
-			return err
+
+	ctx, err := p.unit.Begin(r.Context())
+	if err != nil {
+		return err
+	}
+	defer p.unit.Rollback(ctx)
+
+	if err := p.projects.Delete(ctx, pid, versions, time.Now()); err != nil {
+		switch err {
+		case project.ErrNotFound:
+			return web.NewRequestError(err, http.StatusNotFound)
+		case project.ErrInvalidID:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		case project.ErrConflict:
+			return web.NewRequestError(err, http.StatusPreconditionFailed)
+		default:
+			return errors.Wrapf(err, "deleting project %q", pid)
 		}
-		if err := column.DeleteAll(r.Context(), p.repo, pid); err != nil {
-			return err
+	}
+
+	if err := record(ctx, r, p.activity, p.auth, activity.NewEvent{
+		ProjectID: pid,
+		Action:    activity.ProjectDeleted,
+	}); err != nil {
+		return err
+	}
+
+	if err := p.unit.Commit(ctx); err != nil {
+		return errors.Wrapf(err, "deleting project %q", pid)
+	}
+
+	return web.Respond(r.Context(), w, nil, http.StatusNoContent)
+}
+
+// Archive closes a Project so it is only listed with ?archived=true.
+func (p *Projects) Archive(w http.ResponseWriter, r *http.Request) error {
+	pid := chi.URLParam(r, "pid")
+
+	versions, err := web.IfMatch(r)
+	if err != nil {
+		return err
+	}
+
+	ctx, err := p.unit.Begin(r.Context())
+	if err != nil {
+		return err
+	}
+	defer p.unit.Rollback(ctx)
+
+	if err := p.projects.Archive(ctx, pid, versions); err != nil {
+		switch err {
+		case project.ErrNotFound:
+			return web.NewRequestError(err, http.StatusNotFound)
+		case project.ErrInvalidID:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		case project.ErrConflict:
+			return web.NewRequestError(err, http.StatusPreconditionFailed)
+		default:
+			return errors.Wrapf(err, "archiving project %q", pid)
 		}
-		if err := project.Delete(r.Context(), p.repo, pid, uid); err != nil {
-			switch err {
-			case project.ErrInvalidID:
-				return web.NewRequestError(err, http.StatusBadRequest)
-			default:
-				return errors.Wrapf(err, "deleting project %q", pid)
-			}
+	}
+
+	if err := record(ctx, r, p.activity, p.auth, activity.NewEvent{
+		ProjectID: pid,
+		Action:    activity.ProjectArchived,
+	}); err != nil {
+		return err
+	}
+
+	if err := p.unit.Commit(ctx); err != nil {
+		return errors.Wrapf(err, "archiving project %q", pid)
+	}
+
+	return web.Respond(r.Context(), w, nil, http.StatusNoContent)
+}
+
+// Restore reopens an archived Project or takes it out of the trash. Only the
+// project owner can restore it.
+func (p *Projects) Restore(w http.ResponseWriter, r *http.Request) error {
+	pid := chi.URLParam(r, "pid")
+	uid := p.auth.GetUserById(r)
+
+	versions, err := web.IfMatch(r)
+	if err != nil {
+		return err
+	}
+
+	ctx, err := p.unit.Begin(r.Context())
+	if err != nil {
+		return err
+	}
+	defer p.unit.Rollback(ctx)
+
+	if err := p.projects.Restore(ctx, pid, uid, versions); err != nil {
+		switch err {
+		case project.ErrNotFound:
+			return web.NewRequestError(err, http.StatusNotFound)
+		case project.ErrInvalidID:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		case project.ErrConflict:
+			return web.NewRequestError(err, http.StatusPreconditionFailed)
+		default:
+			return errors.Wrapf(err, "restoring project %q", pid)
 		}
 	}
 
+	if err := record(ctx, r, p.activity, p.auth, activity.NewEvent{
+		ProjectID: pid,
+		Action:    activity.ProjectRestored,
+	}); err != nil {
+		return err
+	}
+
+	if err := p.unit.Commit(ctx); err != nil {
+		return errors.Wrapf(err, "restoring project %q", pid)
+	}
 
 	return web.Respond(r.Context(), w, nil, http.StatusNoContent)
 }
+
+// Trash gets the deleted projects of the user. They are purged after the
+// retention period.
+func (p *Projects) Trash(w http.ResponseWriter, r *http.Request) error {
+	uid := p.auth.GetUserById(r)
+
+	list, err := p.projects.ListTrash(r.Context(), uid)
+	if err != nil {
+		return err
+	}
+
+	return web.Respond(r.Context(), w, list, http.StatusOK)
+}
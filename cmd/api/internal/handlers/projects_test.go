@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ivorscott/devpie-client-backend-go/internal/member"
+	"github.com/ivorscott/devpie-client-backend-go/internal/platform/database"
+	"github.com/ivorscott/devpie-client-backend-go/internal/project"
+)
+
+func (s *stores) projectHandlers() *Projects {
+	return &Projects{unit: noUnit{}, projects: s.projects, columns: s.columns, tasks: s.tasks, activity: s.activity, log: s.log, auth: s.auth}
+}
+
+func TestProjectsListPage(t *testing.T) {
+	s := newStores()
+	h := s.projectHandlers()
+	ctx := context.Background()
+	start := time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	create := func(name string, created time.Time) *project.Project {
+		p, err := s.projects.Create(ctx, project.NewProject{Name: name}, testUser, created)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+
+	var ps []*project.Project
+	for i, name := range []string{"p0", "p1", "p2", "p3", "p4"} {
+		ps = append(ps, create(name, start.Add(time.Duration(i)*time.Hour)))
+	}
+
+	rt := route{method: http.MethodGet, pattern: "/v2/projects", handler: h.ListPage}
+
+	var names []string
+	var cursor string
+	target := "/v2/projects?limit=2"
+	for n := 0; ; n++ {
+		var page struct {
+			Data       []project.Project `json:"data"`
+			NextCursor *string           `json:"nextCursor"`
+		}
+
+		rec := s.serve(t, rt, testUser, target, nil, nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("page %d: got %d, want %d: %s", n, rec.Code, http.StatusOK, rec.Body)
+		}
+		decode(t, rec, &page)
+		for _, p := range page.Data {
+			names = append(names, p.Name)
+		}
+		if page.NextCursor == nil {
+			break
+		}
+		cursor = *page.NextCursor
+		target = "/v2/projects?limit=2&cursor=" + cursor
+
+		// Changes before the position of the cursor don't move it.
+		switch n {
+		case 0:
+			if err := s.projects.Delete(ctx, ps[0].ID, database.Versions{ps[0].CurrentVersion()}, time.Now()); err != nil {
+				t.Fatal(err)
+			}
+		case 1:
+			create("early", start.Add(-time.Hour))
+		}
+	}
+
+	want := []string{"p0", "p1", "p2", "p3", "p4"}
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Errorf("pages: got %v, want %v", names, want)
+	}
+
+	rec := s.serve(t, rt, testUser, "/v2/projects?archived=true&limit=2&cursor="+cursor, nil, nil)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("cursor of another listing: got %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestProjectsUpdate(t *testing.T) {
+	s := newStores()
+	h := s.projectHandlers()
+	p, _ := s.board(t)
+
+	update := route{http.MethodPut, "/projects/{pid}", h.Update, member.RoleEditor}
+	target := "/projects/" + p.ID
+
+	rec := s.serve(t, update, testUser, target, map[string]interface{}{"name": "Renamed"}, nil)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("rename: got %d, want %d: %s", rec.Code, http.StatusNoContent, rec.Body)
+	}
+
+	// Projects are archived through the owner only archive route.
+	rec = s.serve(t, update, testUser, target, map[string]interface{}{"name": "Closed", "open": false}, nil)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("update open: got %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	got, err := s.projects.Retrieve(context.Background(), p.ID, testUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "Renamed" || !got.Open {
+		t.Errorf("project: got %+v, want it renamed and still open", got)
+	}
+}
+
+func TestProjectsDeleteAndRestore(t *testing.T) {
+	s := newStores()
+	h := s.projectHandlers()
+	p, _ := s.board(t)
+
+	del := route{http.MethodDelete, "/projects/{pid}", h.Delete, member.RoleOwner}
+	target := "/projects/" + p.ID
+
+	rec := s.serve(t, del, testUser, target, nil, ifMatch(p.Version+1))
+	if rec.Code != http.StatusPreconditionFailed {
+		t.Fatalf("stale version: got %d, want %d", rec.Code, http.StatusPreconditionFailed)
+	}
+
+	rec = s.serve(t, del, testUser, target, nil, ifMatch(p.Version))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("delete: got %d, want %d: %s", rec.Code, http.StatusNoContent, rec.Body)
+	}
+
+	retrieve := route{http.MethodGet, "/projects/{pid}", h.Retrieve, member.RoleViewer}
+	if rec := s.serve(t, retrieve, testUser, target, nil, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("deleted project: got %d, want %d", rec.Code, http.StatusNotFound)
+	}
+
+	trash := route{method: http.MethodGet, pattern: "/projects/trash", handler: h.Trash}
+	var got []project.Project
+	decode(t, s.serve(t, trash, testUser, "/projects/trash", nil, nil), &got)
+	if len(got) != 1 || got[0].ID != p.ID {
+		t.Fatalf("trash: got %+v", got)
+	}
+
+	restore := route{method: http.MethodPost, pattern: "/projects/{pid}/restore", handler: h.Restore}
+	if rec := s.serve(t, restore, "0b2a0d4e-8a43-4c51-a4ba-4a7f1f0f5d55", target+"/restore", nil, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("restore by another user: got %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	if rec := s.serve(t, restore, testUser, target+"/restore", nil, nil); rec.Code != http.StatusNoContent {
+		t.Fatalf("restore: got %d, want %d: %s", rec.Code, http.StatusNoContent, rec.Body)
+	}
+
+	if _, err := s.projects.Retrieve(context.Background(), p.ID, testUser); err != nil {
+		t.Errorf("restored project: %v", err)
+	}
+}
+
+func TestProjectsArchive(t *testing.T) {
+	s := newStores()
+	h := s.projectHandlers()
+	p, _ := s.board(t)
+	s.projects.Create(context.Background(), project.NewProject{Name: "other"}, testUser, time.Now())
+
+	archive := route{http.MethodPost, "/projects/{pid}/archive", h.Archive, member.RoleOwner}
+	if rec := s.serve(t, archive, testUser, "/projects/"+p.ID+"/archive", nil, ifMatch(p.Version+1)); rec.Code != http.StatusPreconditionFailed {
+		t.Fatalf("stale archive: got %d, want %d: %s", rec.Code, http.StatusPreconditionFailed, rec.Body)
+	}
+	if rec := s.serve(t, archive, testUser, "/projects/"+p.ID+"/archive", nil, ifMatch(p.Version)); rec.Code != http.StatusNoContent {
+		t.Fatalf("archive: got %d, want %d: %s", rec.Code, http.StatusNoContent, rec.Body)
+	}
+
+	list := route{method: http.MethodGet, pattern: "/projects", handler: h.List}
+
+	var open, archived []project.Project
+	decode(t, s.serve(t, list, testUser, "/projects", nil, nil), &open)
+	decode(t, s.serve(t, list, testUser, "/projects?archived=true", nil, nil), &archived)
+
+	if len(open) != 1 || open[0].ID == p.ID {
+		t.Errorf("open projects: got %+v", open)
+	}
+	if len(archived) != 1 || archived[0].ID != p.ID {
+		t.Errorf("archived projects: got %+v", archived)
+	}
+}
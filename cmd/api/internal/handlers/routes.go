@@ -1,58 +1,136 @@
 package handlers
 
 import (
+	"github.com/ivorscott/devpie-client-backend-go/internal/activity"
+	"github.com/ivorscott/devpie-client-backend-go/internal/column"
+	"github.com/ivorscott/devpie-client-backend-go/internal/identity"
+	"github.com/ivorscott/devpie-client-backend-go/internal/member"
 	"github.com/ivorscott/devpie-client-backend-go/internal/mid"
 	"github.com/ivorscott/devpie-client-backend-go/internal/platform/database"
+	"github.com/ivorscott/devpie-client-backend-go/internal/platform/hub"
 	"github.com/ivorscott/devpie-client-backend-go/internal/platform/web"
+	"github.com/ivorscott/devpie-client-backend-go/internal/project"
+	"github.com/ivorscott/devpie-client-backend-go/internal/task"
+	"github.com/ivorscott/devpie-client-backend-go/internal/user"
 	"github.com/rs/cors"
 	"log"
 	"net/http"
 	"os"
 )
 
-func API(shutdown chan os.Signal, repo *database.Repository, log *log.Logger, FrontendAddress,
-	Auth0Audience, Auth0Domain, Auth0M2MClient, Auth0M2MSecret, AuthMAPIAudience string) http.Handler {
+func API(shutdown chan os.Signal, repo *database.Repository, events *hub.Hub, log *log.Logger, FrontendAddress string,
+	auth *mid.Auth) http.Handler {
 
-	auth0 := &mid.Auth0{
-		Audience:     Auth0Audience,
-		Domain:       Auth0Domain,
-		M2MClient:    Auth0M2MClient,
-		M2MSecret:    Auth0M2MSecret,
-		MAPIAudience: AuthMAPIAudience,
-	}
-
-	app := web.NewApp(shutdown, log, mid.Logger(log), auth0.Authenticate(), mid.Errors(log), mid.Panics(log))
+	app := web.NewApp(shutdown, log, mid.Logger(log), mid.Errors(log), mid.Panics(log))
 
 	cor := cors.New(cors.Options{
-		AllowedOrigins: []string{FrontendAddress},
-		AllowedHeaders: []string{"Authorization", "Cache-Control", "Content-Type", "Strict-Transport-Security"},
-		AllowedMethods: []string{http.MethodHead, http.MethodGet, http.MethodPost, http.MethodDelete, http.MethodPatch,
-		},
+		AllowedOrigins:   []string{FrontendAddress},
+		AllowedHeaders:   []string{"Authorization", "Cache-Control", "Content-Type", "If-Match", "Last-Event-ID", "Strict-Transport-Security"},
+		ExposedHeaders:   []string{"ETag"},
+		AllowedMethods:   []string{http.MethodHead, http.MethodGet, http.MethodPost, http.MethodDelete, http.MethodPatch, http.MethodPut},
 		AllowCredentials: true,
 	})
 
 	h := HealthCheck{repo: repo}
 
+	// Public routes don't need an access token.
 	app.Handle(http.MethodGet, "/v1/health", h.Health)
 
-	u := Users{repo: repo, log: log, auth0: auth0}
-	t := Tasks{repo: repo, log: log, auth0: auth0}
-	c := Columns{repo: repo, log: log, auth0: auth0}
-	p := Projects{repo: repo, log: log, auth0: auth0}
-
-	app.Handle(http.MethodPost, "/v1/users", u.Create)
-	app.Handle(http.MethodGet, "/v1/users/me", u.RetrieveMe)
-	app.Handle(http.MethodGet, "/v1/projects", p.List)
-	app.Handle(http.MethodPost, "/v1/projects", p.Create)
-	app.Handle(http.MethodGet, "/v1/projects/{pid}", p.Retrieve)
-	app.Handle(http.MethodPut, "/v1/projects/{pid}", p.Update)
-	app.Handle(http.MethodDelete, "/v1/projects/{pid}", p.Delete)
-	app.Handle(http.MethodGet, "/v1/projects/{pid}/columns", c.List)
-	app.Handle(http.MethodGet, "/v1/projects/{pid}/tasks", t.List)
-	app.Handle(http.MethodPost, "/v1/projects/{pid}/columns/{cid}/tasks", t.Create)
-	app.Handle(http.MethodPatch, "/v1/projects/{pid}/tasks/{tid}", t.Update)
-	app.Handle(http.MethodPatch, "/v1/projects/{pid}/tasks/{tid}/move", t.Move)
-	app.Handle(http.MethodDelete, "/v1/projects/{pid}/columns/{cid}/tasks/{tid}", t.Delete)
+	users := user.NewPostgres(repo)
+
+	// The development auth mode issues its own tokens, see identity.Dev.
+	if dev, ok := auth.Provider.(*identity.Dev); ok {
+		d := Dev{users: users, issuer: dev.Signer, log: log}
+
+		app.Handle(http.MethodGet, "/.well-known/jwks.json", d.JWKS)
+		app.Handle(http.MethodPost, "/v1/dev/token", d.Token)
+	}
+
+	// Every other route does.
+	api := app.Group(auth.Authenticate())
+
+	projects := project.NewPostgres(repo)
+	columns := column.NewPostgres(repo)
+	tasks := task.NewPostgres(repo)
+	history := activity.NewPostgres(repo)
+
+	u := Users{repo: repo, users: users, log: log, auth: auth}
+	t := Tasks{unit: repo, tasks: tasks, activity: history, log: log, auth: auth}
+	c := Columns{unit: repo, columns: columns, projects: projects, activity: history, log: log, auth: auth}
+	p := Projects{unit: repo, repo: repo, projects: projects, columns: columns, tasks: tasks, activity: history, log: log, auth: auth}
+	m := Members{repo: repo, users: users, log: log, auth: auth}
+	tm := Templates{repo: repo, columns: columns, tasks: tasks, log: log, auth: auth}
+	l := Labels{repo: repo, log: log, auth: auth}
+	cm := Comments{repo: repo, log: log, auth: auth}
+	a := Activity{repo: repo, log: log, auth: auth}
+	ev := Events{repo: repo, log: log, auth: auth, hub: events}
+
+	// Project scoped routes load the project once and enforce the caller's role.
+	viewer := mid.Project(repo, auth, member.RoleViewer)
+	editor := mid.Project(repo, auth, member.RoleEditor)
+	owner := mid.Project(repo, auth, member.RoleOwner)
+
+	// Every route requires its scope in the access token, see mid.Require.
+	// The scope is checked before the project is loaded.
+	readUsers := mid.Require(mid.ScopeReadUsers)
+	writeUsers := mid.Require(mid.ScopeWriteUsers)
+	readProjects := mid.Require(mid.ScopeReadProjects)
+	writeProjects := mid.Require(mid.ScopeWriteProjects)
+	readTasks := mid.Require(mid.ScopeReadTasks)
+	writeTasks := mid.Require(mid.ScopeWriteTasks)
+
+	api.Handle(http.MethodPost, "/v1/users", u.Create, writeUsers)
+	api.Handle(http.MethodGet, "/v1/users/me", u.RetrieveMe, readUsers)
+	api.Handle(http.MethodPatch, "/v1/users/me", u.UpdateMe, writeUsers)
+	api.Handle(http.MethodDelete, "/v1/users/me", u.DeleteMe, writeUsers)
+	api.Handle(http.MethodGet, "/v1/users/me/export", u.ExportMe, readUsers)
+	api.Handle(http.MethodGet, "/v1/users/me/invites", m.ListInvites, readProjects)
+	api.Handle(http.MethodGet, "/v1/templates", tm.List, readProjects)
+	api.Handle(http.MethodPost, "/v1/templates", tm.Create, writeProjects)
+	api.Handle(http.MethodGet, "/v1/projects", p.List, readProjects)
+	api.Handle(http.MethodPost, "/v1/projects", p.Create, writeProjects)
+	api.Handle(http.MethodGet, "/v1/projects/trash", p.Trash, readProjects)
+	api.Handle(http.MethodGet, "/v1/projects/{pid}", p.Retrieve, readProjects, viewer)
+	api.Handle(http.MethodPut, "/v1/projects/{pid}", p.Update, writeProjects, editor)
+	api.Handle(http.MethodDelete, "/v1/projects/{pid}", p.Delete, writeProjects, owner)
+	api.Handle(http.MethodPost, "/v1/projects/{pid}/archive", p.Archive, writeProjects, owner)
+	api.Handle(http.MethodPost, "/v1/projects/{pid}/restore", p.Restore, writeProjects)
+	api.Handle(http.MethodGet, "/v1/projects/{pid}/trash", t.Trash, readTasks, viewer)
+	api.Handle(http.MethodPost, "/v1/projects/{pid}/template", tm.SaveProject, writeProjects, viewer)
+	api.Handle(http.MethodGet, "/v1/projects/{pid}/activity", a.List, readProjects, viewer)
+	api.Handle(http.MethodGet, "/v1/projects/{pid}/members", m.List, readProjects, viewer)
+	api.Handle(http.MethodPost, "/v1/projects/{pid}/members", m.Invite, writeProjects, owner)
+	api.Handle(http.MethodPost, "/v1/projects/{pid}/members/accept", m.Accept, writeProjects)
+	api.Handle(http.MethodDelete, "/v1/projects/{pid}/members/{uid}", m.Remove, writeProjects)
+	api.Handle(http.MethodGet, "/v1/projects/{pid}/columns", c.List, readProjects, viewer)
+	api.Handle(http.MethodPost, "/v1/projects/{pid}/columns", c.Create, writeProjects, editor)
+	api.Handle(http.MethodPut, "/v1/projects/{pid}/columns/order", c.Reorder, writeProjects, editor)
+	api.Handle(http.MethodGet, "/v1/projects/{pid}/columns/{cid}", c.Retrieve, readProjects, viewer)
+	api.Handle(http.MethodPatch, "/v1/projects/{pid}/columns/{cid}", c.Update, writeProjects, editor)
+	api.Handle(http.MethodDelete, "/v1/projects/{pid}/columns/{cid}", c.Delete, writeProjects, editor)
+	api.Handle(http.MethodGet, "/v1/projects/{pid}/labels", l.List, readProjects, viewer)
+	api.Handle(http.MethodPost, "/v1/projects/{pid}/labels", l.Create, writeProjects, editor)
+	api.Handle(http.MethodPatch, "/v1/projects/{pid}/labels/{lid}", l.Update, writeProjects, editor)
+	api.Handle(http.MethodDelete, "/v1/projects/{pid}/labels/{lid}", l.Delete, writeProjects, editor)
+	api.Handle(http.MethodGet, "/v1/projects/{pid}/tasks", t.List, readTasks, viewer)
+	api.Handle(http.MethodPost, "/v1/projects/{pid}/columns/{cid}/tasks", t.Create, writeTasks, editor)
+	api.Handle(http.MethodPatch, "/v1/projects/{pid}/tasks/{tid}", t.Update, writeTasks, editor)
+	api.Handle(http.MethodPatch, "/v1/projects/{pid}/tasks/{tid}/move", t.Move, writeTasks, editor)
+	api.Handle(http.MethodPost, "/v1/projects/{pid}/tasks/{tid}/archive", t.Archive, writeTasks, editor)
+	api.Handle(http.MethodPost, "/v1/projects/{pid}/tasks/{tid}/restore", t.Restore, writeTasks, editor)
+	api.Handle(http.MethodDelete, "/v1/projects/{pid}/columns/{cid}/tasks/{tid}", t.Delete, writeTasks, editor)
+	api.Handle(http.MethodGet, "/v1/projects/{pid}/tasks/{tid}/activity", a.ListTask, readTasks, viewer)
+	api.Handle(http.MethodGet, "/v1/projects/{pid}/tasks/{tid}/comments", cm.List, readTasks, viewer)
+	api.Handle(http.MethodPost, "/v1/projects/{pid}/tasks/{tid}/comments", cm.Create, writeTasks, editor)
+	api.Handle(http.MethodPatch, "/v1/projects/{pid}/tasks/{tid}/comments/{cmid}", cm.Update, writeTasks, editor)
+	api.Handle(http.MethodDelete, "/v1/projects/{pid}/tasks/{tid}/comments/{cmid}", cm.Delete, writeTasks, editor)
+
+	// Version 2 listings are paginated, version 1 returns every item.
+	api.Handle(http.MethodGet, "/v2/projects", p.ListPage, readProjects)
+	api.Handle(http.MethodGet, "/v2/projects/{pid}/tasks", t.ListPage, readTasks, viewer)
+
+	// Event streams can take the access token from the URL, see mid.QueryToken.
+	app.Handle(http.MethodGet, "/v1/projects/{pid}/events", ev.Stream, mid.QueryToken(), auth.Authenticate(), readProjects, viewer)
 
 	return cor.Handler(app)
 }
@@ -1,13 +1,14 @@
 package handlers
 
 import (
-	"github.com/ivorscott/devpie-client-backend-go/internal/column"
 	"github.com/ivorscott/devpie-client-backend-go/internal/mid"
 	"log"
 	"net/http"
+	"net/url"
 	"time"
 
 	"github.com/go-chi/chi"
+	"github.com/ivorscott/devpie-client-backend-go/internal/activity"
 	"github.com/ivorscott/devpie-client-backend-go/internal/platform/database"
 	"github.com/ivorscott/devpie-client-backend-go/internal/platform/web"
 	"github.com/ivorscott/devpie-client-backend-go/internal/task"
@@ -16,33 +17,84 @@ import (
 
 // Tasks holds the application state needed by the handler methods.
 type Tasks struct {
-	repo  *database.Repository
-	log   *log.Logger
-	auth0 *mid.Auth0
+	unit     database.Unit
+	tasks    task.Store
+	activity activity.Recorder
+	log      *log.Logger
+	auth     *mid.Auth
 }
 
-// List gets all task
+// List gets the tasks of a project. The query parameters assignee, label,
+// priority, column, dueBefore, dueAfter and q filter the tasks and sort
+// orders them, e.g. ?priority=high&sort=-dueDate.
 func (t *Tasks) List(w http.ResponseWriter, r *http.Request) error {
-	pid := chi.URLParam(r, "pid")
+	list, err := t.list(r, database.Page{})
+	if err != nil {
+		return err
+	}
+
+	return web.Respond(r.Context(), w, list, http.StatusOK)
+}
 
-	list, err := task.List(r.Context(), t.repo, pid)
+// ListPage gets a page of the tasks of a project. It takes the query
+// parameters of List and those of web.ParsePage.
+func (t *Tasks) ListPage(w http.ResponseWriter, r *http.Request) error {
+	pr, err := web.ParsePage(r)
 	if err != nil {
 		return err
 	}
 
-	if list == nil {
-		list = []task.Task{}
+	list, err := t.list(r, database.Page{Limit: pr.Limit + 1, After: pr.After})
+	if err != nil {
+		return err
 	}
 
-	return web.Respond(r.Context(), w, list, http.StatusOK)
+	sort := r.URL.Query().Get("sort")
+	return web.Respond(r.Context(), w, web.NewPage(pr, list, func(i int) []interface{} { return list[i].Key(sort) }), http.StatusOK)
+}
+
+// list queries the tasks matching the filter in the request URL.
+func (t *Tasks) list(r *http.Request, page database.Page) ([]task.Task, error) {
+	pid := chi.URLParam(r, "pid")
+	query := r.URL.Query()
+
+	f := task.Filter{
+		AssigneeID: query.Get("assignee"),
+		LabelID:    query.Get("label"),
+		Priority:   query.Get("priority"),
+		ColumnID:   query.Get("column"),
+		Search:     query.Get("q"),
+		Sort:       query.Get("sort"),
+		Archived:   query.Get("archived") == "true",
+	}
+
+	var err error
+	if f.DueBefore, err = queryTime(query, "dueBefore"); err != nil {
+		return nil, err
+	}
+	if f.DueAfter, err = queryTime(query, "dueAfter"); err != nil {
+		return nil, err
+	}
+
+	list, err := t.tasks.List(r.Context(), pid, f, page)
+	if err != nil {
+		switch err {
+		case task.ErrInvalidFilter, task.ErrInvalidSort, database.ErrInvalidPage:
+			return nil, web.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return nil, errors.Wrap(err, "listing tasks")
+		}
+	}
+
+	return list, nil
 }
 
 // Retrieve a single Task
 func (t *Tasks) Retrieve(w http.ResponseWriter, r *http.Request) error {
-
+	pid := chi.URLParam(r, "pid")
 	tid := chi.URLParam(r, "tid")
 
-	ts, err := task.Retrieve(r.Context(), t.repo, tid)
+	ts, err := t.tasks.Retrieve(r.Context(), pid, tid)
 	if err != nil {
 		switch err {
 		case task.ErrNotFound:
@@ -68,23 +120,38 @@ func (t *Tasks) Create(w http.ResponseWriter, r *http.Request) error {
 		return err
 	}
 
-	ts, err := task.Create(r.Context(), t.repo, nt, pid, time.Now())
+	ctx, err := t.unit.Begin(r.Context())
 	if err != nil {
 		return err
 	}
+	defer t.unit.Rollback(ctx)
 
-	c, err := column.Retrieve(r.Context(), t.repo, pid, cid)
+	ts, err := t.tasks.Create(ctx, nt, pid, cid, time.Now())
 	if err != nil {
-		return err
-	}
-	uc := column.UpdateColumn{
-		TaskIDS: append(c.TaskIDS, ts.ID),
+		switch err {
+		case task.ErrColumnNotFound:
+			return web.NewRequestError(err, http.StatusNotFound)
+		case task.ErrInvalidID, task.ErrInvalidAssignee, task.ErrInvalidLabel:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return errors.Wrapf(err, "creating task in column %q", cid)
+		}
 	}
 
-	if err := column.Update(r.Context(), t.repo, pid, cid, uc); err != nil {
+	if err := record(ctx, r, t.activity, t.auth, activity.NewEvent{
+		ProjectID: pid,
+		TaskID:    &ts.ID,
+		ColumnID:  &ts.ColumnID,
+		Action:    activity.TaskCreated,
+		After:     ts,
+	}); err != nil {
 		return err
 	}
 
+	if err := t.unit.Commit(ctx); err != nil {
+		return errors.Wrapf(err, "creating task in column %q", cid)
+	}
+
 	return web.Respond(r.Context(), w, ts, http.StatusCreated)
 }
 
@@ -99,100 +166,274 @@ func (t *Tasks) Update(w http.ResponseWriter, r *http.Request) error {
 		return errors.Wrap(err, "decoding task update")
 	}
 
-	if err := task.Update(r.Context(), t.repo, pid, tid, ut); err != nil {
+	versions, err := web.IfMatch(r)
+	if err != nil {
+		return err
+	}
+
+	ctx, err := t.unit.Begin(r.Context())
+	if err != nil {
+		return err
+	}
+	defer t.unit.Rollback(ctx)
+
+	before, err := t.tasks.Update(ctx, pid, tid, ut, versions)
+	if err != nil {
 		switch err {
 		case task.ErrNotFound:
 			return web.NewRequestError(err, http.StatusNotFound)
-		case task.ErrInvalidID:
+		case task.ErrInvalidID, task.ErrInvalidAssignee, task.ErrInvalidLabel:
 			return web.NewRequestError(err, http.StatusBadRequest)
+		case task.ErrConflict:
+			return web.NewRequestError(err, http.StatusPreconditionFailed)
 		default:
 			return errors.Wrapf(err, "updating task %v", ut)
 		}
 	}
 
+	after, err := t.tasks.Retrieve(ctx, pid, tid)
+	if err != nil {
+		return errors.Wrapf(err, "looking for updated task %q", tid)
+	}
+
+	if err := record(ctx, r, t.activity, t.auth, activity.NewEvent{
+		ProjectID: pid,
+		TaskID:    &after.ID,
+		ColumnID:  &after.ColumnID,
+		Action:    activity.TaskUpdated,
+		Before:    before,
+		After:     after,
+	}); err != nil {
+		return err
+	}
+
+	if err := t.unit.Commit(ctx); err != nil {
+		return errors.Wrapf(err, "updating task %v", ut)
+	}
+
 	return web.Respond(r.Context(), w, nil, http.StatusNoContent)
 }
 
 // Delete removes a single task identified by an ID in the request URL.
 func (t *Tasks) Delete(w http.ResponseWriter, r *http.Request) error {
 	pid := chi.URLParam(r, "pid")
-	cid := chi.URLParam(r, "cid")
 	tid := chi.URLParam(r, "tid")
 
-	c, err := column.Retrieve(r.Context(), t.repo, pid, cid)
+	versions, err := web.IfMatch(r)
 	if err != nil {
 		return err
 	}
 
-	i := SliceIndex(len(c.TaskIDS), func(i int) bool { return c.TaskIDS[i] == tid })
-
-	newTaskIds := append(c.TaskIDS[:i], c.TaskIDS[i+1:]...)
-	uc := column.UpdateColumn{TaskIDS: newTaskIds}
-
-	if err := column.Update(r.Context(), t.repo, pid, cid, uc); err != nil {
+	ctx, err := t.unit.Begin(r.Context())
+	if err != nil {
 		return err
 	}
+	defer t.unit.Rollback(ctx)
 
-	if err := task.Delete(r.Context(), t.repo, pid, tid); err != nil {
+	before, err := t.tasks.Delete(ctx, pid, tid, versions, time.Now())
+	if err != nil {
 		switch err {
+		case task.ErrNotFound:
+			return web.NewRequestError(err, http.StatusNotFound)
 		case task.ErrInvalidID:
 			return web.NewRequestError(err, http.StatusBadRequest)
+		case task.ErrConflict:
+			return web.NewRequestError(err, http.StatusPreconditionFailed)
 		default:
 			return errors.Wrapf(err, "deleting task %q", tid)
 		}
 	}
 
+	if err := record(ctx, r, t.activity, t.auth, activity.NewEvent{
+		ProjectID: pid,
+		TaskID:    &before.ID,
+		ColumnID:  &before.ColumnID,
+		Action:    activity.TaskDeleted,
+		Before:    before,
+	}); err != nil {
+		return err
+	}
+
+	if err := t.unit.Commit(ctx); err != nil {
+		return errors.Wrapf(err, "deleting task %q", tid)
+	}
+
 	return web.Respond(r.Context(), w, nil, http.StatusNoContent)
 }
 
-func (t *Tasks) Move(w http.ResponseWriter, r *http.Request) error {
+// Archive hides a task from the board. Archived tasks are listed with
+// ?archived=true.
+func (t *Tasks) Archive(w http.ResponseWriter, r *http.Request) error {
 	pid := chi.URLParam(r, "pid")
 	tid := chi.URLParam(r, "tid")
 
-	var mt task.MoveTask
-	if err := web.Decode(r, &mt); err != nil {
-		return errors.Wrap(err, "decoding task move")
+	versions, err := web.IfMatch(r)
+	if err != nil {
+		return err
 	}
 
-	cF, err := column.Retrieve(r.Context(), t.repo, pid, mt.From)
+	ctx, err := t.unit.Begin(r.Context())
 	if err != nil {
 		return err
 	}
+	defer t.unit.Rollback(ctx)
 
-	cT, err := column.Retrieve(r.Context(), t.repo, pid, mt.To)
-	if err != nil {
+	if err := t.tasks.Archive(ctx, pid, tid, versions, time.Now()); err != nil {
+		switch err {
+		case task.ErrNotFound:
+			return web.NewRequestError(err, http.StatusNotFound)
+		case task.ErrInvalidID:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		case task.ErrConflict:
+			return web.NewRequestError(err, http.StatusPreconditionFailed)
+		default:
+			return errors.Wrapf(err, "archiving task %q", tid)
+		}
+	}
+
+	if err := record(ctx, r, t.activity, t.auth, activity.NewEvent{
+		ProjectID: pid,
+		TaskID:    &tid,
+		Action:    activity.TaskArchived,
+	}); err != nil {
 		return err
 	}
 
-	i := SliceIndex(len(cF.TaskIDS), func(i int) bool { return cF.TaskIDS[i] == tid })
+	if err := t.unit.Commit(ctx); err != nil {
+		return errors.Wrapf(err, "archiving task %q", tid)
+	}
 
-	newFromTaskIds := append(cF.TaskIDS[:i], cF.TaskIDS[i+1:]...)
-	foc := column.UpdateColumn{TaskIDS: newFromTaskIds}
+	return web.Respond(r.Context(), w, nil, http.StatusNoContent)
+}
 
-	newToTaskIds := append(cT.TaskIDS, tid)
-	toc := column.UpdateColumn{TaskIDS: newToTaskIds}
+// Restore brings an archived or deleted task back to the board.
+func (t *Tasks) Restore(w http.ResponseWriter, r *http.Request) error {
+	pid := chi.URLParam(r, "pid")
+	tid := chi.URLParam(r, "tid")
 
-	err = column.Update(r.Context(), t.repo, pid, mt.From, foc)
-	err = column.Update(r.Context(), t.repo, pid, mt.To, toc)
+	versions, err := web.IfMatch(r)
 	if err != nil {
+		return err
+	}
+
+	ctx, err := t.unit.Begin(r.Context())
+	if err != nil {
+		return err
+	}
+	defer t.unit.Rollback(ctx)
+
+	if err := t.tasks.Restore(ctx, pid, tid, versions); err != nil {
 		switch err {
 		case task.ErrNotFound:
 			return web.NewRequestError(err, http.StatusNotFound)
 		case task.ErrInvalidID:
 			return web.NewRequestError(err, http.StatusBadRequest)
+		case task.ErrConflict:
+			return web.NewRequestError(err, http.StatusPreconditionFailed)
 		default:
-			return errors.Wrapf(err, "updating column taskIds from:%q, to:%q", mt.From, mt.To)
+			return errors.Wrapf(err, "restoring task %q", tid)
 		}
 	}
 
+	if err := record(ctx, r, t.activity, t.auth, activity.NewEvent{
+		ProjectID: pid,
+		TaskID:    &tid,
+		Action:    activity.TaskRestored,
+	}); err != nil {
+		return err
+	}
+
+	if err := t.unit.Commit(ctx); err != nil {
+		return errors.Wrapf(err, "restoring task %q", tid)
+	}
+
 	return web.Respond(r.Context(), w, nil, http.StatusNoContent)
 }
 
-func SliceIndex(limit int, predicate func(i int) bool) int {
-	for i := 0; i < limit; i++ {
-		if predicate(i) {
-			return i
+// Trash gets the deleted tasks of a project. They are purged after the
+// retention period.
+func (t *Tasks) Trash(w http.ResponseWriter, r *http.Request) error {
+	pid := chi.URLParam(r, "pid")
+
+	list, err := t.tasks.ListTrash(r.Context(), pid)
+	if err != nil {
+		return err
+	}
+
+	return web.Respond(r.Context(), w, list, http.StatusOK)
+}
+
+// Move places a task at an exact position within the same or another column.
+func (t *Tasks) Move(w http.ResponseWriter, r *http.Request) error {
+	pid := chi.URLParam(r, "pid")
+	tid := chi.URLParam(r, "tid")
+
+	var mt task.MoveTask
+	if err := web.Decode(r, &mt); err != nil {
+		return errors.Wrap(err, "decoding task move")
+	}
+
+	versions, err := web.IfMatch(r)
+	if err != nil {
+		return err
+	}
+
+	ctx, err := t.unit.Begin(r.Context())
+	if err != nil {
+		return err
+	}
+	defer t.unit.Rollback(ctx)
+
+	before, err := t.tasks.Move(ctx, pid, tid, mt, versions)
+	if err != nil {
+		switch err {
+		case task.ErrNotFound, task.ErrColumnNotFound:
+			return web.NewRequestError(err, http.StatusNotFound)
+		case task.ErrInvalidID, task.ErrInvalidIndex:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		case task.ErrOrderChanged:
+			return web.NewRequestError(err, http.StatusConflict)
+		case task.ErrConflict:
+			return web.NewRequestError(err, http.StatusPreconditionFailed)
+		default:
+			return errors.Wrapf(err, "moving task %q from:%q, to:%q", tid, mt.From, mt.To)
 		}
 	}
-	return -1
+
+	after, err := t.tasks.Retrieve(ctx, pid, tid)
+	if err != nil {
+		return errors.Wrapf(err, "looking for moved task %q", tid)
+	}
+
+	if err := record(ctx, r, t.activity, t.auth, activity.NewEvent{
+		ProjectID: pid,
+		TaskID:    &after.ID,
+		ColumnID:  &after.ColumnID,
+		Action:    activity.TaskMoved,
+		Before:    before,
+		After:     after,
+	}); err != nil {
+		return err
+	}
+
+	if err := t.unit.Commit(ctx); err != nil {
+		return errors.Wrapf(err, "moving task %q from:%q, to:%q", tid, mt.From, mt.To)
+	}
+
+	return web.Respond(r.Context(), w, nil, http.StatusNoContent)
+}
+
+// queryTime parses the RFC 3339 date in the query parameter param, if present.
+func queryTime(query url.Values, param string) (*time.Time, error) {
+	v := query.Get(param)
+	if v == "" {
+		return nil, nil
+	}
+
+	d, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return nil, web.NewRequestError(errors.Errorf("%s must be an RFC 3339 date", param), http.StatusBadRequest)
+	}
+
+	return &d, nil
 }
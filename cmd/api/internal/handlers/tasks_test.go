@@ -0,0 +1,367 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ivorscott/devpie-client-backend-go/internal/activity"
+	"github.com/ivorscott/devpie-client-backend-go/internal/member"
+	"github.com/ivorscott/devpie-client-backend-go/internal/platform/web"
+	"github.com/ivorscott/devpie-client-backend-go/internal/task"
+	"github.com/pkg/errors"
+)
+
+func (s *stores) taskHandlers() *Tasks {
+	return &Tasks{unit: noUnit{}, tasks: s.tasks, activity: s.activity, log: s.log, auth: s.auth}
+}
+
+func TestTasksCreate(t *testing.T) {
+	s := newStores()
+	h := s.taskHandlers()
+	p, cs := s.board(t, "To Do")
+
+	rt := route{http.MethodPost, "/projects/{pid}/columns/{cid}/tasks", h.Create, member.RoleEditor}
+	rec := s.serve(t, rt, testUser, "/projects/"+p.ID+"/columns/"+cs[0].ID+"/tasks", task.NewTask{Title: "Write tests"}, nil)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status: got %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body)
+	}
+
+	var got task.Task
+	decode(t, rec, &got)
+	if got.Title != "Write tests" || got.ColumnID != cs[0].ID {
+		t.Errorf("created task: got %+v", got)
+	}
+	if etag := rec.Header().Get("ETag"); etag != web.ETag(1) {
+		t.Errorf("ETag: got %s, want %s", etag, web.ETag(1))
+	}
+
+	if a := s.lastAction(); a != activity.TaskCreated {
+		t.Errorf("activity: got %q, want %q", a, activity.TaskCreated)
+	}
+	if actor := s.activity.Events()[0].ActorID; actor == nil || *actor != testUser {
+		t.Errorf("actor: got %v, want %q", actor, testUser)
+	}
+}
+
+func TestTasksList(t *testing.T) {
+	s := newStores()
+	h := s.taskHandlers()
+	p, cs := s.board(t, "To Do", "Done")
+	s.addTasks(t, cs[0], "first", "second")
+	s.addTasks(t, cs[1], "third")
+
+	rt := route{http.MethodGet, "/projects/{pid}/tasks", h.List, member.RoleViewer}
+
+	tests := []struct {
+		name   string
+		query  string
+		status int
+		titles []string
+	}{
+		{"all", "", http.StatusOK, nil},
+		{"column", "?column=" + cs[1].ID, http.StatusOK, []string{"third"}},
+		{"search", "?q=SEC", http.StatusOK, []string{"second"}},
+		{"sort", "?sort=-title", http.StatusOK, []string{"third", "second", "first"}},
+		{"invalid sort", "?sort=color", http.StatusBadRequest, nil},
+		{"invalid filter", "?column=nope", http.StatusBadRequest, nil},
+		{"invalid date", "?dueBefore=tomorrow", http.StatusBadRequest, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := s.serve(t, rt, testUser, "/projects/"+p.ID+"/tasks"+tt.query, nil, nil)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status: got %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+
+			var got []task.Task
+			decode(t, rec, &got)
+			if tt.titles == nil {
+				if len(got) != 3 {
+					t.Errorf("got %d tasks, want 3", len(got))
+				}
+				return
+			}
+			if len(got) != len(tt.titles) {
+				t.Fatalf("got %d tasks, want %d", len(got), len(tt.titles))
+			}
+			for i := range got {
+				if got[i].Title != tt.titles[i] {
+					t.Errorf("task %d: got %q, want %q", i, got[i].Title, tt.titles[i])
+				}
+			}
+		})
+	}
+}
+
+func TestTasksDueDate(t *testing.T) {
+	s := newStores()
+	h := s.taskHandlers()
+	p, cs := s.board(t, "To Do")
+
+	create := route{http.MethodPost, "/projects/{pid}/columns/{cid}/tasks", h.Create, member.RoleEditor}
+	rec := s.serve(t, create, testUser, "/projects/"+p.ID+"/columns/"+cs[0].ID+"/tasks", map[string]string{"title": "launch", "dueDate": "2020-06-01T10:00:00+02:00"}, nil)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status: got %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body)
+	}
+
+	var tk task.Task
+	decode(t, rec, &tk)
+	if want := time.Date(2020, 6, 1, 8, 0, 0, 0, time.UTC); tk.DueDate == nil || !tk.DueDate.Equal(want) || tk.DueDate.Location() != time.UTC {
+		t.Fatalf("due date: got %v, want %v", tk.DueDate, want)
+	}
+
+	list := route{http.MethodGet, "/projects/{pid}/tasks", h.List, member.RoleViewer}
+	for query, want := range map[string]int{
+		"?dueBefore=2020-06-01T09:00:00Z": 1,
+		"?dueAfter=2020-06-01T09:00:00Z":  0,
+		"?dueAfter=2020-06-01T07:00:00Z":  1,
+	} {
+		rec := s.serve(t, list, testUser, "/projects/"+p.ID+"/tasks"+query, nil, nil)
+		var got []task.Task
+		decode(t, rec, &got)
+		if len(got) != want {
+			t.Errorf("%s: got %d tasks, want %d", query, len(got), want)
+		}
+	}
+}
+
+func TestTasksListPage(t *testing.T) {
+	s := newStores()
+	h := s.taskHandlers()
+	p, cs := s.board(t, "To Do", "Done")
+	s.addTasks(t, cs[0], "b", "d", "f")
+	s.addTasks(t, cs[1], "c", "e")
+
+	rt := route{http.MethodGet, "/projects/{pid}/tasks/page", h.ListPage, member.RoleViewer}
+
+	var titles []string
+	target := "/projects/" + p.ID + "/tasks/page?sort=title&limit=2"
+	for n := 0; ; n++ {
+		var page struct {
+			Data       []task.Task `json:"data"`
+			NextCursor *string     `json:"nextCursor"`
+		}
+
+		rec := s.serve(t, rt, testUser, target, nil, nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("page %d: got %d, want %d: %s", n, rec.Code, http.StatusOK, rec.Body)
+		}
+		decode(t, rec, &page)
+		for _, tk := range page.Data {
+			titles = append(titles, tk.Title)
+		}
+		if page.NextCursor == nil {
+			break
+		}
+		target = "/projects/" + p.ID + "/tasks/page?sort=title&limit=2&cursor=" + *page.NextCursor
+
+		// A task added before the position of the cursor doesn't move it.
+		if n == 0 {
+			s.addTasks(t, cs[1], "a")
+		}
+	}
+
+	want := []string{"b", "c", "d", "e", "f"}
+	if strings.Join(titles, ",") != strings.Join(want, ",") {
+		t.Errorf("pages: got %v, want %v", titles, want)
+	}
+}
+
+func TestTasksUpdate(t *testing.T) {
+	s := newStores()
+	h := s.taskHandlers()
+	p, cs := s.board(t, "To Do")
+	tk := s.addTasks(t, cs[0], "draft")[0]
+
+	rt := route{http.MethodPatch, "/projects/{pid}/tasks/{tid}", h.Update, member.RoleEditor}
+	target := "/projects/" + p.ID + "/tasks/" + tk.ID
+	title := "final"
+
+	rec := s.serve(t, rt, testUser, target, map[string]string{"title": title}, ifMatch(tk.Version+1))
+	if rec.Code != http.StatusPreconditionFailed {
+		t.Fatalf("stale version: got %d, want %d: %s", rec.Code, http.StatusPreconditionFailed, rec.Body)
+	}
+
+	rec = s.serve(t, rt, testUser, target, map[string]string{"title": title}, ifMatch(tk.Version))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("status: got %d, want %d: %s", rec.Code, http.StatusNoContent, rec.Body)
+	}
+
+	got, err := s.tasks.Retrieve(context.Background(), p.ID, tk.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Title != title || got.Version != tk.Version+1 {
+		t.Errorf("updated task: got %q at version %d", got.Title, got.Version)
+	}
+	if a := s.lastAction(); a != activity.TaskUpdated {
+		t.Errorf("activity: got %q, want %q", a, activity.TaskUpdated)
+	}
+}
+
+// unit counts the commits of units of work.
+type unit struct {
+	noUnit
+	commits int
+}
+
+func (u *unit) Commit(ctx context.Context) error {
+	u.commits++
+	return nil
+}
+
+// failingRecorder fails to record any event.
+type failingRecorder struct{}
+
+func (failingRecorder) Record(ctx context.Context, ne activity.NewEvent, now time.Time) (*activity.Event, error) {
+	return nil, errors.New("activity log unavailable")
+}
+
+func TestTasksUpdateUnrecorded(t *testing.T) {
+	s := newStores()
+	u := &unit{}
+	h := &Tasks{unit: u, tasks: s.tasks, activity: failingRecorder{}, log: s.log, auth: s.auth}
+	p, cs := s.board(t, "To Do")
+	tk := s.addTasks(t, cs[0], "draft")[0]
+
+	// A change whose event can't be recorded isn't committed.
+	rt := route{http.MethodPatch, "/projects/{pid}/tasks/{tid}", h.Update, member.RoleEditor}
+	rec := s.serve(t, rt, testUser, "/projects/"+p.ID+"/tasks/"+tk.ID, map[string]string{"title": "final"}, nil)
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("status: got %d, want %d: %s", rec.Code, http.StatusInternalServerError, rec.Body)
+	}
+	if u.commits != 0 {
+		t.Errorf("got %d commits, want none", u.commits)
+	}
+}
+
+func TestTasksMove(t *testing.T) {
+	s := newStores()
+	h := s.taskHandlers()
+	p, cs := s.board(t, "To Do", "Done")
+	todo := s.addTasks(t, cs[0], "first", "second")
+	done := s.addTasks(t, cs[1], "third")
+
+	rt := route{http.MethodPatch, "/projects/{pid}/tasks/{tid}/move", h.Move, member.RoleEditor}
+	target := "/projects/" + p.ID + "/tasks/" + todo[1].ID + "/move"
+
+	stale := task.MoveTask{From: cs[0].ID, To: cs[1].ID, Index: 0, TaskIds: []string{}}
+	rec := s.serve(t, rt, testUser, target, stale, nil)
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("stale order: got %d, want %d", rec.Code, http.StatusConflict)
+	}
+
+	mt := task.MoveTask{From: cs[0].ID, To: cs[1].ID, Index: 0, TaskIds: []string{done[0].ID}}
+	rec = s.serve(t, rt, testUser, target, mt, nil)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("status: got %d, want %d: %s", rec.Code, http.StatusNoContent, rec.Body)
+	}
+
+	c, err := s.columns.Retrieve(context.Background(), p.ID, cs[1].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{todo[1].ID, done[0].ID}; !equalIDs(c.TaskIDS, want) {
+		t.Errorf("column tasks: got %v, want %v", c.TaskIDS, want)
+	}
+	if a := s.lastAction(); a != activity.TaskMoved {
+		t.Errorf("activity: got %q, want %q", a, activity.TaskMoved)
+	}
+}
+
+func TestTasksDeleteAndRestore(t *testing.T) {
+	s := newStores()
+	h := s.taskHandlers()
+	p, cs := s.board(t, "To Do")
+	tk := s.addTasks(t, cs[0], "doomed")[0]
+
+	del := route{http.MethodDelete, "/projects/{pid}/columns/{cid}/tasks/{tid}", h.Delete, member.RoleEditor}
+	rec := s.serve(t, del, testUser, "/projects/"+p.ID+"/columns/"+cs[0].ID+"/tasks/"+tk.ID, nil, nil)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("delete: got %d, want %d: %s", rec.Code, http.StatusNoContent, rec.Body)
+	}
+
+	trash := route{http.MethodGet, "/projects/{pid}/trash", h.Trash, member.RoleViewer}
+	rec = s.serve(t, trash, testUser, "/projects/"+p.ID+"/trash", nil, nil)
+
+	var got []task.Task
+	decode(t, rec, &got)
+	if len(got) != 1 || got[0].ID != tk.ID {
+		t.Fatalf("trash: got %+v", got)
+	}
+
+	restore := route{http.MethodPost, "/projects/{pid}/tasks/{tid}/restore", h.Restore, member.RoleEditor}
+	rec = s.serve(t, restore, testUser, "/projects/"+p.ID+"/tasks/"+tk.ID+"/restore", nil, nil)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("restore: got %d, want %d: %s", rec.Code, http.StatusNoContent, rec.Body)
+	}
+
+	rec = s.serve(t, restore, testUser, "/projects/"+p.ID+"/tasks/"+tk.ID+"/restore", nil, nil)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("restoring twice: got %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestTasksArchiveIfMatch(t *testing.T) {
+	s := newStores()
+	h := s.taskHandlers()
+	p, cs := s.board(t, "To Do")
+	tk := s.addTasks(t, cs[0], "shelved")[0]
+
+	archive := route{http.MethodPost, "/projects/{pid}/tasks/{tid}/archive", h.Archive, member.RoleEditor}
+	target := "/projects/" + p.ID + "/tasks/" + tk.ID + "/archive"
+
+	if rec := s.serve(t, archive, testUser, target, nil, ifMatch(tk.Version+1)); rec.Code != http.StatusPreconditionFailed {
+		t.Fatalf("stale archive: got %d, want %d: %s", rec.Code, http.StatusPreconditionFailed, rec.Body)
+	}
+
+	// Any of the listed versions matches.
+	tags := http.Header{"If-Match": {web.ETag(tk.Version+1) + ", " + web.ETag(tk.Version)}}
+	if rec := s.serve(t, archive, testUser, target, nil, tags); rec.Code != http.StatusNoContent {
+		t.Fatalf("archive: got %d, want %d: %s", rec.Code, http.StatusNoContent, rec.Body)
+	}
+
+	restore := route{http.MethodPost, "/projects/{pid}/tasks/{tid}/restore", h.Restore, member.RoleEditor}
+	target = "/projects/" + p.ID + "/tasks/" + tk.ID + "/restore"
+
+	if rec := s.serve(t, restore, testUser, target, nil, ifMatch(tk.Version)); rec.Code != http.StatusPreconditionFailed {
+		t.Fatalf("stale restore: got %d, want %d: %s", rec.Code, http.StatusPreconditionFailed, rec.Body)
+	}
+	if rec := s.serve(t, restore, testUser, target, nil, ifMatch(tk.Version+1)); rec.Code != http.StatusNoContent {
+		t.Fatalf("restore: got %d, want %d: %s", rec.Code, http.StatusNoContent, rec.Body)
+	}
+}
+
+func TestTasksOtherProject(t *testing.T) {
+	s := newStores()
+	h := s.taskHandlers()
+	p, _ := s.board(t)
+
+	rt := route{http.MethodGet, "/projects/{pid}/tasks", h.List, member.RoleViewer}
+	rec := s.serve(t, rt, "0b2a0d4e-8a43-4c51-a4ba-4a7f1f0f5d55", "/projects/"+p.ID+"/tasks", nil, nil)
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status: got %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func equalIDs(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/ivorscott/devpie-client-backend-go/internal/column"
+	"github.com/ivorscott/devpie-client-backend-go/internal/mid"
+	"github.com/ivorscott/devpie-client-backend-go/internal/platform/database"
+	"github.com/ivorscott/devpie-client-backend-go/internal/platform/web"
+	"github.com/ivorscott/devpie-client-backend-go/internal/task"
+	"github.com/ivorscott/devpie-client-backend-go/internal/template"
+	"github.com/pkg/errors"
+)
+
+// Templates holds the application state needed by the handler methods.
+type Templates struct {
+	repo    *database.Repository
+	columns column.Store
+	tasks   task.Store
+	log     *log.Logger
+	auth    *mid.Auth
+}
+
+// List gets the built-in templates and the user's own templates
+func (t *Templates) List(w http.ResponseWriter, r *http.Request) error {
+	uid := t.auth.GetUserById(r)
+
+	list, err := template.List(r.Context(), t.repo, uid)
+	if err != nil {
+		return err
+	}
+
+	return web.Respond(r.Context(), w, list, http.StatusOK)
+}
+
+// Create a new Template
+func (t *Templates) Create(w http.ResponseWriter, r *http.Request) error {
+	uid := t.auth.GetUserById(r)
+
+	var nt template.NewTemplate
+	if err := web.Decode(r, &nt); err != nil {
+		return err
+	}
+
+	tm, err := template.Create(r.Context(), t.repo, nt, uid, time.Now())
+	if err != nil {
+		return errors.Wrapf(err, "creating template %q", nt.Name)
+	}
+
+	return web.Respond(r.Context(), w, tm, http.StatusCreated)
+}
+
+// SaveProject creates a Template from the columns, and optionally the tasks,
+// of the project in the request URL.
+func (t *Templates) SaveProject(w http.ResponseWriter, r *http.Request) error {
+	pid := chi.URLParam(r, "pid")
+	uid := t.auth.GetUserById(r)
+
+	var sp template.SaveProject
+	if err := web.Decode(r, &sp); err != nil {
+		return err
+	}
+
+	cs, err := t.columns.List(r.Context(), pid)
+	if err != nil {
+		return err
+	}
+
+	byName := make(map[string]column.Column, len(cs))
+	for _, c := range cs {
+		byName[c.ColumnName] = c
+	}
+
+	tasks := make(map[string]task.Task)
+	if sp.IncludeTasks {
+		list, err := t.tasks.List(r.Context(), pid, task.Filter{}, database.Page{})
+		if err != nil {
+			return err
+		}
+		for _, ts := range list {
+			tasks[ts.ID] = ts
+		}
+	}
+
+	nt := template.NewTemplate{Name: sp.Name}
+	for _, name := range mid.CurrentProject(r.Context()).ColumnOrder {
+		c, ok := byName[name]
+		if !ok {
+			continue
+		}
+
+		tc := template.Column{Title: c.Title}
+		for _, id := range c.TaskIDS {
+			if ts, ok := tasks[id]; ok {
+				tc.Tasks = append(tc.Tasks, template.Task{Title: ts.Title, Content: ts.Content})
+			}
+		}
+		nt.Columns = append(nt.Columns, tc)
+	}
+
+	tm, err := template.Create(r.Context(), t.repo, nt, uid, time.Now())
+	if err != nil {
+		return errors.Wrapf(err, "saving project %q as template", pid)
+	}
+
+	return web.Respond(r.Context(), w, tm, http.StatusCreated)
+}
@@ -1,21 +1,16 @@
 package handlers
 
 import (
-	"encoding/json"
 	"fmt"
-	"github.com/dgrijalva/jwt-go"
-	"github.com/google/uuid"
-	"github.com/ivorscott/devpie-client-backend-go/internal/ma_token"
+	"github.com/ivorscott/devpie-client-backend-go/internal/account"
 	"github.com/ivorscott/devpie-client-backend-go/internal/mid"
+	"github.com/ivorscott/devpie-client-backend-go/internal/platform/database"
+	"github.com/ivorscott/devpie-client-backend-go/internal/provision"
 	"github.com/pkg/errors"
-	"io/ioutil"
 	"log"
 	"net/http"
-	"net/url"
-	"strings"
 	"time"
 
-	"github.com/ivorscott/devpie-client-backend-go/internal/platform/database"
 	"github.com/ivorscott/devpie-client-backend-go/internal/platform/web"
 	"github.com/ivorscott/devpie-client-backend-go/internal/user"
 )
@@ -23,189 +18,163 @@ import (
 // Users holds the application state needed by the handler methods.
 type Users struct {
 	repo  *database.Repository
+	users user.Store
 	log   *log.Logger
-	auth0 *mid.Auth0
+	auth  *mid.Auth
 }
 
 // Retrieve a single user
 func (u *Users) RetrieveMe(w http.ResponseWriter, r *http.Request) error {
-	var us *user.User
-	var err error
+	us, err := u.me(r)
+	if err != nil {
+		return err
+	}
 
-	id := u.auth0.GetUserById(r)
+	return web.Respond(r.Context(), w, us, http.StatusOK)
+}
 
-	if id == "" {
-		us, err = user.RetrieveMeBySubject(r.Context(), u.repo, u.auth0.GetUserBySubject(r))
-	} else {
-		us, err = user.RetrieveMeById(r.Context(), u.repo, id)
+// UpdateMe decodes the body of a request to update the profile of the user.
+// Omitted fields are left as they are.
+func (u *Users) UpdateMe(w http.ResponseWriter, r *http.Request) error {
+	us, err := u.me(r)
+	if err != nil {
+		return err
+	}
+
+	var uu user.UpdateUser
+	if err := web.Decode(r, &uu); err != nil {
+		return errors.Wrap(err, "decoding user update")
 	}
 
+	updated, err := u.users.Update(r.Context(), us.ID, uu)
 	if err != nil {
 		switch err {
 		case user.ErrNotFound:
 			return web.NewRequestError(err, http.StatusNotFound)
-		case user.ErrInvalidID:
-			return web.NewRequestError(err, http.StatusBadRequest)
 		default:
-			return errors.Wrapf(err, "looking for user %q", id)
+			return errors.Wrapf(err, "updating user %q", us.ID)
 		}
 	}
 
-	return web.Respond(r.Context(), w, us, http.StatusOK)
+	return web.Respond(r.Context(), w, updated, http.StatusOK)
 }
 
-// Create a new user
-func (u *Users) Create(w http.ResponseWriter, r *http.Request) error {
-	var t *ma_token.Token
-	sub := u.auth0.GetUserBySubject(r)
-
-	var nu user.NewUser
-	if err := web.Decode(r, &nu); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		return err
-	}
-
-	// create user
-	us, err := user.Create(r.Context(), u.repo, nu, sub, time.Now())
+// DeleteMe removes the account of the user, see account.Delete. The projects
+// the user owns are transferred to other members, or deleted with
+// ?projects=delete. The user id is removed from the account of the identity
+// provider first, a user whose deletion then fails is found by subject.
+func (u *Users) DeleteMe(w http.ResponseWriter, r *http.Request) error {
+	var transfer bool
+	switch r.URL.Query().Get("projects") {
+	case "", "transfer":
+		transfer = true
+	case "delete":
+	default:
+		return web.NewRequestError(errors.New("projects must be transfer or delete"), http.StatusBadRequest)
+	}
+
+	us, err := u.me(r)
 	if err != nil {
 		return err
 	}
 
-	// begin auth0 account update
-
-	// try getting existing auth0 management api token
-	t, err = ma_token.Retrieve(r.Context(), u.repo)
-	if err == ma_token.ErrNotFound || u.IsExpired(t) {
-		// create new management api token
-		t, err = u.NewManagementToken()
-		if err != nil {
-			return err
-		}
-		// clean table before persisting
-		if err := ma_token.Delete(r.Context(), u.repo); err != nil {
-			return err
-		}
-		// persist management api token
-		if err := ma_token.Persist(r.Context(), u.repo, t, time.Now()); err != nil {
-			return err
-		}
+	if err := u.auth.Provider.UnlinkUser(r.Context(), us.Auth0ID); err != nil {
+		return errors.Wrapf(err, "unlinking user %q", us.ID)
 	}
 
-	// add user_id to app_metadata
-	if err := u.UpdateUserAppMetaData(t, sub, us.ID); err != nil {
-		return err
+	if err := account.Delete(r.Context(), u.repo, us.ID, transfer); err != nil {
+		switch err {
+		case user.ErrNotFound:
+			return web.NewRequestError(err, http.StatusNotFound)
+		default:
+			return errors.Wrapf(err, "deleting user %q", us.ID)
+		}
 	}
 
-	return web.Respond(r.Context(), w, us, http.StatusCreated)
+	return web.Respond(r.Context(), w, nil, http.StatusNoContent)
 }
 
-// Update auth0 user account with user_id from database
-func (u *Users) UpdateUserAppMetaData(token *ma_token.Token, aid, userId string) error {
-
-	if _, err := uuid.Parse(userId); err != nil {
-		return user.ErrInvalidID
-	}
-
-	baseUrl := "https://" + u.auth0.Domain
-	resource := "/api/v2/users/" + aid
-
-	uri, err := url.ParseRequestURI(baseUrl)
+// ExportMe responds with everything the user owns as a JSON file, see
+// account.Export.
+func (u *Users) ExportMe(w http.ResponseWriter, r *http.Request) error {
+	us, err := u.me(r)
 	if err != nil {
 		return err
 	}
 
-	uri.Path = resource
-	urlStr := uri.String()
+	now := time.Now()
 
-	jsonStr := fmt.Sprintf("{\"app_metadata\": { \"id\": \"%s\" }}", userId)
-
-	req, err := http.NewRequest(http.MethodPatch, urlStr, strings.NewReader(jsonStr))
+	a, err := account.Export(r.Context(), u.repo, us.ID, now)
 	if err != nil {
-		return err
+		switch err {
+		case user.ErrNotFound:
+			return web.NewRequestError(err, http.StatusNotFound)
+		default:
+			return errors.Wrapf(err, "exporting user %q", us.ID)
+		}
 	}
 
-	req.Header.Add("content-type", "application/json")
-	req.Header.Add("authorization", fmt.Sprintf("Bearer %s", token.AccessToken))
-
-	res, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return err
-	}
-	defer res.Body.Close()
+	name := fmt.Sprintf("devpie-export-%s.json", now.UTC().Format("20060102"))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
 
-	return nil
+	return web.Respond(r.Context(), w, a, http.StatusOK)
 }
 
-// Create auth0 management token
-func (u *Users) NewManagementToken() (*ma_token.Token, error) {
-	baseUrl := "https://" + u.auth0.Domain
-	resource := "/oauth/token"
+// me finds the user making the request, by id or else by subject.
+func (u *Users) me(r *http.Request) (*user.User, error) {
+	var us *user.User
+	var err error
 
-	data := url.Values{}
-	data.Set("grant_type", "client_credentials")
-	data.Set("client_id", u.auth0.M2MClient)
-	data.Set("client_secret", u.auth0.M2MSecret)
-	data.Set("audience", u.auth0.MAPIAudience)
+	id := u.auth.GetUserById(r)
 
-	uri, err := url.ParseRequestURI(baseUrl)
-	if err != nil {
-		return nil, err
+	if id == "" {
+		us, err = u.users.RetrieveMeBySubject(r.Context(), u.auth.GetUserBySubject(r))
+	} else {
+		us, err = u.users.RetrieveMeById(r.Context(), id)
 	}
 
-	uri.Path = resource
-	urlStr := uri.String()
-
-	req, err := http.NewRequest(http.MethodPost, urlStr, strings.NewReader(data.Encode()))
 	if err != nil {
-		return nil, err
+		switch err {
+		case user.ErrNotFound:
+			return nil, web.NewRequestError(err, http.StatusNotFound)
+		case user.ErrInvalidID:
+			return nil, web.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return nil, errors.Wrapf(err, "looking for user %q", id)
+		}
 	}
 
-	req.Header.Add("content-type", "application/x-www-form-urlencoded")
-
-	res, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer res.Body.Close()
+	return us, nil
+}
 
-	body, err := ioutil.ReadAll(res.Body)
-	if err != nil {
-		return nil, err
-	}
+// Create a new user. Creating a user that exists returns it. The user is
+// linked to the account of the identity provider, a link that fails stays
+// pending and is retried in the background, see provision.Run.
+func (u *Users) Create(w http.ResponseWriter, r *http.Request) error {
+	sub := u.auth.GetUserBySubject(r)
 
-	token := ma_token.Token{}
-	err = json.Unmarshal(body, &token)
-	if err != nil {
-		return nil, err
+	var nu user.NewUser
+	if err := web.Decode(r, &nu); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return err
 	}
 
-	return &token, nil
-}
+	now := time.Now()
 
-// Check management api token for expiration
-func (u *Users) IsExpired(t *ma_token.Token) bool {
-	token, err := jwt.ParseWithClaims(t.AccessToken, &ma_token.CustomClaims{}, func(token *jwt.Token) (interface{}, error) {
-		cert, err := u.auth0.GetPemCert(token)
-		if err != nil {
-			return true, err
-		}
-		return jwt.ParseRSAPublicKeyFromPEM([]byte(cert))
-	})
+	us, created, err := u.users.Provision(r.Context(), nu, sub, now)
 	if err != nil {
-		u.log.Print("error parsing with claims")
-		return true
+		return err
 	}
-
-	claims, ok := token.Claims.(*ma_token.CustomClaims)
-	if !ok || !token.Valid {
-		u.log.Print("not ok or not valid")
-		return true
+	if !created {
+		return web.Respond(r.Context(), w, us, http.StatusOK)
 	}
 
-	if claims.ExpiresAt < time.Now().UTC().Unix() {
-		u.log.Print("token expired")
-		return true
+	l := user.Link{UserID: us.ID, Auth0ID: sub, NextAttempt: now, Created: now}
+	if err := provision.Link(r.Context(), u.users, u.auth.Provider, l, now); err != nil {
+		u.log.Printf("provision : %v", err)
+	} else {
+		us.LinkStatus = user.LinkLinked
 	}
 
-	return false
+	return web.Respond(r.Context(), w, us, http.StatusCreated)
 }
@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ivorscott/devpie-client-backend-go/internal/user"
+	"github.com/pkg/errors"
+)
+
+func TestUsersRetrieveMe(t *testing.T) {
+	s := newStores()
+	h := &Users{users: s.users, log: s.log, auth: s.auth}
+
+	u, err := s.users.Create(context.Background(), user.NewUser{Email: "jane@example.com"}, "auth0|jane", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rt := route{method: http.MethodGet, pattern: "/users/me", handler: h.RetrieveMe}
+
+	tests := []struct {
+		name   string
+		uid    string
+		status int
+	}{
+		{"known", u.ID, http.StatusOK},
+		{"unknown", testUser, http.StatusNotFound},
+		{"invalid id", "jane", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := s.serve(t, rt, tt.uid, "/users/me", nil, nil)
+			if rec.Code != tt.status {
+				t.Fatalf("status: got %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+
+			var got user.User
+			decode(t, rec, &got)
+			if got.ID != u.ID || got.Email != u.Email {
+				t.Errorf("user: got %+v, want %+v", got, u)
+			}
+		})
+	}
+}
+
+func TestUsersCreate(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status string
+	}{
+		{"linked", nil, user.LinkLinked},
+		{"provider failure", errors.New("provider unavailable"), user.LinkPending},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newStores()
+			s.provider.err = tt.err
+			h := &Users{users: s.users, log: s.log, auth: s.auth}
+
+			rt := route{method: http.MethodPost, pattern: "/users", handler: h.Create}
+
+			rec := s.serve(t, rt, testUser, "/users", user.NewUser{Email: "jane@example.com"}, nil)
+			if rec.Code != http.StatusCreated {
+				t.Fatalf("status: got %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body)
+			}
+
+			var got user.User
+			decode(t, rec, &got)
+			if got.LinkStatus != tt.status {
+				t.Errorf("link status: got %q, want %q", got.LinkStatus, tt.status)
+			}
+
+			links, err := s.users.PendingLinks(context.Background(), time.Now().Add(time.Hour), 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.err == nil {
+				if uid := s.provider.synced["auth0|"+testUser]; uid != got.ID {
+					t.Errorf("synced user id: got %q, want %q", uid, got.ID)
+				}
+				if len(links) != 0 {
+					t.Errorf("pending links: got %d, want none", len(links))
+				}
+			} else if len(links) != 1 || links[0].Attempts != 1 {
+				t.Errorf("pending links: got %+v, want the failed one", links)
+			}
+
+			// Creating the user again returns it.
+			rec = s.serve(t, rt, testUser, "/users", user.NewUser{Email: "jane@example.com"}, nil)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("again: status %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+			}
+			var again user.User
+			decode(t, rec, &again)
+			if again.ID != got.ID {
+				t.Errorf("again: got user %q, want %q", again.ID, got.ID)
+			}
+		})
+	}
+}
+
+func TestUsersUpdateMe(t *testing.T) {
+	s := newStores()
+	h := &Users{users: s.users, log: s.log, auth: s.auth}
+
+	first := "Jane"
+	u, err := s.users.Create(context.Background(), user.NewUser{Email: "jane@example.com", FirstName: &first}, "auth0|jane", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rt := route{method: http.MethodPatch, pattern: "/users/me", handler: h.UpdateMe}
+
+	tests := []struct {
+		name   string
+		body   map[string]interface{}
+		status int
+	}{
+		{"profile", map[string]interface{}{"lastName": "Doe", "picture": "https://example.com/jane.png"}, http.StatusOK},
+		{"invalid picture", map[string]interface{}{"picture": "jane.png"}, http.StatusBadRequest},
+		{"long locale", map[string]interface{}{"locale": "en-GB-oxendict"}, http.StatusBadRequest},
+		{"unknown field", map[string]interface{}{"email": "john@example.com"}, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := s.serve(t, rt, u.ID, "/users/me", tt.body, nil)
+			if rec.Code != tt.status {
+				t.Fatalf("status: got %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+		})
+	}
+
+	got, err := s.users.RetrieveMeById(context.Background(), u.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.FirstName == nil || *got.FirstName != first {
+		t.Errorf("first name: got %v, want it unchanged", got.FirstName)
+	}
+	if got.LastName == nil || *got.LastName != "Doe" || got.Picture == nil || *got.Picture != "https://example.com/jane.png" {
+		t.Errorf("profile: got %+v, want the update", got)
+	}
+	if got.Email != u.Email || got.Locale != nil {
+		t.Errorf("profile: got %+v, want the rejected updates left out", got)
+	}
+}
+
+func TestUsersDeleteMe(t *testing.T) {
+	s := newStores()
+	s.provider.err = errors.New("provider unavailable")
+	h := &Users{users: s.users, log: s.log, auth: s.auth}
+
+	u, err := s.users.Create(context.Background(), user.NewUser{Email: "jane@example.com"}, "auth0|jane", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rt := route{method: http.MethodDelete, pattern: "/users/me", handler: h.DeleteMe}
+
+	tests := []struct {
+		name   string
+		target string
+		status int
+	}{
+		{"invalid projects option", "/users/me?projects=keep", http.StatusBadRequest},
+		{"provider failure", "/users/me", http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := s.serve(t, rt, u.ID, tt.target, nil, nil)
+			if rec.Code != tt.status {
+				t.Fatalf("status: got %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+		})
+	}
+
+	// Nothing is deleted unless the user is unlinked.
+	if _, err := s.users.RetrieveMeById(context.Background(), u.ID); err != nil {
+		t.Errorf("user: got %v, want it kept", err)
+	}
+}
@@ -14,8 +14,18 @@ import (
 	"time"
 
 	"github.com/ivorscott/devpie-client-backend-go/cmd/api/internal/handlers"
+	"github.com/ivorscott/devpie-client-backend-go/internal/activity"
+	"github.com/ivorscott/devpie-client-backend-go/internal/auth0"
+	"github.com/ivorscott/devpie-client-backend-go/internal/identity"
+	"github.com/ivorscott/devpie-client-backend-go/internal/ma_token"
+	"github.com/ivorscott/devpie-client-backend-go/internal/mid"
+	"github.com/ivorscott/devpie-client-backend-go/internal/platform/auth"
 	"github.com/ivorscott/devpie-client-backend-go/internal/platform/conf"
 	"github.com/ivorscott/devpie-client-backend-go/internal/platform/database"
+	"github.com/ivorscott/devpie-client-backend-go/internal/platform/hub"
+	"github.com/ivorscott/devpie-client-backend-go/internal/provision"
+	"github.com/ivorscott/devpie-client-backend-go/internal/purge"
+	"github.com/ivorscott/devpie-client-backend-go/internal/user"
 	"github.com/pkg/errors"
 )
 
@@ -40,18 +50,26 @@ func run() error {
 
 	var cfg struct {
 		Web struct {
-			Address          string        `conf:"default:localhost:4000"`
-			Debug            string        `conf:"default:localhost:6060"`
-			Production       bool          `conf:"default:false"`
-			ReadTimeout      time.Duration `conf:"default:5s"`
-			WriteTimeout     time.Duration `conf:"default:5s"`
-			ShutdownTimeout  time.Duration `conf:"default:5s"`
-			FrontendAddress  string        `conf:"default:https://localhost:3000"`
-			AuthDomain       string        `conf:"default:none,noprint"`
-			AuthAudience     string        `conf:"default:none,noprint"`
-			AuthM2MClient    string        `conf:"default:none,noprint"`
-			AuthM2MSecret    string        `conf:"default:none,noprint"`
-			AuthMAPIAudience string        `conf:"default:none,noprint"`
+			Address              string        `conf:"default:localhost:4000"`
+			Debug                string        `conf:"default:localhost:6060"`
+			Production           bool          `conf:"default:false"`
+			ReadTimeout          time.Duration `conf:"default:5s"`
+			WriteTimeout         time.Duration `conf:"default:5s"`
+			ShutdownTimeout      time.Duration `conf:"default:5s"`
+			FrontendAddress      string        `conf:"default:https://localhost:3000"`
+			AuthDomain           string        `conf:"default:none,noprint"`
+			AuthAudience         string        `conf:"default:none,noprint"`
+			AuthM2MClient        string        `conf:"default:none,noprint"`
+			AuthM2MSecret        string        `conf:"default:none,noprint"`
+			AuthMAPIAudience     string        `conf:"default:none,noprint"`
+			AuthKeysTTL          time.Duration `conf:"default:1h"`
+			AuthMode             string        `conf:"default:auth0"`
+			AuthDevKey           string        `conf:"default:dev-auth.pem,noprint"`
+			AuthIssuer           string        `conf:"default:none"`
+			AuthClaimSubject     string        `conf:"default:sub"`
+			AuthClaimUserID      string        `conf:"default:https://client.devpie.io/claims/user_id"`
+			AuthClaimScope       string        `conf:"default:scope"`
+			AuthClaimPermissions string        `conf:"default:permissions"`
 		}
 		DB struct {
 			User       string `conf:"default:postgres,noprint"`
@@ -60,6 +78,13 @@ func run() error {
 			Name       string `conf:"default:postgres,noprint"`
 			DisableTLS bool   `conf:"default:false"`
 		}
+		Trash struct {
+			Retention     time.Duration `conf:"default:720h"`
+			PurgeInterval time.Duration `conf:"default:1h"`
+		}
+		Provision struct {
+			Interval time.Duration `conf:"default:1m"`
+		}
 	}
 
 	if err := conf.Parse(os.Args[1:], "API", &cfg); err != nil {
@@ -122,6 +147,31 @@ func run() error {
 	}
 	defer repo.Close()
 
+	// =========================================================================
+	// Start Activity Feed
+
+	// Changes recorded by any replica are published to the event streams of
+	// this one.
+	events := hub.New()
+	defer events.Close()
+
+	feed, stopFeed := context.WithCancel(context.Background())
+	defer stopFeed()
+
+	go func() {
+		if err := activity.Listen(feed, repo, events, infolog); err != nil {
+			log.Printf("main : Activity feed stopped : %v", err)
+		}
+	}()
+
+	// =========================================================================
+	// Start Trash Purge
+
+	purging, stopPurge := context.WithCancel(context.Background())
+	defer stopPurge()
+
+	go purge.Run(purging, repo, infolog, cfg.Trash.Retention, cfg.Trash.PurgeInterval)
+
 	// =========================================================================
 	// Clean Logs
 
@@ -138,10 +188,70 @@ func run() error {
 	// Make a channel to listen for shutdown signal from the OS.
 	shutdown := make(chan os.Signal, 1)
 
+	a0 := identity.Auth0Config{
+		Audience:     cfg.Web.AuthAudience,
+		Domain:       cfg.Web.AuthDomain,
+		M2MClient:    cfg.Web.AuthM2MClient,
+		M2MSecret:    cfg.Web.AuthM2MSecret,
+		MAPIAudience: cfg.Web.AuthMAPIAudience,
+	}
+
+	var provider identity.Provider
+
+	switch cfg.Web.AuthMode {
+	case "auth0":
+		client := auth0.NewClient(a0.Management(), ma_token.NewPostgres(repo))
+		provider = identity.NewAuth0(a0, auth.NewJWKS(a0.JWKS(), cfg.Web.AuthKeysTTL), client)
+
+	case "oidc":
+		claims := identity.Claims{
+			Subject:     cfg.Web.AuthClaimSubject,
+			UserID:      cfg.Web.AuthClaimUserID,
+			Scope:       cfg.Web.AuthClaimScope,
+			Permissions: cfg.Web.AuthClaimPermissions,
+		}
+
+		p, err := identity.NewDiscoveredOIDC(context.Background(), cfg.Web.AuthIssuer, cfg.Web.AuthAudience, claims, cfg.Web.AuthKeysTTL)
+		if err != nil {
+			return errors.Wrap(err, "discovering identity provider")
+		}
+		provider = p
+
+	case "dev":
+		if cfg.Web.Production {
+			return errors.New("the dev auth mode can't be used in production")
+		}
+
+		// The API stands in for the tenant, its own address is the domain.
+		if a0.Domain == "none" {
+			a0.Domain = cfg.Web.Address
+		}
+
+		key, err := auth.LoadKey(cfg.Web.AuthDevKey)
+		if err != nil {
+			return errors.Wrap(err, "loading dev auth key")
+		}
+		provider = identity.NewDev(auth.NewIssuer(key, a0.Issuer(), a0.Audience))
+
+		infolog.Printf("main : Dev auth mode : tokens are issued at POST /v1/dev/token")
+
+	default:
+		return errors.Errorf("unknown auth mode %q", cfg.Web.AuthMode)
+	}
+
+	users := user.NewPostgres(repo)
+	authn := &mid.Auth{Provider: provider, Users: users}
+
+	// Users whose link to the account of the provider failed are linked in
+	// the background.
+	provisioning, stopProvisioning := context.WithCancel(context.Background())
+	defer stopProvisioning()
+
+	go provision.Run(provisioning, users, provider, infolog, cfg.Provision.Interval)
+
 	api := http.Server{
-		Addr: cfg.Web.Address,
-		Handler: handlers.API(shutdown, repo, infolog, cfg.Web.FrontendAddress, cfg.Web.AuthAudience,
-			cfg.Web.AuthDomain, cfg.Web.AuthM2MClient, cfg.Web.AuthM2MSecret, cfg.Web.AuthMAPIAudience),
+		Addr:         cfg.Web.Address,
+		Handler:      handlers.API(shutdown, repo, events, infolog, cfg.Web.FrontendAddress, authn),
 		ReadTimeout:  cfg.Web.ReadTimeout,
 		WriteTimeout: cfg.Web.WriteTimeout,
 		ErrorLog:     discardLog,
@@ -180,6 +290,9 @@ func run() error {
 		ctx, cancel := context.WithTimeout(context.Background(), cfg.Web.ShutdownTimeout)
 		defer cancel()
 
+		// End the event streams, they would otherwise hold up the shutdown.
+		events.Close()
+
 		// Asking listener to shutdown and load shed.
 		err := api.Shutdown(ctx)
 		if err != nil {
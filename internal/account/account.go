@@ -0,0 +1,193 @@
+// Package account deletes the accounts of users and exports everything they
+// own.
+package account
+
+import (
+	"context"
+	"time"
+
+	"github.com/ivorscott/devpie-client-backend-go/internal/activity"
+	"github.com/ivorscott/devpie-client-backend-go/internal/column"
+	"github.com/ivorscott/devpie-client-backend-go/internal/comment"
+	"github.com/ivorscott/devpie-client-backend-go/internal/label"
+	"github.com/ivorscott/devpie-client-backend-go/internal/member"
+	"github.com/ivorscott/devpie-client-backend-go/internal/platform/database"
+	"github.com/ivorscott/devpie-client-backend-go/internal/project"
+	"github.com/ivorscott/devpie-client-backend-go/internal/purge"
+	"github.com/ivorscott/devpie-client-backend-go/internal/task"
+	"github.com/ivorscott/devpie-client-backend-go/internal/template"
+	"github.com/ivorscott/devpie-client-backend-go/internal/user"
+	"github.com/pkg/errors"
+)
+
+// Delete removes the account of the user uid. With transfer, the projects the
+// user owns are handed to their successor, see member.Successor, and only the
+// projects without other members are deleted. Without it every owned project
+// is deleted. The comments and activity of the user stay in the projects of
+// others without an author. Nothing is removed unless everything is.
+func Delete(ctx context.Context, repo *database.Repository, uid string, transfer bool) error {
+	ctx, err := repo.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer repo.Rollback(ctx)
+
+	if _, err := user.RetrieveMeById(ctx, repo, uid); err != nil {
+		return err
+	}
+
+	owned, err := project.ListOwned(ctx, repo, uid)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range owned {
+		if transfer && p.Deleted == nil {
+			to, err := member.Successor(ctx, repo, p.ID, uid)
+			if err == nil {
+				if err := handOver(ctx, repo, p.ID, to.UserID); err != nil {
+					return err
+				}
+				continue
+			}
+			if err != member.ErrNotFound {
+				return err
+			}
+		}
+
+		if err := purge.Project(ctx, repo, p.ID); err != nil {
+			return errors.Wrapf(err, "deleting project %s", p.ID)
+		}
+	}
+
+	if err := member.DeleteUser(ctx, repo, uid); err != nil {
+		return err
+	}
+	if err := template.DeleteAll(ctx, repo, uid); err != nil {
+		return err
+	}
+	if err := task.Unassign(ctx, repo, uid); err != nil {
+		return err
+	}
+	if err := comment.Anonymize(ctx, repo, uid); err != nil {
+		return err
+	}
+	if err := activity.Anonymize(ctx, repo, uid); err != nil {
+		return err
+	}
+	if err := user.Delete(ctx, repo, uid); err != nil {
+		return err
+	}
+
+	return repo.Commit(ctx)
+}
+
+// handOver makes the member uid the owner of the project pid.
+func handOver(ctx context.Context, repo *database.Repository, pid, uid string) error {
+	if err := member.Transfer(ctx, repo, pid, uid); err != nil {
+		return errors.Wrapf(err, "transferring project %s", pid)
+	}
+
+	return project.Transfer(ctx, repo, pid, uid)
+}
+
+// Archive is everything a user owns.
+type Archive struct {
+	User        *user.User          `json:"user"`
+	Projects    []Project           `json:"projects"`
+	Memberships []member.Member     `json:"memberships"`
+	Templates   []template.Template `json:"templates"`
+	Comments    []comment.Comment   `json:"comments"`
+	Activity    []activity.Event    `json:"activity"`
+	Exported    time.Time           `json:"exported"`
+}
+
+// Project is a project the user owns with its content.
+type Project struct {
+	project.Project
+	Columns []column.Column `json:"columns"`
+	Tasks   []task.Task     `json:"tasks"`
+	Labels  []label.Label   `json:"labels"`
+	Members []member.Member `json:"members"`
+}
+
+// Export returns the Archive of the user uid: its profile, the projects it
+// owns, archived and deleted ones included, its memberships and templates,
+// and the comments and activity it authored in any project.
+func Export(ctx context.Context, repo *database.Repository, uid string, now time.Time) (*Archive, error) {
+	u, err := user.RetrieveMeById(ctx, repo, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	a := Archive{User: u, Projects: make([]Project, 0), Templates: make([]template.Template, 0), Exported: now.UTC()}
+
+	owned, err := project.ListOwned(ctx, repo, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range owned {
+		ep, err := exportProject(ctx, repo, p)
+		if err != nil {
+			return nil, errors.Wrapf(err, "exporting project %s", p.ID)
+		}
+		a.Projects = append(a.Projects, *ep)
+	}
+
+	if a.Memberships, err = member.ListByUser(ctx, repo, uid); err != nil {
+		return nil, err
+	}
+
+	ts, err := template.List(ctx, repo, uid)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range ts {
+		if !t.Builtin {
+			a.Templates = append(a.Templates, t)
+		}
+	}
+
+	if a.Comments, err = comment.ListByAuthor(ctx, repo, uid); err != nil {
+		return nil, err
+	}
+	if a.Activity, err = activity.ListByActor(ctx, repo, uid); err != nil {
+		return nil, err
+	}
+
+	return &a, nil
+}
+
+// exportProject returns p with its columns, labels, members and tasks, open,
+// archived and deleted ones.
+func exportProject(ctx context.Context, repo *database.Repository, p project.Project) (*Project, error) {
+	var err error
+	ep := Project{Project: p, Tasks: make([]task.Task, 0)}
+
+	if ep.Columns, err = column.List(ctx, repo, p.ID); err != nil {
+		return nil, err
+	}
+	if ep.Labels, err = label.List(ctx, repo, p.ID); err != nil {
+		return nil, err
+	}
+	if ep.Members, err = member.List(ctx, repo, p.ID); err != nil {
+		return nil, err
+	}
+
+	for _, f := range []task.Filter{{}, {Archived: true}} {
+		ts, err := task.List(ctx, repo, p.ID, f, database.Page{})
+		if err != nil {
+			return nil, err
+		}
+		ep.Tasks = append(ep.Tasks, ts...)
+	}
+
+	trash, err := task.ListTrash(ctx, repo, p.ID)
+	if err != nil {
+		return nil, err
+	}
+	ep.Tasks = append(ep.Tasks, trash...)
+
+	return &ep, nil
+}
@@ -0,0 +1,267 @@
+package activity
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"reflect"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/ivorscott/devpie-client-backend-go/internal/platform/database"
+	"github.com/jmoiron/sqlx/types"
+	"github.com/pkg/errors"
+)
+
+// The Activity package shouldn't know anything about http
+// While it may identify common know errors, how to respond is left to the handlers
+var (
+	ErrNotFound  = errors.New("event not found")
+	ErrInvalidID = errors.New("id provided was not a valid UUID")
+)
+
+// columns are the selected columns of an event.
+var columns = []string{
+	"event_id",
+	"project_id",
+	"task_id",
+	"column_id",
+	"user_id",
+	"action",
+	"before",
+	"after",
+	"created",
+}
+
+// Retrieve finds the Event identified by eid.
+func Retrieve(ctx context.Context, repo *database.Repository, eid string) (*Event, error) {
+	var e Event
+
+	if _, err := uuid.Parse(eid); err != nil {
+		return nil, ErrInvalidID
+	}
+
+	stmt := repo.SQ.Select(columns...).From("activity").Where(sq.Eq{"event_id": eid})
+
+	q, args, err := stmt.ToSql()
+	if err != nil {
+		return nil, errors.Wrapf(err, "building query: %v", args)
+	}
+
+	if err := repo.GetContext(ctx, &e, q, args...); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &e, nil
+}
+
+// Record adds an Event to the activity log of a project.
+func Record(ctx context.Context, repo *database.Repository, ne NewEvent, now time.Time) (*Event, error) {
+	e, err := newEvent(ne, now)
+	if err != nil {
+		return nil, err
+	}
+
+	stmt := repo.SQ.Insert(
+		"activity",
+	).SetMap(map[string]interface{}{
+		"event_id":   e.ID,
+		"project_id": e.ProjectID,
+		"task_id":    e.TaskID,
+		"column_id":  e.ColumnID,
+		"user_id":    e.ActorID,
+		"action":     e.Action,
+		"before":     e.Before,
+		"after":      e.After,
+		"created":    e.Created,
+	})
+
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return nil, errors.Wrapf(err, "inserting %s event", e.Action)
+	}
+
+	return e, nil
+}
+
+// newEvent builds the Event recording ne. An event without an actor is
+// stored without one, like the events of deleted users.
+func newEvent(ne NewEvent, now time.Time) (*Event, error) {
+	before, after, err := Diff(ne.Before, ne.After)
+	if err != nil {
+		return nil, err
+	}
+
+	var actor *string
+	if ne.ActorID != "" {
+		actor = &ne.ActorID
+	}
+
+	return &Event{
+		ID:        uuid.New().String(),
+		ProjectID: ne.ProjectID,
+		TaskID:    ne.TaskID,
+		ColumnID:  ne.ColumnID,
+		ActorID:   actor,
+		Action:    ne.Action,
+		Before:    before,
+		After:     after,
+		Created:   now.UTC(),
+	}, nil
+}
+
+// Keys order the listings of events, newest first. An Event's values of the
+// keys are those of Key.
+var Keys = []database.Key{{Expr: "created", Desc: true}, {Expr: "event_id"}}
+
+// List returns a page of the events of a project, newest first.
+func List(ctx context.Context, repo *database.Repository, pid string, page database.Page) ([]Event, error) {
+	return list(ctx, repo, sq.Eq{"project_id": pid}, page)
+}
+
+// ListTask returns a page of the events of a single task, newest first.
+func ListTask(ctx context.Context, repo *database.Repository, pid, tid string, page database.Page) ([]Event, error) {
+	if _, err := uuid.Parse(tid); err != nil {
+		return nil, ErrInvalidID
+	}
+
+	return list(ctx, repo, sq.Eq{"project_id": pid, "task_id": tid}, page)
+}
+
+// ListByActor returns every event recorded for the user uid, newest first.
+func ListByActor(ctx context.Context, repo *database.Repository, uid string) ([]Event, error) {
+	return list(ctx, repo, sq.Eq{"user_id": uid}, database.Page{})
+}
+
+// ListSince returns up to limit events of a project recorded after the event
+// eid, oldest first. It lets a client resume a stream of events.
+func ListSince(ctx context.Context, repo *database.Repository, pid, eid string, limit int) ([]Event, error) {
+	var es = make([]Event, 0)
+
+	if _, err := uuid.Parse(eid); err != nil {
+		return nil, ErrInvalidID
+	}
+
+	stmt := repo.SQ.Select(columns...).From("activity").Where(
+		sq.Eq{"project_id": pid},
+	).Where(
+		"(created, event_id) > (SELECT created, event_id FROM activity WHERE event_id = ?)", eid,
+	).OrderBy("created", "event_id").Limit(uint64(limit))
+
+	q, args, err := stmt.ToSql()
+	if err != nil {
+		return nil, errors.Wrapf(err, "building query: %v", args)
+	}
+
+	if err := repo.SelectContext(ctx, &es, q, args...); err != nil {
+		return nil, errors.Wrap(err, "selecting activity")
+	}
+
+	return es, nil
+}
+
+// DeleteAll removes the activity log of the project pid.
+func DeleteAll(ctx context.Context, repo *database.Repository, pid string) error {
+	if _, err := uuid.Parse(pid); err != nil {
+		return ErrInvalidID
+	}
+
+	stmt := repo.SQ.Delete(
+		"activity",
+	).Where(sq.Eq{"project_id": pid})
+
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return errors.Wrapf(err, "deleting all activity")
+	}
+
+	return nil
+}
+
+// Anonymize removes the user uid from the events it was recorded for. The
+// events stay in the activity log of their projects.
+func Anonymize(ctx context.Context, repo *database.Repository, uid string) error {
+	stmt := repo.SQ.Update(
+		"activity",
+	).Set("user_id", nil).Where(sq.Eq{"user_id": uid})
+
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return errors.Wrapf(err, "anonymizing activity of user %s", uid)
+	}
+
+	return nil
+}
+
+// Diff returns the JSON fields of before and after whose values differ. When
+// one side is nil the other is returned whole.
+func Diff(before, after interface{}) (types.JSONText, types.JSONText, error) {
+	b, err := fields(before)
+	if err != nil {
+		return nil, nil, err
+	}
+	a, err := fields(after)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if b != nil && a != nil {
+		for k, v := range b {
+			if reflect.DeepEqual(v, a[k]) {
+				delete(b, k)
+				delete(a, k)
+			}
+		}
+	}
+
+	bj, err := json.Marshal(b)
+	if err != nil {
+		return nil, nil, err
+	}
+	aj, err := json.Marshal(a)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return types.JSONText(bj), types.JSONText(aj), nil
+}
+
+func list(ctx context.Context, repo *database.Repository, where sq.Eq, page database.Page) ([]Event, error) {
+	var es = make([]Event, 0)
+
+	stmt, err := page.Apply(repo.SQ.Select(columns...).From("activity").Where(where), Keys)
+	if err != nil {
+		return nil, err
+	}
+
+	q, args, err := stmt.ToSql()
+	if err != nil {
+		return nil, errors.Wrapf(err, "building query: %v", args)
+	}
+
+	if err := repo.SelectContext(ctx, &es, q, args...); err != nil {
+		return nil, errors.Wrap(err, "selecting activity")
+	}
+
+	return es, nil
+}
+
+// fields decodes the JSON form of v into a map, nil stays nil.
+func fields(v interface{}) (map[string]interface{}, error) {
+	if v == nil || reflect.ValueOf(v).Kind() == reflect.Ptr && reflect.ValueOf(v).IsNil() {
+		return nil, nil
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, errors.Wrap(err, "encoding activity")
+	}
+
+	var m map[string]interface{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, errors.Wrap(err, "decoding activity")
+	}
+
+	return m, nil
+}
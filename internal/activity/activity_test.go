@@ -0,0 +1,54 @@
+package activity
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDiff(t *testing.T) {
+	type task struct {
+		Title  string `json:"title"`
+		Column string `json:"columnId"`
+	}
+
+	tests := []struct {
+		name          string
+		before, after interface{}
+		wantBefore    string
+		wantAfter     string
+	}{
+		{"created", nil, task{"a", "c1"}, `null`, `{"columnId":"c1","title":"a"}`},
+		{"deleted", &task{"a", "c1"}, (*task)(nil), `{"columnId":"c1","title":"a"}`, `null`},
+		{"renamed", task{"a", "c1"}, task{"b", "c1"}, `{"title":"a"}`, `{"title":"b"}`},
+		{"unchanged", task{"a", "c1"}, task{"a", "c1"}, `{}`, `{}`},
+	}
+
+	for _, tt := range tests {
+		before, after, err := Diff(tt.before, tt.after)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if string(before) != tt.wantBefore || string(after) != tt.wantAfter {
+			t.Errorf("%s: got %s -> %s, want %s -> %s", tt.name, before, after, tt.wantBefore, tt.wantAfter)
+		}
+	}
+}
+
+func TestNewEventActor(t *testing.T) {
+	e, err := newEvent(NewEvent{ProjectID: "p1", ActorID: "u1", Action: TaskCreated}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.ActorID == nil || *e.ActorID != "u1" {
+		t.Errorf("got actor %v, want u1", e.ActorID)
+	}
+
+	// No actor is stored as NULL, not as an empty user id.
+	e, err = newEvent(NewEvent{ProjectID: "p1", Action: TaskCreated}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.ActorID != nil {
+		t.Errorf("got actor %q, want none", *e.ActorID)
+	}
+}
@@ -0,0 +1,80 @@
+package activity
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/ivorscott/devpie-client-backend-go/internal/platform/database"
+	"github.com/ivorscott/devpie-client-backend-go/internal/platform/hub"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+// channel is the Postgres notification channel an insert into the activity
+// table is announced on. Every API replica listens to it, so clients see the
+// changes made through any replica.
+const channel = "activity"
+
+// notification is the payload of an activity notification.
+type notification struct {
+	ID        string `json:"id"`
+	ProjectID string `json:"projectId"`
+}
+
+// Listen publishes the events recorded in any project to h, on the topic of
+// the project id, until ctx is done.
+func Listen(ctx context.Context, repo *database.Repository, h *hub.Hub, log *log.Logger) error {
+	l := pq.NewListener(repo.URL.String(), 10*time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("activity : listener : %v", err)
+		}
+	})
+	defer l.Close()
+
+	if err := l.Listen(channel); err != nil {
+		return errors.Wrapf(err, "listening to %s", channel)
+	}
+
+	ping := time.NewTicker(90 * time.Second)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ping.C:
+			go l.Ping()
+		case n := <-l.Notify:
+			// A nil notification follows a reconnect, events in between are
+			// replayed by clients resuming from their last event.
+			if n == nil {
+				continue
+			}
+			if err := publish(ctx, repo, h, n.Extra); err != nil {
+				log.Printf("activity : publishing %s : %+v", n.Extra, err)
+			}
+		}
+	}
+}
+
+func publish(ctx context.Context, repo *database.Repository, h *hub.Hub, payload string) error {
+	var n notification
+	if err := json.Unmarshal([]byte(payload), &n); err != nil {
+		return errors.Wrap(err, "decoding notification")
+	}
+
+	e, err := Retrieve(ctx, repo, n.ID)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return errors.Wrap(err, "encoding event")
+	}
+
+	h.Publish(e.ProjectID, hub.Message{ID: e.ID, Name: e.Action, Data: data})
+	return nil
+}
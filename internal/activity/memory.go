@@ -0,0 +1,39 @@
+package activity
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Memory is a Recorder keeping events in memory.
+type Memory struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+// NewMemory returns an empty Memory recorder.
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+func (s *Memory) Record(ctx context.Context, ne NewEvent, now time.Time) (*Event, error) {
+	e, err := newEvent(ne, now)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.events = append(s.events, *e)
+	s.mu.Unlock()
+
+	return e, nil
+}
+
+// Events returns the recorded events, oldest first.
+func (s *Memory) Events() []Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Event(nil), s.events...)
+}
@@ -0,0 +1,58 @@
+package activity
+
+import (
+	"time"
+
+	"github.com/jmoiron/sqlx/types"
+)
+
+// Actions recorded in the activity log.
+const (
+	ProjectCreated   = "project.created"
+	ProjectUpdated   = "project.updated"
+	ProjectArchived  = "project.archived"
+	ProjectDeleted   = "project.deleted"
+	ProjectRestored  = "project.restored"
+	ColumnCreated    = "column.created"
+	ColumnUpdated    = "column.updated"
+	ColumnDeleted    = "column.deleted"
+	ColumnsReordered = "columns.reordered"
+	TaskCreated      = "task.created"
+	TaskUpdated      = "task.updated"
+	TaskMoved        = "task.moved"
+	TaskDeleted      = "task.deleted"
+	TaskArchived     = "task.archived"
+	TaskRestored     = "task.restored"
+)
+
+// Event records who changed what in a project. Before and After hold the
+// fields that changed, with their old and new values. The events of deleted
+// users have no actor.
+type Event struct {
+	ID        string         `db:"event_id" json:"id"`
+	ProjectID string         `db:"project_id" json:"projectId"`
+	TaskID    *string        `db:"task_id" json:"taskId"`
+	ColumnID  *string        `db:"column_id" json:"columnId"`
+	ActorID   *string        `db:"user_id" json:"actorId"`
+	Action    string         `db:"action" json:"action"`
+	Before    types.JSONText `db:"before" json:"before"`
+	After     types.JSONText `db:"after" json:"after"`
+	Created   time.Time      `db:"created" json:"created"`
+}
+
+// Key returns the values of the event for the Keys of listings.
+func (e Event) Key() []interface{} {
+	return []interface{}{e.Created, e.ID}
+}
+
+// NewEvent is a change to record. Before and After are the changed entity as
+// it was and as it is, either may be nil when it was created or deleted.
+type NewEvent struct {
+	ProjectID string
+	TaskID    *string
+	ColumnID  *string
+	ActorID   string
+	Action    string
+	Before    interface{}
+	After     interface{}
+}
@@ -0,0 +1,28 @@
+package activity
+
+import (
+	"context"
+	"time"
+
+	"github.com/ivorscott/devpie-client-backend-go/internal/platform/database"
+)
+
+// Recorder records changes in the activity log. Postgres is the
+// implementation used by the API, Memory stands in for it in tests.
+type Recorder interface {
+	Record(ctx context.Context, ne NewEvent, now time.Time) (*Event, error)
+}
+
+// Postgres is the Recorder backed by the repository's database.
+type Postgres struct {
+	repo *database.Repository
+}
+
+// NewPostgres returns a Recorder keeping events in the repository's database.
+func NewPostgres(repo *database.Repository) *Postgres {
+	return &Postgres{repo: repo}
+}
+
+func (s *Postgres) Record(ctx context.Context, ne NewEvent, now time.Time) (*Event, error) {
+	return Record(ctx, s.repo, ne, now)
+}
@@ -0,0 +1,349 @@
+// Package auth0 is a client of the Auth0 Management API. The management
+// token it authenticates with is cached in memory and shared with the other
+// replicas of the API through a ma_token.Store.
+package auth0
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/ivorscott/devpie-client-backend-go/internal/ma_token"
+	"github.com/pkg/errors"
+)
+
+// Defaults of the Config fields left empty.
+const (
+	DefaultTimeout = 10 * time.Second
+	DefaultRetries = 3
+	DefaultBackoff = 250 * time.Millisecond
+)
+
+// leeway is how long before it expires a token is replaced.
+const leeway = time.Minute
+
+// Error is a response of Auth0 with an error status. Errors of the Client
+// are caused by an Error when Auth0 responded, see errors.Cause.
+type Error struct {
+	StatusCode int
+	Code       string
+	Message    string
+
+	// RetryAfter is how long Auth0 asked to wait before sending the request
+	// again, if it did.
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("auth0: %d %s", e.StatusCode, e.Code)
+	}
+	return fmt.Sprintf("auth0: %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// Temporary reports whether the request can succeed when sent again: Auth0
+// failed or is limiting the rate of requests.
+func (e *Error) Temporary() bool {
+	return e.StatusCode >= http.StatusInternalServerError || e.StatusCode == http.StatusTooManyRequests
+}
+
+// Config is the tenant and the machine to machine application of a Client.
+type Config struct {
+	Domain       string
+	ClientID     string
+	ClientSecret string
+	Audience     string
+
+	// Timeout bounds every request, Retries is how many times a request
+	// that failed temporarily is sent again. The wait before a retry starts
+	// at Backoff and doubles with every retry. Zero values are replaced by
+	// the defaults.
+	Timeout time.Duration
+	Retries int
+	Backoff time.Duration
+}
+
+// Client calls the Management API of a tenant.
+type Client struct {
+	BaseURL string
+	HTTP    *http.Client
+	Config  Config
+
+	tokens ma_token.Store
+	now    func() time.Time
+
+	mu     sync.Mutex
+	token  *ma_token.Token
+	flight *refresh
+}
+
+// refresh is a token refresh in progress, shared by the callers needing a
+// token in the meantime.
+type refresh struct {
+	done  chan struct{}
+	token *ma_token.Token
+	err   error
+}
+
+// NewClient returns a Client of the tenant of cfg, sharing its management
+// token through tokens.
+func NewClient(cfg Config, tokens ma_token.Store) *Client {
+	if cfg.Timeout == 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.Retries == 0 {
+		cfg.Retries = DefaultRetries
+	}
+	if cfg.Backoff == 0 {
+		cfg.Backoff = DefaultBackoff
+	}
+
+	return &Client{
+		BaseURL: "https://" + cfg.Domain,
+		HTTP:    &http.Client{Timeout: cfg.Timeout},
+		Config:  cfg,
+		tokens:  tokens,
+		now:     time.Now,
+	}
+}
+
+// UpdateAppMetadata merges metadata into the app_metadata of the user with
+// the Auth0 id aid.
+func (c *Client) UpdateAppMetadata(ctx context.Context, aid string, metadata map[string]interface{}) error {
+	body, err := json.Marshal(map[string]interface{}{"app_metadata": metadata})
+	if err != nil {
+		return errors.Wrap(err, "encoding app_metadata")
+	}
+
+	token, err := c.Token(ctx)
+	if err != nil {
+		return err
+	}
+
+	header := http.Header{
+		"Authorization": {"Bearer " + token},
+		"Content-Type":  {"application/json"},
+	}
+
+	path := "/api/v2/users/" + url.PathEscape(aid)
+	if err := c.do(ctx, http.MethodPatch, path, header, body, nil); err != nil {
+		return errors.Wrapf(err, "updating app_metadata of %s", aid)
+	}
+
+	return nil
+}
+
+// Token returns a valid management token. It is cached in memory, then in
+// the store shared by the replicas, and only requested from Auth0 when
+// neither has one. Concurrent callers share a single refresh.
+func (c *Client) Token(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	if c.token != nil && c.token.Valid(c.now().Add(leeway)) {
+		t := c.token.AccessToken
+		c.mu.Unlock()
+		return t, nil
+	}
+
+	f := c.flight
+	if f == nil {
+		f = &refresh{done: make(chan struct{})}
+		c.flight = f
+
+		// The refresh outlives the caller starting it, the other callers
+		// would otherwise fail with it.
+		go c.refresh(f)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-f.done:
+		if f.err != nil {
+			return "", f.err
+		}
+		return f.token.AccessToken, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// refresh loads a valid token from the store, or has the store replace the
+// stored one with a new token.
+func (c *Client) refresh(f *refresh) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.Config.Timeout*time.Duration(c.Config.Retries+1))
+	defer cancel()
+
+	now := c.now()
+	until := now.Add(leeway)
+
+	t, err := c.tokens.Retrieve(ctx)
+	if err == ma_token.ErrNotFound || (err == nil && !t.Valid(until)) {
+		t, err = c.tokens.Refresh(ctx, now, until, c.newToken)
+	}
+
+	c.mu.Lock()
+	if err == nil {
+		c.token = t
+	}
+	c.flight = nil
+	c.mu.Unlock()
+
+	f.token, f.err = t, errors.Wrap(err, "getting management token")
+	close(f.done)
+}
+
+// newToken requests a management token with the client credentials grant.
+func (c *Client) newToken(ctx context.Context) (*ma_token.Token, error) {
+	body, err := json.Marshal(map[string]string{
+		"grant_type":    "client_credentials",
+		"client_id":     c.Config.ClientID,
+		"client_secret": c.Config.ClientSecret,
+		"audience":      c.Config.Audience,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "encoding token request")
+	}
+
+	var resp struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+
+	header := http.Header{"Content-Type": {"application/json"}}
+	if err := c.do(ctx, http.MethodPost, "/oauth/token", header, body, &resp); err != nil {
+		return nil, errors.Wrap(err, "requesting management token")
+	}
+	if resp.AccessToken == "" {
+		return nil, errors.New("requesting management token: no access token in response")
+	}
+
+	return &ma_token.Token{
+		AccessToken: resp.AccessToken,
+		Expires:     expires(resp.AccessToken, c.now().Add(time.Duration(resp.ExpiresIn)*time.Second)),
+	}, nil
+}
+
+// expires returns the time of the exp claim of token, or def when it has
+// none. The token comes straight from Auth0, its signature isn't checked.
+func expires(token string, def time.Time) time.Time {
+	claims := jwt.MapClaims{}
+	if _, _, err := new(jwt.Parser).ParseUnverified(token, claims); err != nil {
+		return def
+	}
+	if exp, ok := claims["exp"].(float64); ok {
+		return time.Unix(int64(exp), 0)
+	}
+	return def
+}
+
+// do sends a request to the API, decoding the JSON response into v unless
+// it's nil. Requests failing temporarily are retried with backoff.
+func (c *Client) do(ctx context.Context, method, path string, header http.Header, body []byte, v interface{}) error {
+	wait := c.Config.Backoff
+
+	for attempt := 0; ; attempt++ {
+		err := c.send(ctx, method, path, header, body, v)
+		if err == nil || attempt == c.Config.Retries || !temporary(ctx, err) {
+			return err
+		}
+
+		next := wait
+		if e, ok := err.(*Error); ok && e.RetryAfter > 0 {
+			next = e.RetryAfter
+		}
+
+		select {
+		case <-time.After(next):
+		case <-ctx.Done():
+			return err
+		}
+		wait *= 2
+	}
+}
+
+// temporary reports whether a request failing with err can be sent again:
+// Auth0 failed temporarily, or the request timed out or its connection was
+// reset. Requests of a done ctx can't.
+func temporary(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	if e, ok := err.(*Error); ok {
+		return e.Temporary()
+	}
+	if e, ok := err.(net.Error); ok && e.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNRESET)
+}
+
+// send sends a request once.
+func (c *Client) send(ctx context.Context, method, path string, header http.Header, body []byte, v interface{}) error {
+	req, err := http.NewRequest(method, c.BaseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "building request")
+	}
+	for k, vs := range header {
+		req.Header[k] = vs
+	}
+
+	resp, err := c.HTTP.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return decodeError(resp)
+	}
+
+	if v == nil {
+		io.Copy(ioutil.Discard, resp.Body)
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return errors.Wrap(err, "decoding response")
+	}
+
+	return nil
+}
+
+// decodeError reads the error of a response. The Management API describes
+// errors with error and message, the authentication API with error and
+// error_description.
+func decodeError(resp *http.Response) *Error {
+	var body struct {
+		Error       string `json:"error"`
+		ErrorCode   string `json:"errorCode"`
+		Message     string `json:"message"`
+		Description string `json:"error_description"`
+	}
+	json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&body)
+
+	e := Error{StatusCode: resp.StatusCode, Code: body.ErrorCode, Message: body.Message}
+	if e.Code == "" {
+		e.Code = body.Error
+	}
+	if e.Message == "" {
+		e.Message = body.Description
+	}
+	if e.Code == "" {
+		e.Code = http.StatusText(resp.StatusCode)
+	}
+	if s, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		e.RetryAfter = time.Duration(s) * time.Second
+	}
+
+	return &e
+}
@@ -0,0 +1,275 @@
+package auth0
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/ivorscott/devpie-client-backend-go/internal/ma_token"
+	"github.com/pkg/errors"
+)
+
+// tenant stands in for an Auth0 tenant. It issues management tokens valid
+// for an hour and fails the first requests of its failures.
+type tenant struct {
+	mu       sync.Mutex
+	requests int
+	tokens   int
+	updates  []update
+	failures []int
+	delay    time.Duration
+}
+
+// update is a request to update the app_metadata of a user.
+type update struct {
+	path string
+	auth string
+	body map[string]interface{}
+}
+
+func (s *tenant) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	time.Sleep(s.delay)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests++
+	if len(s.failures) > 0 {
+		status := s.failures[0]
+		s.failures = s.failures[1:]
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"error": http.StatusText(status), "message": "failing on purpose"})
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/oauth/token":
+		s.tokens++
+		claims := jwt.MapClaims{"exp": time.Now().Add(time.Hour).Unix(), "n": s.tokens}
+		token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("secret"))
+		json.NewEncoder(w).Encode(map[string]interface{}{"access_token": token, "expires_in": 86400})
+
+	case r.Method == http.MethodPatch:
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		s.updates = append(s.updates, update{path: r.URL.EscapedPath(), auth: r.Header.Get("Authorization"), body: body})
+		w.WriteHeader(http.StatusOK)
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Not Found", "errorCode": "inexistent_user", "message": "The user does not exist."})
+	}
+}
+
+func (s *tenant) issued() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tokens
+}
+
+func newClient(t *testing.T, srv *httptest.Server, tokens ma_token.Store) *Client {
+	t.Helper()
+
+	c := NewClient(Config{Domain: "tenant.test", Retries: 2, Backoff: time.Millisecond}, tokens)
+	c.BaseURL = srv.URL
+	c.HTTP = srv.Client()
+
+	return c
+}
+
+func TestClientToken(t *testing.T) {
+	ten := &tenant{}
+	srv := httptest.NewServer(ten)
+	defer srv.Close()
+
+	store := ma_token.NewMemory()
+	c := newClient(t, srv, store)
+
+	// Concurrent callers share one refresh.
+	var wg sync.WaitGroup
+	tokens := make([]string, 20)
+	for i := range tokens {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tok, err := c.Token(context.Background())
+			if err != nil {
+				t.Error(err)
+			}
+			tokens[i] = tok
+		}(i)
+	}
+	wg.Wait()
+
+	if n := ten.issued(); n != 1 {
+		t.Fatalf("tokens issued: got %d, want 1", n)
+	}
+	for _, tok := range tokens {
+		if tok != tokens[0] {
+			t.Fatal("callers got different tokens")
+		}
+	}
+
+	// The token expires with its exp claim, not expires_in.
+	stored, err := store.Retrieve(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Until(stored.Expires); d > time.Hour || d < 59*time.Minute {
+		t.Errorf("stored token expires in %v, want an hour", d)
+	}
+	if stored.Created.After(time.Now()) {
+		t.Errorf("stored token created at %v, in the future", stored.Created)
+	}
+
+	// A replica uses the stored token.
+	replica := newClient(t, srv, store)
+	if tok, err := replica.Token(context.Background()); err != nil || tok != tokens[0] {
+		t.Errorf("replica: got %v, want the stored token", err)
+	}
+	if n := ten.issued(); n != 1 {
+		t.Errorf("tokens issued after replica: got %d, want 1", n)
+	}
+
+	// Tokens about to expire are replaced.
+	c.now = func() time.Time { return time.Now().Add(59*time.Minute + 30*time.Second) }
+	tok, err := c.Token(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok == tokens[0] || ten.issued() != 2 {
+		t.Errorf("expiring token: got %d tokens issued, want a new one", ten.issued())
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures []int
+		status   int
+		requests int
+	}{
+		{"recovers", []int{http.StatusServiceUnavailable, http.StatusTooManyRequests}, 0, 3},
+		{"server error", []int{500, 502, 503, 504}, http.StatusServiceUnavailable, 3},
+		{"client error", []int{http.StatusUnauthorized}, http.StatusUnauthorized, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ten := &tenant{failures: tt.failures}
+			srv := httptest.NewServer(ten)
+			defer srv.Close()
+
+			c := newClient(t, srv, ma_token.NewMemory())
+			_, err := c.Token(context.Background())
+
+			if ten.requests != tt.requests {
+				t.Errorf("requests: got %d, want %d", ten.requests, tt.requests)
+			}
+			if tt.status == 0 {
+				if err != nil || ten.issued() != 1 {
+					t.Fatalf("got %v and %d tokens, want one token", err, ten.issued())
+				}
+				return
+			}
+
+			e, ok := errors.Cause(err).(*Error)
+			if !ok {
+				t.Fatalf("error: got %v, want an *Error", err)
+			}
+			if e.StatusCode != tt.status || e.Message != "failing on purpose" {
+				t.Errorf("error: got %+v, want status %d", e, tt.status)
+			}
+			if e.Temporary() != (tt.status >= 500) {
+				t.Errorf("temporary: got %v", e.Temporary())
+			}
+		})
+	}
+}
+
+func TestClientTimeout(t *testing.T) {
+	ten := &tenant{delay: 100 * time.Millisecond}
+	srv := httptest.NewServer(ten)
+	defer srv.Close()
+
+	c := newClient(t, srv, ma_token.NewMemory())
+	c.HTTP.Timeout = 10 * time.Millisecond
+
+	_, err := c.Token(context.Background())
+	if _, ok := errors.Cause(err).(net.Error); !ok {
+		t.Fatalf("error: got %v, want a timeout", err)
+	}
+}
+
+func TestTemporary(t *testing.T) {
+	done, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	reset := &url.Error{Op: "Post", URL: "https://tenant.test", Err: &net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ECONNRESET)}}
+	refused := &url.Error{Op: "Post", URL: "https://tenant.test", Err: &net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}}
+	timeout := &url.Error{Op: "Post", URL: "https://tenant.test", Err: context.DeadlineExceeded}
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want bool
+	}{
+		{"server error", context.Background(), &Error{StatusCode: http.StatusBadGateway}, true},
+		{"client error", context.Background(), &Error{StatusCode: http.StatusBadRequest}, false},
+		{"timeout", context.Background(), timeout, true},
+		{"connection reset", context.Background(), reset, true},
+		{"connection refused", context.Background(), refused, false},
+		{"done context", done, reset, false},
+	}
+
+	for _, tt := range tests {
+		if got := temporary(tt.ctx, tt.err); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestUpdateAppMetadata(t *testing.T) {
+	ten := &tenant{}
+	srv := httptest.NewServer(ten)
+	defer srv.Close()
+
+	c := newClient(t, srv, ma_token.NewMemory())
+
+	id := `5cf37266-"quoted"`
+	if err := c.UpdateAppMetadata(context.Background(), "auth0|jane", map[string]interface{}{"id": id}); err != nil {
+		t.Fatal(err)
+	}
+
+	token, _ := c.Token(context.Background())
+
+	if len(ten.updates) != 1 {
+		t.Fatalf("updates: got %d, want 1", len(ten.updates))
+	}
+	u := ten.updates[0]
+	if u.path != "/api/v2/users/auth0%7Cjane" {
+		t.Errorf("path: got %s", u.path)
+	}
+	if u.auth != "Bearer "+token {
+		t.Errorf("authorization: got %q", u.auth)
+	}
+	if md, _ := u.body["app_metadata"].(map[string]interface{}); md["id"] != id {
+		t.Errorf("body: got %v", u.body)
+	}
+
+	ten.failures = []int{http.StatusNotFound}
+	err := c.UpdateAppMetadata(context.Background(), "auth0|john", map[string]interface{}{"id": id})
+	if e, ok := errors.Cause(err).(*Error); !ok || e.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown user: got %v, want a 404 *Error", err)
+	}
+}
@@ -3,9 +3,11 @@ package column
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	sq "github.com/Masterminds/squirrel"
 	"github.com/google/uuid"
 	"github.com/ivorscott/devpie-client-backend-go/internal/platform/database"
+	"github.com/ivorscott/devpie-client-backend-go/internal/task"
 	"github.com/lib/pq"
 	"github.com/pkg/errors"
 	"time"
@@ -14,10 +16,19 @@ import (
 // The Column package shouldn't know anything about http
 // While it may identify common know errors, how to respond is left to the handlers
 var (
-	ErrNotFound  = errors.New("column not found")
-	ErrInvalidID = errors.New("id provided was not a valid UUID")
+	ErrNotFound      = errors.New("column not found")
+	ErrInvalidID     = errors.New("id provided was not a valid UUID")
+	ErrNotEmpty      = errors.New("column has tasks, choose whether to move or delete them")
+	ErrInvalidPolicy = errors.New("tasks policy must be move or delete")
+	ErrInvalidTarget = errors.New("tasks can only be moved to another column of the same project")
+	ErrConflict      = errors.New("column changed since it was last read")
+	ErrLastColumn    = errors.New("the only column of a project can't be deleted")
 )
 
+// taskIDs selects the ids of the tasks on the board in a column, in rank
+// order. Archived and deleted tasks are left out.
+const taskIDs = "ARRAY(SELECT t.task_id::text FROM tasks t WHERE t.column_id = columns.column_id AND t.archived IS NULL AND t.deleted IS NULL ORDER BY t.rank, t.created) AS task_ids"
+
 func Retrieve(ctx context.Context, repo *database.Repository, pid, cid string) (*Column, error) {
 	var c Column
 
@@ -33,7 +44,8 @@ func Retrieve(ctx context.Context, repo *database.Repository, pid, cid string) (
 		"project_id",
 		"title",
 		"column_name",
-		"task_ids",
+		taskIDs,
+		"version",
 		"created",
 	).From(
 		"columns",
@@ -44,7 +56,7 @@ func Retrieve(ctx context.Context, repo *database.Repository, pid, cid string) (
 		return nil, errors.Wrapf(err, "building query: %v", args)
 	}
 
-	err = repo.DB.QueryRowContext(ctx, q, cid, pid).Scan(&c.ID, &c.ProjectID, &c.Title, &c.ColumnName, (*pq.StringArray)(&c.TaskIDS), &c.Created)
+	err = repo.QueryRowContext(ctx, q, cid, pid).Scan(&c.ID, &c.ProjectID, &c.Title, &c.ColumnName, (*pq.StringArray)(&c.TaskIDS), &c.Version, &c.Created)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, ErrNotFound
@@ -55,29 +67,35 @@ func Retrieve(ctx context.Context, repo *database.Repository, pid, cid string) (
 	return &c, nil
 }
 
+// List returns the columns of a project in the project's column order.
 func List(ctx context.Context, repo *database.Repository, pid string) ([]Column, error) {
 	var c Column
 	var cs = make([]Column, 0)
 
 	stmt := repo.SQ.Select(
-		"column_id",
-		"project_id",
-		"title",
-		"column_name",
-		"task_ids",
-		"created",
-	).From("columns").Where(sq.Eq{"project_id": "?"})
+		"columns.column_id",
+		"columns.project_id",
+		"columns.title",
+		"columns.column_name",
+		taskIDs,
+		"columns.version",
+		"columns.created",
+	).From(
+		"columns",
+	).Join(
+		"projects p ON p.project_id = columns.project_id",
+	).Where(sq.Eq{"columns.project_id": "?"}).OrderBy("array_position(p.column_order, columns.column_name::text)", "columns.column_name")
 	q, args, err := stmt.ToSql()
 	if err != nil {
 		return nil, errors.Wrapf(err, "building query: %v", args)
 	}
 
-	rows, err := repo.DB.QueryContext(ctx, q, pid)
+	rows, err := repo.QueryContext(ctx, q, pid)
 	if err != nil {
 		return nil, errors.Wrap(err, "selecting columns")
 	}
 	for rows.Next() {
-		err = rows.Scan(&c.ID, &c.ProjectID, &c.Title, &c.ColumnName, (*pq.StringArray)(&c.TaskIDS), &c.Created)
+		err = rows.Scan(&c.ID, &c.ProjectID, &c.Title, &c.ColumnName, (*pq.StringArray)(&c.TaskIDS), &c.Version, &c.Created)
 		if err != nil {
 			return nil, errors.Wrap(err, "scanning row into Struct")
 		}
@@ -87,14 +105,30 @@ func List(ctx context.Context, repo *database.Repository, pid string) ([]Column,
 	return cs, nil
 }
 
-// Create adds a new Column
+// Create adds a new Column to the end of the project's column order.
 func Create(ctx context.Context, repo *database.Repository, nc NewColumn, now time.Time) (*Column, error) {
+	if _, err := uuid.Parse(nc.ProjectID); err != nil {
+		return nil, ErrInvalidID
+	}
+
+	ctx, err := repo.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer repo.Rollback(ctx)
+
+	names, err := lockProject(ctx, repo, nc.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+
 	c := Column{
 		ID:         uuid.New().String(),
 		Title:      nc.Title,
-		ColumnName: nc.ColumnName,
+		ColumnName: nextName(names),
 		TaskIDS:    make([]string, 0),
 		ProjectID:  nc.ProjectID,
+		Version:    1,
 		Created:    now.UTC(),
 	}
 
@@ -104,7 +138,6 @@ func Create(ctx context.Context, repo *database.Repository, nc NewColumn, now ti
 		"column_id":   c.ID,
 		"title":       c.Title,
 		"column_name": c.ColumnName,
-		"task_ids":    pq.Array(c.TaskIDS),
 		"project_id":  c.ProjectID,
 		"created":     now.UTC(),
 	})
@@ -113,53 +146,151 @@ func Create(ctx context.Context, repo *database.Repository, nc NewColumn, now ti
 		return nil, errors.Wrapf(err, "inserting column: %v", nc)
 	}
 
+	order := repo.SQ.Update(
+		"projects",
+	).SetMap(map[string]interface{}{
+		"column_order": sq.Expr("array_append(column_order, ?)", c.ColumnName),
+		"version":      sq.Expr("version + 1"),
+	}).Where(sq.Eq{"project_id": c.ProjectID})
+
+	if _, err := order.ExecContext(ctx); err != nil {
+		return nil, errors.Wrap(err, "appending column to project column order")
+	}
+
+	if err := repo.Commit(ctx); err != nil {
+		return nil, errors.Wrap(err, "committing column")
+	}
+
 	return &c, nil
 }
 
 // Update modifies data about a Column. It will error if the specified ID is
-// invalid or does not reference an existing Column.
-func Update(ctx context.Context, repo *database.Repository, pid, cid string, uc UpdateColumn) error {
-	c, err := Retrieve(ctx, repo, pid, cid)
+// invalid or does not reference an existing Column. Unless the stored version
+// matches versions ErrConflict is returned. It returns the column as it was
+// before the update.
+func Update(ctx context.Context, repo *database.Repository, pid, cid string, uc UpdateColumn, versions database.Versions) (*Column, error) {
+	if _, err := Retrieve(ctx, repo, pid, cid); err != nil {
+		return nil, err
+	}
+
+	ctx, err := repo.Begin(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	defer repo.Rollback(ctx)
 
-	if uc.Title != nil {
-		c.Title = *uc.Title
+	before, err := retrieveTx(ctx, repo, pid, cid)
+	if err != nil {
+		return nil, err
+	}
+	if !versions.Match(before.Version) {
+		return nil, ErrConflict
 	}
 
-	c.TaskIDS = uc.TaskIDS
+	title := before.Title
+	if uc.Title != nil {
+		title = *uc.Title
+	}
 
 	stmt := repo.SQ.Update(
 		"columns",
 	).SetMap(map[string]interface{}{
-		"title":    c.Title,
-		"task_ids": pq.Array(c.TaskIDS),
-	}).Where(sq.Eq{"column_id": cid, "project_id": c.ProjectID})
+		"title":   title,
+		"version": sq.Expr("version + 1"),
+	}).Where(sq.Eq{"column_id": cid, "project_id": pid})
 
-	_, err = stmt.ExecContext(ctx)
-	if err != nil {
-		return errors.Wrap(err, "updating column")
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return nil, errors.Wrap(err, "updating column")
 	}
 
-	return nil
+	if err := repo.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return before, nil
 }
 
-// Delete removes the column identified by a given ID.
-func Delete(ctx context.Context, repo *database.Repository, cid string) error {
+// Delete removes the column identified by a given ID and takes it out of the
+// project's column order. Its tasks, including archived and deleted ones, are
+// handled according to dc. The only column of a project can't be deleted, it
+// holds the tasks of the project. Unless the stored version matches versions
+// ErrConflict is returned. It returns the deleted column.
+func Delete(ctx context.Context, repo *database.Repository, pid, cid string, dc DeleteColumn, versions database.Versions, now time.Time) (*Column, error) {
 	if _, err := uuid.Parse(cid); err != nil {
-		return ErrInvalidID
+		return nil, ErrInvalidID
+	}
+	if _, err := uuid.Parse(pid); err != nil {
+		return nil, ErrInvalidID
+	}
+
+	ctx, err := repo.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer repo.Rollback(ctx)
+
+	names, err := lockProject(ctx, repo, pid)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := retrieveTx(ctx, repo, pid, cid)
+	if err != nil {
+		return nil, err
+	}
+	if !versions.Match(c.Version) {
+		return nil, ErrConflict
+	}
+	if len(names) == 1 {
+		return nil, ErrLastColumn
+	}
+
+	tids, err := allTaskIDs(ctx, repo, cid)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tids) > 0 {
+		switch dc.Tasks {
+		case "":
+			return nil, ErrNotEmpty
+		case TasksDelete:
+			if err := trashTasks(ctx, repo, pid, cid, tids, now); err != nil {
+				return nil, err
+			}
+		case TasksMove:
+			if err := moveTasks(ctx, repo, pid, cid, tids, dc.To); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, ErrInvalidPolicy
+		}
 	}
 
 	stmt := repo.SQ.Delete(
 		"columns",
-	).Where(sq.Eq{"column_id": cid})
+	).Where(sq.Eq{"column_id": cid, "project_id": pid})
 
 	if _, err := stmt.ExecContext(ctx); err != nil {
-		return errors.Wrapf(err, "deleting column %s", cid)
+		return nil, errors.Wrapf(err, "deleting column %s", cid)
 	}
 
-	return nil
+	order := repo.SQ.Update(
+		"projects",
+	).SetMap(map[string]interface{}{
+		"column_order": sq.Expr("array_remove(column_order, ?)", c.ColumnName),
+		"version":      sq.Expr("version + 1"),
+	}).Where(sq.Eq{"project_id": pid})
+
+	if _, err := order.ExecContext(ctx); err != nil {
+		return nil, errors.Wrap(err, "removing column from project column order")
+	}
+
+	if err := repo.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return c, nil
 }
 
 // Delete removes all columns identified by pid
@@ -178,3 +309,179 @@ func DeleteAll(ctx context.Context, repo *database.Repository, pid string) error
 
 	return nil
 }
+
+// lockProject locks the project row so column changes of a project are
+// serialized, and returns the names of the project's columns.
+func lockProject(ctx context.Context, repo *database.Repository, pid string) ([]string, error) {
+	var names []string
+
+	stmt := repo.SQ.Select("project_id").From("projects").Where(sq.Eq{"project_id": pid}).Suffix("FOR UPDATE")
+
+	q, args, err := stmt.ToSql()
+	if err != nil {
+		return nil, errors.Wrapf(err, "building query: %v", args)
+	}
+
+	var id string
+	if err := repo.GetContext(ctx, &id, q, args...); err != nil {
+		return nil, errors.Wrapf(err, "locking project %s", pid)
+	}
+
+	stmt = repo.SQ.Select("column_name").From("columns").Where(sq.Eq{"project_id": pid})
+
+	q, args, err = stmt.ToSql()
+	if err != nil {
+		return nil, errors.Wrapf(err, "building query: %v", args)
+	}
+
+	if err := repo.SelectContext(ctx, &names, q, args...); err != nil {
+		return nil, errors.Wrap(err, "selecting column names")
+	}
+
+	return names, nil
+}
+
+// retrieveTx is Retrieve within the unit of work of ctx, locking the column.
+func retrieveTx(ctx context.Context, repo *database.Repository, pid, cid string) (*Column, error) {
+	var c Column
+
+	stmt := repo.SQ.Select(
+		"column_id",
+		"project_id",
+		"title",
+		"column_name",
+		taskIDs,
+		"version",
+		"created",
+	).From(
+		"columns",
+	).Where(sq.Eq{"column_id": cid, "project_id": pid}).Suffix("FOR UPDATE")
+
+	q, args, err := stmt.ToSql()
+	if err != nil {
+		return nil, errors.Wrapf(err, "building query: %v", args)
+	}
+
+	err = repo.QueryRowContext(ctx, q, args...).Scan(&c.ID, &c.ProjectID, &c.Title, &c.ColumnName, (*pq.StringArray)(&c.TaskIDS), &c.Version, &c.Created)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+// allTaskIDs returns the ids of every task in a column in rank order.
+func allTaskIDs(ctx context.Context, repo *database.Repository, cid string) ([]string, error) {
+	var tids []string
+
+	stmt := repo.SQ.Select("task_id").From("tasks").Where(sq.Eq{"column_id": cid}).OrderBy("rank", "created")
+
+	q, args, err := stmt.ToSql()
+	if err != nil {
+		return nil, errors.Wrapf(err, "building query: %v", args)
+	}
+
+	if err := repo.SelectContext(ctx, &tids, q, args...); err != nil {
+		return nil, errors.Wrap(err, "selecting task ids")
+	}
+
+	return tids, nil
+}
+
+// moveTasks appends the tasks tids of column cid, in order, to the column to.
+func moveTasks(ctx context.Context, repo *database.Repository, pid, cid string, tids []string, to string) error {
+	if _, err := uuid.Parse(to); err != nil || to == cid {
+		return ErrInvalidTarget
+	}
+
+	target, err := retrieveTx(ctx, repo, pid, to)
+	if err != nil {
+		if err == ErrNotFound {
+			return ErrInvalidTarget
+		}
+		return err
+	}
+
+	var last string
+	if n := len(target.TaskIDS); n > 0 {
+		stmt := repo.SQ.Select("rank").From("tasks").Where(sq.Eq{"task_id": target.TaskIDS[n-1]})
+
+		q, args, err := stmt.ToSql()
+		if err != nil {
+			return errors.Wrapf(err, "building query: %v", args)
+		}
+
+		if err := repo.GetContext(ctx, &last, q, args...); err != nil {
+			return errors.Wrap(err, "selecting last rank")
+		}
+	}
+
+	for _, tid := range tids {
+		last, _ = task.Between(last, "")
+
+		stmt := repo.SQ.Update(
+			"tasks",
+		).SetMap(map[string]interface{}{
+			"column_id": to,
+			"rank":      last,
+			"version":   sq.Expr("version + 1"),
+		}).Where(sq.Eq{"task_id": tid})
+
+		if _, err := stmt.ExecContext(ctx); err != nil {
+			return errors.Wrapf(err, "moving task %s", tid)
+		}
+	}
+
+	return nil
+}
+
+// trashTasks moves the tasks tids of column cid to the trash. They are
+// appended to the first other column of the project, where they return when
+// restored.
+func trashTasks(ctx context.Context, repo *database.Repository, pid, cid string, tids []string, now time.Time) error {
+	stmt := repo.SQ.Select(
+		"c.column_id",
+	).From("columns c").Join("projects p ON p.project_id = c.project_id").Where(sq.And{
+		sq.Eq{"c.project_id": pid},
+		sq.NotEq{"c.column_id": cid},
+	}).OrderBy("array_position(p.column_order, c.column_name::text)", "c.column_name").Limit(1)
+
+	q, args, err := stmt.ToSql()
+	if err != nil {
+		return errors.Wrapf(err, "building query: %v", args)
+	}
+
+	var to string
+	if err := repo.GetContext(ctx, &to, q, args...); err != nil {
+		return errors.Wrap(err, "selecting column for deleted tasks")
+	}
+
+	if err := moveTasks(ctx, repo, pid, cid, tids, to); err != nil {
+		return err
+	}
+
+	trash := repo.SQ.Update(
+		"tasks",
+	).Set("deleted", now.UTC()).Where(sq.Eq{"task_id": tids, "deleted": nil})
+
+	if _, err := trash.ExecContext(ctx); err != nil {
+		return errors.Wrapf(err, "deleting tasks of column %s", cid)
+	}
+
+	return nil
+}
+
+// nextName returns the first unused column-N name after the existing ones.
+func nextName(names []string) string {
+	max := 0
+	for _, name := range names {
+		var n int
+		if _, err := fmt.Sscanf(name, "column-%d", &n); err == nil && n > max {
+			max = n
+		}
+	}
+	return fmt.Sprintf("column-%d", max+1)
+}
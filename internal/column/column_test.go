@@ -0,0 +1,23 @@
+package column
+
+import "testing"
+
+func TestNextName(t *testing.T) {
+	tests := []struct {
+		names []string
+		want  string
+	}{
+		{nil, "column-1"},
+		{[]string{"column-1", "column-2"}, "column-3"},
+		{[]string{"column-2", "column-10", "column-9"}, "column-11"},
+		// Gaps left by deleted columns aren't reused.
+		{[]string{"column-4"}, "column-5"},
+		{[]string{"backlog", "column-1"}, "column-2"},
+	}
+
+	for _, tt := range tests {
+		if got := nextName(tt.names); got != tt.want {
+			t.Errorf("nextName(%v): got %q, want %q", tt.names, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package column
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ivorscott/devpie-client-backend-go/internal/platform/database"
+	"github.com/ivorscott/devpie-client-backend-go/internal/task"
+)
+
+// Memory is a Store keeping columns in memory. The tasks of its columns live
+// in a task store. It doesn't know about projects, so it can't keep their
+// column order, and archived and deleted tasks don't keep a column from
+// being deleted without a policy.
+type Memory struct {
+	mu      sync.Mutex
+	columns map[string]*Column
+	tasks   task.Store
+}
+
+// NewMemory returns an empty Memory store whose columns hold the tasks of
+// tasks.
+func NewMemory(tasks task.Store) *Memory {
+	return &Memory{columns: make(map[string]*Column), tasks: tasks}
+}
+
+func (s *Memory) Retrieve(ctx context.Context, pid, cid string) (*Column, error) {
+	if _, err := uuid.Parse(cid); err != nil {
+		return nil, ErrInvalidID
+	}
+	if _, err := uuid.Parse(pid); err != nil {
+		return nil, ErrInvalidID
+	}
+
+	s.mu.Lock()
+	c, ok := s.columns[cid]
+	s.mu.Unlock()
+
+	if !ok || c.ProjectID != pid {
+		return nil, ErrNotFound
+	}
+
+	return s.withTasks(ctx, *c)
+}
+
+func (s *Memory) List(ctx context.Context, pid string) ([]Column, error) {
+	s.mu.Lock()
+	var cs = make([]Column, 0)
+	for _, c := range s.columns {
+		if c.ProjectID == pid {
+			cs = append(cs, *c)
+		}
+	}
+	s.mu.Unlock()
+
+	sort.Slice(cs, func(i, j int) bool { return cs[i].ColumnName < cs[j].ColumnName })
+
+	for i := range cs {
+		c, err := s.withTasks(ctx, cs[i])
+		if err != nil {
+			return nil, err
+		}
+		cs[i] = *c
+	}
+
+	return cs, nil
+}
+
+func (s *Memory) Create(ctx context.Context, nc NewColumn, now time.Time) (*Column, error) {
+	if _, err := uuid.Parse(nc.ProjectID); err != nil {
+		return nil, ErrInvalidID
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var names []string
+	for _, c := range s.columns {
+		if c.ProjectID == nc.ProjectID {
+			names = append(names, c.ColumnName)
+		}
+	}
+
+	c := Column{
+		ID:         uuid.New().String(),
+		Title:      nc.Title,
+		ColumnName: nextName(names),
+		TaskIDS:    make([]string, 0),
+		ProjectID:  nc.ProjectID,
+		Version:    1,
+		Created:    now.UTC(),
+	}
+	s.columns[c.ID] = &c
+
+	out := c
+	return &out, nil
+}
+
+func (s *Memory) Update(ctx context.Context, pid, cid string, uc UpdateColumn, versions database.Versions) (*Column, error) {
+	if _, err := s.Retrieve(ctx, pid, cid); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.columns[cid]
+	if !versions.Match(c.Version) {
+		return nil, ErrConflict
+	}
+	before := *c
+
+	if uc.Title != nil {
+		c.Title = *uc.Title
+	}
+	c.Version++
+
+	return &before, nil
+}
+
+func (s *Memory) Delete(ctx context.Context, pid, cid string, dc DeleteColumn, versions database.Versions, now time.Time) (*Column, error) {
+	c, err := s.Retrieve(ctx, pid, cid)
+	if err != nil {
+		return nil, err
+	}
+	if !versions.Match(c.Version) {
+		return nil, ErrConflict
+	}
+
+	cs, err := s.List(ctx, pid)
+	if err != nil {
+		return nil, err
+	}
+	if len(cs) == 1 {
+		return nil, ErrLastColumn
+	}
+
+	if len(c.TaskIDS) > 0 {
+		switch dc.Tasks {
+		case "":
+			return nil, ErrNotEmpty
+		case TasksDelete:
+			// Deleted tasks go to the first other column, where they return
+			// when restored.
+			target := cs[0]
+			if target.ID == cid {
+				target = cs[1]
+			}
+			for _, tid := range c.TaskIDS {
+				// Deleted tasks leave the board, each goes after its tasks.
+				mt := task.MoveTask{From: cid, To: target.ID, Index: len(target.TaskIDS)}
+				if _, err := s.tasks.Move(ctx, pid, tid, mt, nil); err != nil {
+					return nil, err
+				}
+				if _, err := s.tasks.Delete(ctx, pid, tid, nil, now); err != nil {
+					return nil, err
+				}
+			}
+		case TasksMove:
+			if _, err := uuid.Parse(dc.To); err != nil || dc.To == cid {
+				return nil, ErrInvalidTarget
+			}
+			target, err := s.Retrieve(ctx, pid, dc.To)
+			if err != nil {
+				if err == ErrNotFound {
+					return nil, ErrInvalidTarget
+				}
+				return nil, err
+			}
+			for i, tid := range c.TaskIDS {
+				mt := task.MoveTask{From: cid, To: target.ID, Index: len(target.TaskIDS) + i}
+				if _, err := s.tasks.Move(ctx, pid, tid, mt, nil); err != nil {
+					return nil, err
+				}
+			}
+		default:
+			return nil, ErrInvalidPolicy
+		}
+	}
+
+	s.mu.Lock()
+	delete(s.columns, cid)
+	s.mu.Unlock()
+
+	return c, nil
+}
+
+// withTasks fills in the ids of the tasks on the board in c.
+func (s *Memory) withTasks(ctx context.Context, c Column) (*Column, error) {
+	ts, err := s.tasks.List(ctx, c.ProjectID, task.Filter{ColumnID: c.ID}, database.Page{})
+	if err != nil {
+		return nil, err
+	}
+
+	c.TaskIDS = make([]string, len(ts))
+	for i, t := range ts {
+		c.TaskIDS[i] = t.ID
+	}
+
+	return &c, nil
+}
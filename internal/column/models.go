@@ -4,22 +4,46 @@ import (
 	"time"
 )
 
+// Column is a lane of a project board. TaskIDS is read only and lists the
+// column's tasks in rank order.
 type Column struct {
 	ID         string    `db:"column_id" json:"id"`
 	Title      string    `db:"title" json:"title"`
 	ColumnName string    `db:"column_name" json:"columnName"`
 	TaskIDS    []string  `db:"task_ids" json:"taskIds"`
 	ProjectID  string    `db:"project_id" json:"projectId"`
+	Version    int       `db:"version" json:"version"`
 	Created    time.Time `db:"created" json:"created"`
 }
 
+// CurrentVersion returns the version of the column. It changes when the
+// column is renamed, not when its tasks change.
+func (c Column) CurrentVersion() int {
+	return c.Version
+}
+
+// NewColumn adds a column to the end of a project board. The column name
+// used in the project's column order is generated on creation.
 type NewColumn struct {
-	Title      string `json:"title"`
-	ColumnName string `json:"columnName"`
-	ProjectID  string `json:"projectId"`
+	Title     string `json:"title" validate:"required,max=36"`
+	ProjectID string `json:"-"`
 }
 
 type UpdateColumn struct {
-	Title      *string   `json:"title"`
-	TaskIDS    []string `json:"taskIds"`
+	Title *string `json:"title" validate:"omitempty,max=36"`
+}
+
+// Policies for the tasks of a column that is being deleted.
+const (
+	TasksMove   = "move"
+	TasksDelete = "delete"
+)
+
+// DeleteColumn says what happens to the tasks of a deleted column. With
+// TasksMove they are appended to the column To, with TasksDelete they are
+// moved to the trash of the project. Deleting a column that still has tasks
+// requires a policy.
+type DeleteColumn struct {
+	Tasks string
+	To    string
 }
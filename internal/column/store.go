@@ -0,0 +1,48 @@
+package column
+
+import (
+	"context"
+	"time"
+
+	"github.com/ivorscott/devpie-client-backend-go/internal/platform/database"
+)
+
+// Store persists the columns of project boards. Postgres is the
+// implementation used by the API, Memory stands in for it in tests.
+type Store interface {
+	Retrieve(ctx context.Context, pid, cid string) (*Column, error)
+	List(ctx context.Context, pid string) ([]Column, error)
+	Create(ctx context.Context, nc NewColumn, now time.Time) (*Column, error)
+	Update(ctx context.Context, pid, cid string, uc UpdateColumn, versions database.Versions) (*Column, error)
+	Delete(ctx context.Context, pid, cid string, dc DeleteColumn, versions database.Versions, now time.Time) (*Column, error)
+}
+
+// Postgres is the Store backed by the repository's database.
+type Postgres struct {
+	repo *database.Repository
+}
+
+// NewPostgres returns a Store keeping columns in the repository's database.
+func NewPostgres(repo *database.Repository) *Postgres {
+	return &Postgres{repo: repo}
+}
+
+func (s *Postgres) Retrieve(ctx context.Context, pid, cid string) (*Column, error) {
+	return Retrieve(ctx, s.repo, pid, cid)
+}
+
+func (s *Postgres) List(ctx context.Context, pid string) ([]Column, error) {
+	return List(ctx, s.repo, pid)
+}
+
+func (s *Postgres) Create(ctx context.Context, nc NewColumn, now time.Time) (*Column, error) {
+	return Create(ctx, s.repo, nc, now)
+}
+
+func (s *Postgres) Update(ctx context.Context, pid, cid string, uc UpdateColumn, versions database.Versions) (*Column, error) {
+	return Update(ctx, s.repo, pid, cid, uc, versions)
+}
+
+func (s *Postgres) Delete(ctx context.Context, pid, cid string, dc DeleteColumn, versions database.Versions, now time.Time) (*Column, error) {
+	return Delete(ctx, s.repo, pid, cid, dc, versions, now)
+}
@@ -0,0 +1,272 @@
+package comment
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/ivorscott/devpie-client-backend-go/internal/member"
+	"github.com/ivorscott/devpie-client-backend-go/internal/platform/database"
+	"github.com/pkg/errors"
+)
+
+// The Comment package shouldn't know anything about http
+// While it may identify common know errors, how to respond is left to the handlers
+var (
+	ErrNotFound       = errors.New("comment not found")
+	ErrInvalidID      = errors.New("id provided was not a valid UUID")
+	ErrTaskNotFound   = errors.New("task not found")
+	ErrParentNotFound = errors.New("comment replied to was not found")
+	ErrForbidden      = errors.New("only the author can change a comment")
+)
+
+// Retrieve finds the Comment identified by cid on the task tid of project pid.
+func Retrieve(ctx context.Context, repo *database.Repository, pid, tid, cid string) (*Comment, error) {
+	var c Comment
+
+	if _, err := uuid.Parse(cid); err != nil {
+		return nil, ErrInvalidID
+	}
+	if err := checkTask(ctx, repo, pid, tid); err != nil {
+		return nil, err
+	}
+
+	stmt := repo.SQ.Select(
+		"comment_id",
+		"task_id",
+		"parent_id",
+		"user_id",
+		"content",
+		"edited",
+		"deleted",
+		"created",
+	).From(
+		"comments",
+	).Where(sq.Eq{"comment_id": cid, "task_id": tid})
+
+	q, args, err := stmt.ToSql()
+	if err != nil {
+		return nil, errors.Wrapf(err, "building query: %v", args)
+	}
+
+	if err := repo.GetContext(ctx, &c, q, args...); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+// List returns the comments of a task in the order they were written.
+// Clients build the threads from the parent ids.
+func List(ctx context.Context, repo *database.Repository, pid, tid string) ([]Comment, error) {
+	var cs = make([]Comment, 0)
+
+	if err := checkTask(ctx, repo, pid, tid); err != nil {
+		return nil, err
+	}
+
+	stmt := repo.SQ.Select(
+		"comment_id",
+		"task_id",
+		"parent_id",
+		"user_id",
+		"content",
+		"edited",
+		"deleted",
+		"created",
+	).From("comments").Where(sq.Eq{"task_id": tid}).OrderBy("created", "comment_id")
+
+	q, args, err := stmt.ToSql()
+	if err != nil {
+		return nil, errors.Wrapf(err, "building query: %v", args)
+	}
+
+	if err := repo.SelectContext(ctx, &cs, q, args...); err != nil {
+		return nil, errors.Wrap(err, "selecting comments")
+	}
+
+	return cs, nil
+}
+
+// ListByAuthor returns every comment the user uid wrote, in the order they
+// were written.
+func ListByAuthor(ctx context.Context, repo *database.Repository, uid string) ([]Comment, error) {
+	var cs = make([]Comment, 0)
+
+	stmt := repo.SQ.Select(
+		"comment_id",
+		"task_id",
+		"parent_id",
+		"user_id",
+		"content",
+		"edited",
+		"deleted",
+		"created",
+	).From("comments").Where(sq.Eq{"user_id": uid}).OrderBy("created", "comment_id")
+
+	q, args, err := stmt.ToSql()
+	if err != nil {
+		return nil, errors.Wrapf(err, "building query: %v", args)
+	}
+
+	if err := repo.SelectContext(ctx, &cs, q, args...); err != nil {
+		return nil, errors.Wrap(err, "selecting comments")
+	}
+
+	return cs, nil
+}
+
+// Create adds a new Comment by the user uid to a task.
+func Create(ctx context.Context, repo *database.Repository, nc NewComment, pid, tid, uid string, now time.Time) (*Comment, error) {
+	if err := checkTask(ctx, repo, pid, tid); err != nil {
+		return nil, err
+	}
+
+	if nc.ParentID != nil {
+		parent, err := Retrieve(ctx, repo, pid, tid, *nc.ParentID)
+		switch {
+		case err == ErrNotFound:
+			return nil, ErrParentNotFound
+		case err != nil:
+			return nil, err
+		case parent.Deleted != nil:
+			return nil, ErrParentNotFound
+		}
+	}
+
+	c := Comment{
+		ID:       uuid.New().String(),
+		TaskID:   tid,
+		ParentID: nc.ParentID,
+		AuthorID: &uid,
+		Content:  nc.Content,
+		Created:  now.UTC(),
+	}
+
+	stmt := repo.SQ.Insert(
+		"comments",
+	).SetMap(map[string]interface{}{
+		"comment_id": c.ID,
+		"task_id":    c.TaskID,
+		"parent_id":  c.ParentID,
+		"user_id":    c.AuthorID,
+		"content":    c.Content,
+		"created":    c.Created,
+	})
+
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return nil, errors.Wrapf(err, "inserting comment: %v", nc)
+	}
+
+	return &c, nil
+}
+
+// Update replaces the content of a Comment. Only its author can edit it.
+func Update(ctx context.Context, repo *database.Repository, pid, tid, cid string, m *member.Member, uc UpdateComment, now time.Time) error {
+	c, err := Retrieve(ctx, repo, pid, tid, cid)
+	if err != nil {
+		return err
+	}
+	if c.Deleted != nil {
+		return ErrNotFound
+	}
+	if !c.canEdit(m) {
+		return ErrForbidden
+	}
+
+	stmt := repo.SQ.Update(
+		"comments",
+	).SetMap(map[string]interface{}{
+		"content": uc.Content,
+		"edited":  now.UTC(),
+	}).Where(sq.Eq{"comment_id": cid})
+
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return errors.Wrap(err, "updating comment")
+	}
+
+	return nil
+}
+
+// Delete soft deletes a Comment, clearing its content so replies keep their
+// place in the thread. The author and project owners can delete a comment.
+func Delete(ctx context.Context, repo *database.Repository, pid, tid, cid string, m *member.Member, now time.Time) error {
+	c, err := Retrieve(ctx, repo, pid, tid, cid)
+	if err != nil {
+		return err
+	}
+	if c.Deleted != nil {
+		return ErrNotFound
+	}
+	if !c.canDelete(m) {
+		return ErrForbidden
+	}
+
+	stmt := repo.SQ.Update(
+		"comments",
+	).SetMap(map[string]interface{}{
+		"content": "",
+		"deleted": now.UTC(),
+	}).Where(sq.Eq{"comment_id": cid})
+
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return errors.Wrapf(err, "deleting comment %s", cid)
+	}
+
+	return nil
+}
+
+// Anonymize removes the user uid as author of its comments. The comments
+// stay in their threads without an author.
+func Anonymize(ctx context.Context, repo *database.Repository, uid string) error {
+	stmt := repo.SQ.Update(
+		"comments",
+	).Set("user_id", nil).Where(sq.Eq{"user_id": uid})
+
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return errors.Wrapf(err, "anonymizing comments of user %s", uid)
+	}
+
+	return nil
+}
+
+// canEdit reports whether the member m may edit the comment, which only its
+// author can. Comments without an author can't be edited.
+func (c Comment) canEdit(m *member.Member) bool {
+	return c.AuthorID != nil && *c.AuthorID == m.UserID
+}
+
+// canDelete reports whether the member m may delete the comment. Project
+// owners can delete any comment.
+func (c Comment) canDelete(m *member.Member) bool {
+	return c.canEdit(m) || m.Role == member.RoleOwner
+}
+
+// checkTask verifies the task tid belongs to the project pid.
+func checkTask(ctx context.Context, repo *database.Repository, pid, tid string) error {
+	if _, err := uuid.Parse(tid); err != nil {
+		return ErrInvalidID
+	}
+
+	stmt := repo.SQ.Select("count(*)").From("tasks").Where(sq.Eq{"task_id": tid, "project_id": pid, "deleted": nil})
+
+	q, args, err := stmt.ToSql()
+	if err != nil {
+		return errors.Wrapf(err, "building query: %v", args)
+	}
+
+	var n int
+	if err := repo.GetContext(ctx, &n, q, args...); err != nil {
+		return errors.Wrap(err, "looking for task")
+	}
+	if n == 0 {
+		return ErrTaskNotFound
+	}
+
+	return nil
+}
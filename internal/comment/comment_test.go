@@ -0,0 +1,40 @@
+package comment
+
+import (
+	"testing"
+
+	"github.com/ivorscott/devpie-client-backend-go/internal/member"
+)
+
+func TestPermissions(t *testing.T) {
+	author := "author"
+	c := Comment{AuthorID: &author}
+
+	tests := []struct {
+		name      string
+		m         *member.Member
+		edit, del bool
+	}{
+		{"author", &member.Member{UserID: "author", Role: member.RoleViewer}, true, true},
+		{"editor", &member.Member{UserID: "editor", Role: member.RoleEditor}, false, false},
+		{"owner", &member.Member{UserID: "owner", Role: member.RoleOwner}, false, true},
+	}
+
+	for _, tt := range tests {
+		if got := c.canEdit(tt.m); got != tt.edit {
+			t.Errorf("%s editing: got %v, want %v", tt.name, got, tt.edit)
+		}
+		if got := c.canDelete(tt.m); got != tt.del {
+			t.Errorf("%s deleting: got %v, want %v", tt.name, got, tt.del)
+		}
+	}
+
+	// The comments of deleted users stay, only owners can delete them.
+	anon := Comment{}
+	if anon.canEdit(&member.Member{UserID: ""}) {
+		t.Error("comment without author is editable")
+	}
+	if !anon.canDelete(&member.Member{Role: member.RoleOwner}) {
+		t.Error("owner can't delete comment without author")
+	}
+}
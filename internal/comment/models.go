@@ -0,0 +1,28 @@
+package comment
+
+import (
+	"time"
+)
+
+// Comment is a markdown message about a task. A reply has the comment it
+// answers as parent. A deleted comment keeps its place in the thread without
+// its content. The comments of deleted users have no author.
+type Comment struct {
+	ID       string     `db:"comment_id" json:"id"`
+	TaskID   string     `db:"task_id" json:"taskId"`
+	ParentID *string    `db:"parent_id" json:"parentId"`
+	AuthorID *string    `db:"user_id" json:"authorId"`
+	Content  string     `db:"content" json:"content"`
+	Edited   *time.Time `db:"edited" json:"edited"`
+	Deleted  *time.Time `db:"deleted" json:"deleted"`
+	Created  time.Time  `db:"created" json:"created"`
+}
+
+type NewComment struct {
+	Content  string  `json:"content" validate:"required,max=10000"`
+	ParentID *string `json:"parentId" validate:"omitempty,uuid"`
+}
+
+type UpdateComment struct {
+	Content string `json:"content" validate:"required,max=10000"`
+}
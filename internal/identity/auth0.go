@@ -0,0 +1,77 @@
+package identity
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/ivorscott/devpie-client-backend-go/internal/auth0"
+	"github.com/ivorscott/devpie-client-backend-go/internal/platform/auth"
+	"github.com/pkg/errors"
+)
+
+// ErrInvalidUserID is returned when syncing a user id that isn't a UUID.
+var ErrInvalidUserID = errors.New("user id is not in its proper UUID format")
+
+// Auth0Config is the tenant and the machine to machine application the API
+// uses with the Auth0 Management API.
+type Auth0Config struct {
+	Domain       string
+	Audience     string
+	M2MClient    string
+	M2MSecret    string
+	MAPIAudience string
+}
+
+// Issuer returns the issuer of the tenant's tokens.
+func (c Auth0Config) Issuer() string {
+	return "https://" + c.Domain + "/"
+}
+
+// JWKS returns the URL of the tenant's JSON web key set.
+func (c Auth0Config) JWKS() string {
+	return c.Issuer() + ".well-known/jwks.json"
+}
+
+// Management returns the configuration of the Management API client.
+func (c Auth0Config) Management() auth0.Config {
+	return auth0.Config{
+		Domain:       c.Domain,
+		ClientID:     c.M2MClient,
+		ClientSecret: c.M2MSecret,
+		Audience:     c.MAPIAudience,
+	}
+}
+
+// Auth0 is the Provider of an Auth0 tenant. User ids are synced to the
+// app_metadata of accounts, a rule of the tenant adds them to the tokens.
+type Auth0 struct {
+	*OIDC
+	Config Auth0Config
+
+	client *auth0.Client
+}
+
+// NewAuth0 returns the Provider of the tenant of cfg, whose tokens are
+// signed with a key of keys. User ids are synced with client.
+func NewAuth0(cfg Auth0Config, keys auth.KeySet, client *auth0.Client) *Auth0 {
+	return &Auth0{
+		OIDC:   NewOIDC(keys, cfg.Issuer(), cfg.Audience, DefaultClaims),
+		Config: cfg,
+		client: client,
+	}
+}
+
+// SyncUser adds the user id to the app_metadata of the subject's account.
+func (p *Auth0) SyncUser(ctx context.Context, subject, userID string) error {
+	if _, err := uuid.Parse(userID); err != nil {
+		return ErrInvalidUserID
+	}
+
+	return p.client.UpdateAppMetadata(ctx, subject, map[string]interface{}{"id": userID})
+}
+
+// UnlinkUser removes the user id from the app_metadata of the subject's
+// account. Auth0 removes the properties set to null.
+func (p *Auth0) UnlinkUser(ctx context.Context, subject string) error {
+	return p.client.UpdateAppMetadata(ctx, subject, map[string]interface{}{"id": nil})
+}
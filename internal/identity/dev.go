@@ -0,0 +1,21 @@
+package identity
+
+import (
+	"github.com/ivorscott/devpie-client-backend-go/internal/platform/auth"
+)
+
+// Dev is the Provider of the development auth mode, where the API issues
+// tokens itself. There are no accounts to sync user ids to, tokens are
+// issued with the user id claim instead.
+type Dev struct {
+	*OIDC
+	Signer *auth.Issuer
+}
+
+// NewDev returns the Provider of the tokens of issuer.
+func NewDev(issuer *auth.Issuer) *Dev {
+	return &Dev{
+		OIDC:   NewOIDC(issuer.Keys(), issuer.Name, issuer.Audience, DefaultClaims),
+		Signer: issuer,
+	}
+}
@@ -0,0 +1,68 @@
+// Package identity connects the API to the identity provider its users sign
+// in with. A Provider verifies access tokens, maps their claims to the names
+// the API reads and records the user ids of the API in the provider's
+// accounts.
+package identity
+
+import (
+	"context"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// ClaimUserID is the claim of access tokens holding the id of the user.
+const ClaimUserID = "https://client.devpie.io/claims/user_id"
+
+// Provider is an identity provider.
+type Provider interface {
+	// Verify checks an access token. The claims of the token are given the
+	// names of DefaultClaims.
+	Verify(ctx context.Context, token string) (*jwt.Token, error)
+
+	// SyncUser records userID in the account of the subject, for the
+	// provider to add to the subject's later tokens. Providers unable to
+	// do so return nil, the user is then found by subject.
+	SyncUser(ctx context.Context, subject, userID string) error
+
+	// UnlinkUser removes the user id recorded in the account of the
+	// subject, when the user is deleted.
+	UnlinkUser(ctx context.Context, subject string) error
+}
+
+// Claims names the claims of a provider's tokens that the API reads.
+type Claims struct {
+	Subject     string
+	UserID      string
+	Scope       string
+	Permissions string
+}
+
+// DefaultClaims are the names of the claims the API reads.
+var DefaultClaims = Claims{
+	Subject:     "sub",
+	UserID:      ClaimUserID,
+	Scope:       "scope",
+	Permissions: "permissions",
+}
+
+// apply copies the claims named by c to the names of DefaultClaims. Names
+// left empty are the default ones.
+func (c Claims) apply(claims jwt.MapClaims) {
+	names := []struct{ from, to string }{
+		{c.Subject, DefaultClaims.Subject},
+		{c.UserID, DefaultClaims.UserID},
+		{c.Scope, DefaultClaims.Scope},
+		{c.Permissions, DefaultClaims.Permissions},
+	}
+
+	for _, n := range names {
+		if n.from == "" || n.from == n.to {
+			continue
+		}
+		if v, ok := claims[n.from]; ok {
+			claims[n.to] = v
+		} else {
+			delete(claims, n.to)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package identity
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/ivorscott/devpie-client-backend-go/internal/platform/auth"
+	"github.com/pkg/errors"
+)
+
+// OIDC is a Provider of any OpenID Connect issuer. It can't write to the
+// accounts of the issuer, so users are found by the subject of their tokens
+// unless the issuer is set up to add the user id claim.
+type OIDC struct {
+	Issuer   string
+	Audience string
+	Claims   Claims
+
+	verifier auth.TokenVerifier
+}
+
+// NewOIDC returns the Provider of tokens for audience by issuer, signed with
+// a key of keys and holding the claims named by claims.
+func NewOIDC(keys auth.KeySet, issuer, audience string, claims Claims) *OIDC {
+	return &OIDC{
+		Issuer:   issuer,
+		Audience: audience,
+		Claims:   claims,
+		verifier: auth.NewVerifier(keys, audience, issuer),
+	}
+}
+
+func (p *OIDC) Verify(ctx context.Context, token string) (*jwt.Token, error) {
+	t, err := p.verifier.Verify(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	p.Claims.apply(t.Claims.(jwt.MapClaims))
+
+	return t, nil
+}
+
+func (p *OIDC) SyncUser(ctx context.Context, subject, userID string) error {
+	return nil
+}
+
+func (p *OIDC) UnlinkUser(ctx context.Context, subject string) error {
+	return nil
+}
+
+// Discovery is the part of an OpenID Connect discovery document the API
+// uses.
+type Discovery struct {
+	Issuer  string `json:"issuer"`
+	JWKSURI string `json:"jwks_uri"`
+}
+
+// Discover reads the discovery document of issuer, published at
+// /.well-known/openid-configuration.
+func Discover(ctx context.Context, client *http.Client, issuer string) (*Discovery, error) {
+	url := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetching discovery document")
+	}
+
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, errors.Wrap(err, "fetching discovery document")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("fetching discovery document: %s", resp.Status)
+	}
+
+	var d Discovery
+	if err := json.NewDecoder(resp.Body).Decode(&d); err != nil {
+		return nil, errors.Wrap(err, "decoding discovery document")
+	}
+
+	// The issuer of the document has to be the one asked for, or tokens of
+	// another issuer would be accepted.
+	if d.Issuer != issuer {
+		return nil, errors.Errorf("discovery document of %s is for issuer %s", issuer, d.Issuer)
+	}
+	if d.JWKSURI == "" {
+		return nil, errors.New("discovery document has no jwks_uri")
+	}
+
+	return &d, nil
+}
+
+// NewDiscoveredOIDC returns the Provider of issuer, found with its discovery
+// document. Its keys are fetched from the key set the document names and
+// kept for ttl.
+func NewDiscoveredOIDC(ctx context.Context, issuer, audience string, claims Claims, ttl time.Duration) (*OIDC, error) {
+	d, err := Discover(ctx, &http.Client{Timeout: 10 * time.Second}, issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewOIDC(auth.NewJWKS(d.JWKSURI, ttl), d.Issuer, audience, claims), nil
+}
@@ -0,0 +1,123 @@
+package identity
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/ivorscott/devpie-client-backend-go/internal/platform/auth"
+)
+
+const testAudience = "https://api.devpie.test"
+
+func TestOIDCClaims(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer := auth.NewIssuer(key, "https://idp.test/", testAudience)
+
+	tests := []struct {
+		name   string
+		claims Claims
+		token  jwt.MapClaims
+		want   jwt.MapClaims
+	}{
+		{
+			"default names",
+			DefaultClaims,
+			jwt.MapClaims{"sub": "idp|jane", ClaimUserID: "7", "scope": "read:tasks"},
+			jwt.MapClaims{"sub": "idp|jane", ClaimUserID: "7", "scope": "read:tasks"},
+		},
+		{
+			"empty names",
+			Claims{},
+			jwt.MapClaims{"sub": "idp|jane", ClaimUserID: "7"},
+			jwt.MapClaims{"sub": "idp|jane", ClaimUserID: "7"},
+		},
+		{
+			"mapped names",
+			Claims{Subject: "email", UserID: "uid", Scope: "scp", Permissions: "roles"},
+			jwt.MapClaims{"sub": "idp|jane", "email": "jane@example.com", "uid": "7", "scp": "read:tasks", "roles": []interface{}{"admin"}},
+			jwt.MapClaims{"sub": "jane@example.com", ClaimUserID: "7", "scope": "read:tasks", "permissions": []interface{}{"admin"}},
+		},
+		{
+			// A claim of the default name doesn't stand in for a mapped one
+			// missing from the token.
+			"missing mapped claims",
+			Claims{UserID: "uid", Scope: "scp"},
+			jwt.MapClaims{"sub": "idp|jane", ClaimUserID: "7", "scope": "admin"},
+			jwt.MapClaims{"sub": "idp|jane", ClaimUserID: nil, "scope": nil},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewOIDC(issuer.Keys(), issuer.Name, issuer.Audience, tt.claims)
+
+			raw, err := issuer.Sign(tt.token, time.Hour)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			token, err := p.Verify(context.Background(), raw)
+			if err != nil {
+				t.Fatalf("verifying: %v", err)
+			}
+
+			got := token.Claims.(jwt.MapClaims)
+			for k, v := range tt.want {
+				b1, _ := json.Marshal(got[k])
+				b2, _ := json.Marshal(v)
+				if string(b1) != string(b2) {
+					t.Errorf("%s: got %s, want %s", k, b1, b2)
+				}
+			}
+		})
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	var doc map[string]string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/.well-known/openid-configuration" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(doc)
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name   string
+		issuer string
+		doc    map[string]string
+		ok     bool
+	}{
+		{"valid", srv.URL + "/", map[string]string{"issuer": srv.URL + "/", "jwks_uri": srv.URL + "/keys"}, true},
+		{"other issuer", srv.URL + "/", map[string]string{"issuer": "https://evil.test/", "jwks_uri": srv.URL + "/keys"}, false},
+		{"no key set", srv.URL + "/", map[string]string{"issuer": srv.URL + "/"}, false},
+		{"not found", srv.URL + "/tenant/", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc = tt.doc
+
+			d, err := Discover(context.Background(), srv.Client(), tt.issuer)
+			if tt.ok != (err == nil) {
+				t.Fatalf("error: got %v, want ok %v", err, tt.ok)
+			}
+			if tt.ok && (d.Issuer != tt.doc["issuer"] || d.JWKSURI != tt.doc["jwks_uri"]) {
+				t.Errorf("document: got %+v, want %v", d, tt.doc)
+			}
+		})
+	}
+}
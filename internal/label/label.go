@@ -0,0 +1,183 @@
+package label
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/ivorscott/devpie-client-backend-go/internal/platform/database"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+// The Label package shouldn't know anything about http
+// While it may identify common know errors, how to respond is left to the handlers
+var (
+	ErrNotFound  = errors.New("label not found")
+	ErrInvalidID = errors.New("id provided was not a valid UUID")
+	ErrDuplicate = errors.New("a label with this name already exists in the project")
+)
+
+// Retrieve finds the Label identified by lid within the project pid.
+func Retrieve(ctx context.Context, repo *database.Repository, pid, lid string) (*Label, error) {
+	var l Label
+
+	if _, err := uuid.Parse(lid); err != nil {
+		return nil, ErrInvalidID
+	}
+
+	stmt := repo.SQ.Select(
+		"label_id",
+		"project_id",
+		"name",
+		"color",
+		"created",
+	).From(
+		"labels",
+	).Where(sq.Eq{"label_id": "?", "project_id": "?"})
+
+	q, args, err := stmt.ToSql()
+	if err != nil {
+		return nil, errors.Wrapf(err, "building query: %v", args)
+	}
+
+	if err := repo.GetContext(ctx, &l, q, lid, pid); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &l, nil
+}
+
+// List returns the labels of a project.
+func List(ctx context.Context, repo *database.Repository, pid string) ([]Label, error) {
+	var ls = make([]Label, 0)
+
+	stmt := repo.SQ.Select(
+		"label_id",
+		"project_id",
+		"name",
+		"color",
+		"created",
+	).From("labels").Where(sq.Eq{"project_id": "?"}).OrderBy("name")
+
+	q, args, err := stmt.ToSql()
+	if err != nil {
+		return nil, errors.Wrapf(err, "building query: %v", args)
+	}
+
+	if err := repo.SelectContext(ctx, &ls, q, pid); err != nil {
+		return nil, errors.Wrap(err, "selecting labels")
+	}
+
+	return ls, nil
+}
+
+// Create adds a new Label to a project.
+func Create(ctx context.Context, repo *database.Repository, nl NewLabel, pid string, now time.Time) (*Label, error) {
+	l := Label{
+		ID:        uuid.New().String(),
+		ProjectID: pid,
+		Name:      nl.Name,
+		Color:     nl.Color,
+		Created:   now.UTC(),
+	}
+
+	stmt := repo.SQ.Insert(
+		"labels",
+	).SetMap(map[string]interface{}{
+		"label_id":   l.ID,
+		"project_id": l.ProjectID,
+		"name":       l.Name,
+		"color":      l.Color,
+		"created":    l.Created,
+	})
+
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		if isUniqueViolation(err) {
+			return nil, ErrDuplicate
+		}
+		return nil, errors.Wrapf(err, "inserting label: %v", nl)
+	}
+
+	return &l, nil
+}
+
+// Update modifies the name or color of a Label.
+func Update(ctx context.Context, repo *database.Repository, pid, lid string, ul UpdateLabel) error {
+	l, err := Retrieve(ctx, repo, pid, lid)
+	if err != nil {
+		return err
+	}
+
+	if ul.Name != nil {
+		l.Name = *ul.Name
+	}
+	if ul.Color != nil {
+		l.Color = *ul.Color
+	}
+
+	stmt := repo.SQ.Update(
+		"labels",
+	).SetMap(map[string]interface{}{
+		"name":  l.Name,
+		"color": l.Color,
+	}).Where(sq.Eq{"label_id": lid, "project_id": pid})
+
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		if isUniqueViolation(err) {
+			return ErrDuplicate
+		}
+		return errors.Wrap(err, "updating label")
+	}
+
+	return nil
+}
+
+// Delete removes a Label, untagging every task that used it.
+func Delete(ctx context.Context, repo *database.Repository, pid, lid string) error {
+	if _, err := uuid.Parse(lid); err != nil {
+		return ErrInvalidID
+	}
+
+	stmt := repo.SQ.Delete(
+		"labels",
+	).Where(sq.Eq{"label_id": lid, "project_id": pid})
+
+	res, err := stmt.ExecContext(ctx)
+	if err != nil {
+		return errors.Wrapf(err, "deleting label %s", lid)
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// DeleteAll removes all labels identified by pid
+func DeleteAll(ctx context.Context, repo *database.Repository, pid string) error {
+	if _, err := uuid.Parse(pid); err != nil {
+		return ErrInvalidID
+	}
+
+	stmt := repo.SQ.Delete(
+		"labels",
+	).Where(sq.Eq{"project_id": pid})
+
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return errors.Wrapf(err, "deleting all labels")
+	}
+
+	return nil
+}
+
+func isUniqueViolation(err error) bool {
+	pqErr, ok := err.(*pq.Error)
+	return ok && pqErr.Code == "23505"
+}
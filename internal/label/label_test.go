@@ -0,0 +1,43 @@
+package label
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestInvalidID(t *testing.T) {
+	ctx := context.Background()
+	pid := "5cf37266-3473-4006-984f-9325122678b7"
+
+	// Invalid ids are rejected before the database is queried.
+	if _, err := Retrieve(ctx, nil, pid, "not-a-uuid"); err != ErrInvalidID {
+		t.Errorf("Retrieve: got %v, want %v", err, ErrInvalidID)
+	}
+	if err := Delete(ctx, nil, pid, "not-a-uuid"); err != ErrInvalidID {
+		t.Errorf("Delete: got %v, want %v", err, ErrInvalidID)
+	}
+	if err := DeleteAll(ctx, nil, "not-a-uuid"); err != ErrInvalidID {
+		t.Errorf("DeleteAll: got %v, want %v", err, ErrInvalidID)
+	}
+}
+
+func TestIsUniqueViolation(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&pq.Error{Code: "23505"}, true},
+		{&pq.Error{Code: "23503"}, false},
+		{errors.New("23505"), false},
+		{nil, false},
+	}
+
+	for _, tt := range tests {
+		if got := isUniqueViolation(tt.err); got != tt.want {
+			t.Errorf("isUniqueViolation(%v): got %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package label
+
+import (
+	"time"
+)
+
+// Label tags tasks of a single project.
+type Label struct {
+	ID        string    `db:"label_id" json:"id"`
+	ProjectID string    `db:"project_id" json:"projectId"`
+	Name      string    `db:"name" json:"name"`
+	Color     string    `db:"color" json:"color"`
+	Created   time.Time `db:"created" json:"created"`
+}
+
+type NewLabel struct {
+	Name  string `json:"name" validate:"required,max=36"`
+	Color string `json:"color" validate:"required,hexcolor,max=7"`
+}
+
+type UpdateLabel struct {
+	Name  *string `json:"name" validate:"omitempty,max=36"`
+	Color *string `json:"color" validate:"omitempty,hexcolor,max=7"`
+}
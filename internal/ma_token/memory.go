@@ -0,0 +1,57 @@
+package ma_token
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Memory is a Store keeping the token in memory.
+type Memory struct {
+	mu    sync.Mutex
+	token *Token
+}
+
+// NewMemory returns a Memory store without a token.
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+func (s *Memory) Retrieve(ctx context.Context) (*Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token == nil {
+		return nil, ErrNotFound
+	}
+
+	t := *s.token
+	return &t, nil
+}
+
+func (s *Memory) Refresh(ctx context.Context, now, until time.Time, fetch func(ctx context.Context) (*Token, error)) (*Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != nil && s.token.Valid(until) {
+		t := *s.token
+		return &t, nil
+	}
+
+	nt, err := fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.token = &Token{
+		ID:          uuid.New().String(),
+		AccessToken: nt.AccessToken,
+		Expires:     nt.Expires.UTC(),
+		Created:     now.UTC(),
+	}
+
+	t := *s.token
+	return &t, nil
+}
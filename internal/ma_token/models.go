@@ -5,18 +5,17 @@
 package ma_token
 
 import (
-	"github.com/dgrijalva/jwt-go"
 	"time"
 )
 
 type Token struct {
 	ID          string    `db:"ma_token_id" json:"id"`
 	AccessToken string    `db:"token" json:"access_token"`
+	Expires     time.Time `db:"expires" json:"expires"`
 	Created     time.Time `db:"created" json:"created"`
 }
 
-type CustomClaims struct {
-	Scope string `json:"scope"`
-	jwt.StandardClaims
+// Valid reports whether the token is unexpired at now.
+func (t *Token) Valid(now time.Time) bool {
+	return now.Before(t.Expires)
 }
-
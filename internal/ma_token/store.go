@@ -0,0 +1,33 @@
+package ma_token
+
+import (
+	"context"
+	"time"
+
+	"github.com/ivorscott/devpie-client-backend-go/internal/platform/database"
+)
+
+// Store persists the management api token. Postgres is the implementation
+// used by the API, Memory stands in for it in tests.
+type Store interface {
+	Retrieve(ctx context.Context) (*Token, error)
+	Refresh(ctx context.Context, now, until time.Time, fetch func(ctx context.Context) (*Token, error)) (*Token, error)
+}
+
+// Postgres is the Store backed by the repository's database.
+type Postgres struct {
+	repo *database.Repository
+}
+
+// NewPostgres returns a Store keeping the token in the repository's database.
+func NewPostgres(repo *database.Repository) *Postgres {
+	return &Postgres{repo: repo}
+}
+
+func (s *Postgres) Retrieve(ctx context.Context) (*Token, error) {
+	return Retrieve(ctx, s.repo)
+}
+
+func (s *Postgres) Refresh(ctx context.Context, now, until time.Time, fetch func(ctx context.Context) (*Token, error)) (*Token, error) {
+	return Refresh(ctx, s.repo, now, until, fetch)
+}
@@ -3,16 +3,27 @@ package ma_token
 import (
 	"context"
 	"database/sql"
+	"time"
+
 	"github.com/google/uuid"
 	"github.com/ivorscott/devpie-client-backend-go/internal/platform/database"
 	"github.com/pkg/errors"
-	"time"
 )
 
 var (
 	ErrNotFound = errors.New("token not found")
 )
 
+// errLocked is returned by refresh while another replica holds the lock.
+var errLocked = errors.New("token is being refreshed")
+
+// lockID is the key of the advisory lock serializing token refreshes.
+const lockID = 7346218
+
+// pollInterval is how often a replica waiting for another one's refresh looks
+// for the token it stores.
+const pollInterval = 100 * time.Millisecond
+
 // Retrieve auth0 management token
 func Retrieve(ctx context.Context, repo *database.Repository) (*Token, error) {
 	var t Token
@@ -20,9 +31,12 @@ func Retrieve(ctx context.Context, repo *database.Repository) (*Token, error) {
 	stmt := repo.SQ.Select(
 		"ma_token_id",
 		"token",
+		"expires",
 		"created",
 	).From(
 		"ma_token",
+	).OrderBy(
+		"expires DESC",
 	).Limit(1)
 
 	q, args, err := stmt.ToSql()
@@ -30,7 +44,7 @@ func Retrieve(ctx context.Context, repo *database.Repository) (*Token, error) {
 		return nil, errors.Wrapf(err, "building query: %v", args)
 	}
 
-	if err := repo.DB.GetContext(ctx, &t, q); err != nil {
+	if err := repo.GetContext(ctx, &t, q); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, ErrNotFound
 		}
@@ -45,6 +59,7 @@ func Persist(ctx context.Context, repo *database.Repository, nt *Token, now time
 	t := Token{
 		ID:          uuid.New().String(),
 		AccessToken: nt.AccessToken,
+		Expires:     nt.Expires.UTC(),
 		Created:     now.UTC(),
 	}
 
@@ -52,12 +67,13 @@ func Persist(ctx context.Context, repo *database.Repository, nt *Token, now time
 		"ma_token",
 	).SetMap(map[string]interface{}{
 		"ma_token_id": t.ID,
-		"token": t.AccessToken,
-		"created": t.Created,
+		"token":       t.AccessToken,
+		"expires":     t.Expires,
+		"created":     t.Created,
 	})
 
-	if _, err := stmt.ExecContext(ctx); err != nil{
-		return errors.Wrapf(err, "inserting token: %v", nt)
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return errors.Wrap(err, "inserting token")
 	}
 
 	return nil
@@ -73,3 +89,75 @@ func Delete(ctx context.Context, repo *database.Repository) error {
 
 	return nil
 }
+
+// Refresh replaces the token with the one returned by fetch, unless the
+// stored token is still valid at until. The new token is created at now.
+// Replicas refresh one at a time, the others wait for the token it stores
+// without holding a connection.
+func Refresh(ctx context.Context, repo *database.Repository, now, until time.Time, fetch func(ctx context.Context) (*Token, error)) (*Token, error) {
+	for {
+		t, err := refresh(ctx, repo, now, until, fetch)
+		if err != errLocked {
+			return t, err
+		}
+
+		select {
+		case <-time.After(pollInterval):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+		t, err = Retrieve(ctx, repo)
+		switch {
+		case err == nil && t.Valid(until):
+			return t, nil
+		case err != nil && err != ErrNotFound:
+			return nil, err
+		}
+	}
+}
+
+// refresh is a single attempt of Refresh. It returns errLocked when another
+// replica is refreshing the token.
+func refresh(ctx context.Context, repo *database.Repository, now, until time.Time, fetch func(ctx context.Context) (*Token, error)) (*Token, error) {
+	ctx, err := repo.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer repo.Rollback(ctx)
+
+	// The lock is released when the transaction ends.
+	var locked bool
+	if err := repo.GetContext(ctx, &locked, "SELECT pg_try_advisory_xact_lock($1)", lockID); err != nil {
+		return nil, errors.Wrap(err, "locking token")
+	}
+	if !locked {
+		return nil, errLocked
+	}
+
+	t, err := Retrieve(ctx, repo)
+	switch {
+	case err == nil && t.Valid(until):
+		return t, nil
+	case err != nil && err != ErrNotFound:
+		return nil, err
+	}
+
+	t, err = fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := Delete(ctx, repo); err != nil {
+		return nil, err
+	}
+	if err := Persist(ctx, repo, t, now); err != nil {
+		return nil, err
+	}
+
+	if err := repo.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
@@ -0,0 +1,63 @@
+package ma_token
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ivorscott/devpie-client-backend-go/internal/tests"
+)
+
+func TestRefresh(t *testing.T) {
+	repo := tests.NewDatabase(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	var fetched int32
+	fetch := func(ctx context.Context) (*Token, error) {
+		n := atomic.AddInt32(&fetched, 1)
+		time.Sleep(50 * time.Millisecond)
+		return &Token{AccessToken: fmt.Sprintf("token %d", n), Expires: now.Add(time.Hour)}, nil
+	}
+
+	// Replicas refreshing at once fetch a single token.
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := Refresh(ctx, repo, now, now, fetch); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if fetched != 1 {
+		t.Fatalf("fetched: got %d tokens, want 1", fetched)
+	}
+
+	// An expired token is replaced, the new one is created at now.
+	later := now.Add(2 * time.Hour)
+	tok, err := Refresh(ctx, repo, later, later.Add(time.Minute), fetch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok.AccessToken != "token 2" {
+		t.Errorf("token: got %q, want the second one", tok.AccessToken)
+	}
+
+	stored, err := Retrieve(ctx, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.AccessToken != "token 2" || !stored.Expires.Equal(now.Add(time.Hour).UTC().Truncate(time.Microsecond)) {
+		t.Errorf("stored: got %+v", stored)
+	}
+	if !stored.Created.Equal(later.UTC().Truncate(time.Microsecond)) {
+		t.Errorf("created: got %v, want %v", stored.Created, later)
+	}
+}
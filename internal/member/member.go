@@ -0,0 +1,320 @@
+package member
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/ivorscott/devpie-client-backend-go/internal/platform/database"
+	"github.com/pkg/errors"
+)
+
+// The Member package shouldn't know anything about http
+// While it may identify common know errors, how to respond is left to the handlers
+var (
+	ErrNotFound      = errors.New("member not found")
+	ErrInvalidID     = errors.New("id provided was not a valid UUID")
+	ErrAlreadyMember = errors.New("user is already a member of this project")
+	ErrOwnerRemoval  = errors.New("the project owner cannot be removed")
+	ErrForbidden     = errors.New("your project role does not allow this action")
+)
+
+// Retrieve finds the membership of a user in a project, accepted or not.
+func Retrieve(ctx context.Context, repo *database.Repository, pid, uid string) (*Member, error) {
+	var m Member
+
+	if _, err := uuid.Parse(pid); err != nil {
+		return nil, ErrInvalidID
+	}
+	if _, err := uuid.Parse(uid); err != nil {
+		return nil, ErrInvalidID
+	}
+
+	stmt := repo.SQ.Select(
+		"member_id",
+		"project_id",
+		"user_id",
+		"role",
+		"accepted",
+		"invited_by",
+		"created",
+	).From(
+		"project_members",
+	).Where(sq.Eq{"project_id": "?", "user_id": "?"})
+
+	q, args, err := stmt.ToSql()
+	if err != nil {
+		return nil, errors.Wrapf(err, "building query: %v", args)
+	}
+
+	if err := repo.GetContext(ctx, &m, q, pid, uid); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &m, nil
+}
+
+// List returns every member of a project, including pending invites.
+func List(ctx context.Context, repo *database.Repository, pid string) ([]Member, error) {
+	var ms = make([]Member, 0)
+
+	if _, err := uuid.Parse(pid); err != nil {
+		return nil, ErrInvalidID
+	}
+
+	stmt := repo.SQ.Select(
+		"member_id",
+		"project_id",
+		"user_id",
+		"role",
+		"accepted",
+		"invited_by",
+		"created",
+	).From("project_members").Where(sq.Eq{"project_id": "?"}).OrderBy("created")
+
+	q, args, err := stmt.ToSql()
+	if err != nil {
+		return nil, errors.Wrapf(err, "building query: %v", args)
+	}
+
+	if err := repo.SelectContext(ctx, &ms, q, pid); err != nil {
+		return nil, errors.Wrap(err, "selecting members")
+	}
+
+	return ms, nil
+}
+
+// ListByUser returns every membership of a user, including pending invites.
+func ListByUser(ctx context.Context, repo *database.Repository, uid string) ([]Member, error) {
+	var ms = make([]Member, 0)
+
+	stmt := repo.SQ.Select(
+		"member_id",
+		"project_id",
+		"user_id",
+		"role",
+		"accepted",
+		"invited_by",
+		"created",
+	).From("project_members").Where(sq.Eq{"user_id": uid}).OrderBy("created")
+
+	q, args, err := stmt.ToSql()
+	if err != nil {
+		return nil, errors.Wrapf(err, "building query: %v", args)
+	}
+
+	if err := repo.SelectContext(ctx, &ms, q, args...); err != nil {
+		return nil, errors.Wrap(err, "selecting memberships")
+	}
+
+	return ms, nil
+}
+
+// ListInvites returns the pending invitations of a user.
+func ListInvites(ctx context.Context, repo *database.Repository, uid string) ([]Member, error) {
+	var ms = make([]Member, 0)
+
+	stmt := repo.SQ.Select(
+		"member_id",
+		"project_id",
+		"user_id",
+		"role",
+		"accepted",
+		"invited_by",
+		"created",
+	).From("project_members").Where(sq.Eq{"user_id": uid, "accepted": false}).OrderBy("created")
+
+	q, args, err := stmt.ToSql()
+	if err != nil {
+		return nil, errors.Wrapf(err, "building query: %v", args)
+	}
+
+	if err := repo.SelectContext(ctx, &ms, q, args...); err != nil {
+		return nil, errors.Wrap(err, "selecting invites")
+	}
+
+	return ms, nil
+}
+
+// Create adds a user to a project. Owners are added accepted, everyone else
+// starts as a pending invite until they accept it.
+func Create(ctx context.Context, repo *database.Repository, pid, uid string, role Role, invitedBy *string, now time.Time) (*Member, error) {
+	if _, err := Retrieve(ctx, repo, pid, uid); err == nil {
+		return nil, ErrAlreadyMember
+	} else if err != ErrNotFound {
+		return nil, err
+	}
+
+	m := Member{
+		ID:        uuid.New().String(),
+		ProjectID: pid,
+		UserID:    uid,
+		Role:      role,
+		Accepted:  role == RoleOwner,
+		InvitedBy: invitedBy,
+		Created:   now.UTC(),
+	}
+
+	stmt := repo.SQ.Insert(
+		"project_members",
+	).SetMap(map[string]interface{}{
+		"member_id":  m.ID,
+		"project_id": m.ProjectID,
+		"user_id":    m.UserID,
+		"role":       m.Role,
+		"accepted":   m.Accepted,
+		"invited_by": m.InvitedBy,
+		"created":    m.Created,
+	})
+
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return nil, errors.Wrapf(err, "inserting member: %v", m)
+	}
+
+	return &m, nil
+}
+
+// Accept marks a pending invitation as accepted.
+func Accept(ctx context.Context, repo *database.Repository, pid, uid string) error {
+	m, err := Retrieve(ctx, repo, pid, uid)
+	if err != nil {
+		return err
+	}
+
+	stmt := repo.SQ.Update(
+		"project_members",
+	).Set("accepted", true).Where(sq.Eq{"member_id": m.ID})
+
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return errors.Wrap(err, "accepting invite")
+	}
+
+	return nil
+}
+
+// Delete removes a user from a project. The owner can't be removed.
+func Delete(ctx context.Context, repo *database.Repository, pid, uid string) error {
+	m, err := Retrieve(ctx, repo, pid, uid)
+	if err != nil {
+		return err
+	}
+
+	if m.Role == RoleOwner {
+		return ErrOwnerRemoval
+	}
+
+	stmt := repo.SQ.Delete(
+		"project_members",
+	).Where(sq.Eq{"member_id": m.ID})
+
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return errors.Wrapf(err, "deleting member %s", m.ID)
+	}
+
+	return nil
+}
+
+// DeleteAll removes all members identified by pid
+func DeleteAll(ctx context.Context, repo *database.Repository, pid string) error {
+	if _, err := uuid.Parse(pid); err != nil {
+		return ErrInvalidID
+	}
+
+	stmt := repo.SQ.Delete(
+		"project_members",
+	).Where(sq.Eq{"project_id": pid})
+
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return errors.Wrapf(err, "deleting all members")
+	}
+
+	return nil
+}
+
+// DeleteUser removes the user uid from every project, along with its pending
+// invites.
+func DeleteUser(ctx context.Context, repo *database.Repository, uid string) error {
+	stmt := repo.SQ.Delete(
+		"project_members",
+	).Where(sq.Eq{"user_id": uid})
+
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return errors.Wrapf(err, "deleting memberships of user %s", uid)
+	}
+
+	return nil
+}
+
+// Successor returns the member a project of the user uid is best handed to:
+// the accepted member with the highest role, the earliest among equals.
+// ErrNotFound is returned when the project has no other accepted member.
+func Successor(ctx context.Context, repo *database.Repository, pid, uid string) (*Member, error) {
+	var m Member
+
+	stmt := repo.SQ.Select(
+		"member_id",
+		"project_id",
+		"user_id",
+		"role",
+		"accepted",
+		"invited_by",
+		"created",
+	).From(
+		"project_members",
+	).Where(
+		sq.Eq{"project_id": pid, "accepted": true},
+	).Where(
+		sq.NotEq{"user_id": uid},
+	).OrderBy(
+		"array_position(ARRAY['owner','editor','viewer']::varchar[], role)", "created", "member_id",
+	).Limit(1)
+
+	q, args, err := stmt.ToSql()
+	if err != nil {
+		return nil, errors.Wrapf(err, "building query: %v", args)
+	}
+
+	if err := repo.GetContext(ctx, &m, q, args...); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &m, nil
+}
+
+// Transfer makes the accepted member uid the owner of a project. The previous
+// owner stays on as an editor.
+func Transfer(ctx context.Context, repo *database.Repository, pid, uid string) error {
+	m, err := Retrieve(ctx, repo, pid, uid)
+	if err != nil {
+		return err
+	}
+	if !m.Accepted {
+		return ErrNotFound
+	}
+
+	demote := repo.SQ.Update(
+		"project_members",
+	).Set("role", RoleEditor).Where(sq.Eq{"project_id": pid, "role": RoleOwner})
+
+	if _, err := demote.ExecContext(ctx); err != nil {
+		return errors.Wrapf(err, "demoting owner of project %s", pid)
+	}
+
+	promote := repo.SQ.Update(
+		"project_members",
+	).Set("role", RoleOwner).Where(sq.Eq{"member_id": m.ID})
+
+	if _, err := promote.ExecContext(ctx); err != nil {
+		return errors.Wrapf(err, "promoting member %s", m.ID)
+	}
+
+	return nil
+}
@@ -0,0 +1,54 @@
+package member
+
+import (
+	"time"
+)
+
+// Role determines what a member is allowed to do on a shared project.
+type Role string
+
+const (
+	RoleOwner  Role = "owner"
+	RoleEditor Role = "editor"
+	RoleViewer Role = "viewer"
+)
+
+var rank = map[Role]int{
+	RoleViewer: 1,
+	RoleEditor: 2,
+	RoleOwner:  3,
+}
+
+// Allows reports whether the role grants at least the permissions of min.
+func (r Role) Allows(min Role) bool {
+	return rank[r] >= rank[min] && rank[r] > 0
+}
+
+// Access reports whether the membership m, nil for users outside the project,
+// lets its user act on the project with at least the role min. Strangers and
+// pending invitees get ErrNotFound, so project ids aren't leaked, members with
+// a lesser role ErrForbidden.
+func Access(m *Member, min Role) error {
+	if m == nil || !m.Accepted {
+		return ErrNotFound
+	}
+	if !m.Role.Allows(min) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+type Member struct {
+	ID        string    `db:"member_id" json:"id"`
+	ProjectID string    `db:"project_id" json:"projectId"`
+	UserID    string    `db:"user_id" json:"userId"`
+	Role      Role      `db:"role" json:"role"`
+	Accepted  bool      `db:"accepted" json:"accepted"`
+	InvitedBy *string   `db:"invited_by" json:"invitedBy"`
+	Created   time.Time `db:"created" json:"created"`
+}
+
+type NewInvite struct {
+	Email string `json:"email" validate:"required,email"`
+	Role  Role   `json:"role" validate:"required,oneof=editor viewer"`
+}
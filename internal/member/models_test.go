@@ -0,0 +1,40 @@
+package member
+
+import "testing"
+
+func TestAccess(t *testing.T) {
+	members := map[string]*Member{
+		"owner":    {Role: RoleOwner, Accepted: true},
+		"editor":   {Role: RoleEditor, Accepted: true},
+		"viewer":   {Role: RoleViewer, Accepted: true},
+		"pending":  {Role: RoleEditor},
+		"stranger": nil,
+	}
+
+	// The error of each member, by the role required.
+	tests := []struct {
+		min  Role
+		want map[string]error
+	}{
+		{RoleViewer, map[string]error{"owner": nil, "editor": nil, "viewer": nil, "pending": ErrNotFound, "stranger": ErrNotFound}},
+		{RoleEditor, map[string]error{"owner": nil, "editor": nil, "viewer": ErrForbidden, "pending": ErrNotFound, "stranger": ErrNotFound}},
+		{RoleOwner, map[string]error{"owner": nil, "editor": ErrForbidden, "viewer": ErrForbidden, "pending": ErrNotFound, "stranger": ErrNotFound}},
+	}
+
+	for _, tt := range tests {
+		for name, m := range members {
+			if got := Access(m, tt.min); got != tt.want[name] {
+				t.Errorf("%s needing %s: got %v, want %v", name, tt.min, got, tt.want[name])
+			}
+		}
+	}
+}
+
+func TestRoleAllows(t *testing.T) {
+	if Role("admin").Allows(RoleViewer) {
+		t.Error("unknown role allows viewing")
+	}
+	if !RoleOwner.Allows(RoleOwner) {
+		t.Error("owner doesn't allow owner actions")
+	}
+}
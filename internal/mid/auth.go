@@ -1,75 +1,58 @@
 package mid
 
 import (
+	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
-	jwtmiddleware "github.com/auth0/go-jwt-middleware"
-	"github.com/dgrijalva/jwt-go"
-	"github.com/ivorscott/devpie-client-backend-go/internal/platform/web"
 	"net/http"
 	"strings"
-)
+	"time"
 
-type Auth0 struct {
-	Audience     string
-	Domain       string
-	M2MClient    string
-	M2MSecret    string
-	MAPIAudience string
-}
+	"github.com/dgrijalva/jwt-go"
+	"github.com/ivorscott/devpie-client-backend-go/internal/identity"
+	"github.com/ivorscott/devpie-client-backend-go/internal/platform/auth"
+	"github.com/ivorscott/devpie-client-backend-go/internal/platform/web"
+	"github.com/ivorscott/devpie-client-backend-go/internal/user"
+	"github.com/pkg/errors"
+)
 
-type Jwks struct {
-	Keys []JSONWebKeys `json:"keys"`
-}
+// Auth authenticates requests with the access tokens of an identity
+// provider.
+type Auth struct {
+	Provider identity.Provider
 
-type JSONWebKeys struct {
-	Kty string   `json:"kty"`
-	Kid string   `json:"kid"`
-	Use string   `json:"use"`
-	N   string   `json:"n"`
-	E   string   `json:"e"`
-	X5c []string `json:"x5c"`
+	// Users, when set, finds the user of tokens without the user id claim
+	// by their subject.
+	Users user.Store
 }
 
-type CustomClaims struct {
-	Scope string `json:"scope"`
-	jwt.StandardClaims
-}
-
-// Authenticate middleware verifies the access token sent from auth0
-func (a0 *Auth0) Authenticate() web.Middleware {
+// Authenticate middleware verifies the access token sent from the identity
+// provider
+func (a *Auth) Authenticate() web.Middleware {
 	// this is the actual middleware function to be executed.
 	f := func(after web.Handler) web.Handler {
 		// create the handler that will be attached in the middleware chain.
 		h := func(w http.ResponseWriter, r *http.Request) error {
+			raw, err := accessToken(r)
+			if err != nil {
+				return web.NewRequestError(err, http.StatusUnauthorized)
+			}
 
-			jwtMiddleware := jwtmiddleware.New(jwtmiddleware.Options{
-				Debug: false,
-				ValidationKeyGetter: func(token *jwt.Token) (interface{}, error) {
-					checkAud := token.Claims.(jwt.MapClaims).VerifyAudience(a0.Audience, false)
-					if !checkAud {
-						return token, errors.New("invalid audience.")
-					}
-					iss := "https://" + a0.Domain + "/"
-					checkIss := token.Claims.(jwt.MapClaims).VerifyIssuer(iss, false)
-					if !checkIss {
-						return token, errors.New("invalid issuer.")
-					}
-					cert, err := a0.GetPemCert(token)
-					if err != nil {
-						return nil, err
-					}
-					return jwt.ParseRSAPublicKeyFromPEM([]byte(cert))
-				},
-				SigningMethod: jwt.SigningMethodRS256,
-			})
-
-			if err := jwtMiddleware.CheckJWT(w, r); err != nil {
-				return web.NewRequestError(err, http.StatusForbidden)
+			token, err := a.Provider.Verify(r.Context(), raw)
+			if err != nil {
+				if errors.Cause(err) == auth.ErrInvalidToken {
+					return web.NewRequestError(err, http.StatusUnauthorized)
+				}
+				return errors.Wrap(err, "verifying access token")
 			}
 
-			return after(w, r)
+			if err := a.resolveUser(r.Context(), token); err != nil {
+				return err
+			}
+
+			ctx := context.WithValue(r.Context(), "user", token)
+
+			return after(w, r.WithContext(ctx))
 		}
 
 		return h
@@ -78,74 +61,86 @@ func (a0 *Auth0) Authenticate() web.Middleware {
 	return f
 }
 
-// CheckScope middleware verifies the access token has the correct scope before returning a successful response
-func (a0 *Auth0) CheckScope(scope, tokenString string) (bool, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &CustomClaims{}, func(token *jwt.Token) (interface{}, error) {
-		cert, err := a0.GetPemCert(token)
-		if err != nil {
-			return nil, err
+// QueryToken middleware lets a route take its access token from the
+// access_token query parameter, as browsers cannot set headers on an
+// EventSource. It runs before Authenticate, which only reads the header.
+func QueryToken() web.Middleware {
+	f := func(after web.Handler) web.Handler {
+		h := func(w http.ResponseWriter, r *http.Request) error {
+			t := r.URL.Query().Get("access_token")
+			if t != "" && r.Header.Get("Authorization") == "" {
+				r = r.Clone(r.Context())
+				r.Header.Set("Authorization", "Bearer "+t)
+			}
+
+			return after(w, r)
 		}
 
-		// Parse pem encoded pkcs1 or pkcs8 public key
-		return jwt.ParseRSAPublicKeyFromPEM([]byte(cert))
-	})
-	if err != nil {
-		return false, err
+		return h
 	}
 
-	claims, ok := token.Claims.(*CustomClaims)
-	hasScope := false
-
-	if ok && token.Valid {
-		result := strings.Split(claims.Scope, " ")
-		for i := range result {
-			if result[i] == scope {
-				hasScope = true
-			}
-		}
-	}
-	return hasScope, nil
+	return f
 }
 
-// You need to create a function that grabs the json web key set and returns the public key certificate.
-// GetPemCert takes a token and returns the associated certificate in pem format so it can be parsed.
-func (a0 *Auth0) GetPemCert(token *jwt.Token) (string, error) {
-	cert := ""
-	resp, err := http.Get("https://" + a0.Domain + "/.well-known/jwks.json")
-	if err != nil {
-		return cert, err
+// accessToken reads the bearer token of the Authorization header.
+func accessToken(r *http.Request) (string, error) {
+	h := r.Header.Get("Authorization")
+	if h == "" {
+		return "", errors.New("required authorization token not found")
 	}
-	defer resp.Body.Close()
-
-	var jwks = Jwks{}
 
-	err = json.NewDecoder(resp.Body).Decode(&jwks)
-	if err != nil {
-		return cert, err
+	parts := strings.Fields(h)
+	if len(parts) != 2 || !strings.EqualFold(parts[0], "bearer") {
+		return "", errors.New("authorization header format must be Bearer {token}")
 	}
+	return parts[1], nil
+}
 
-	for k := range jwks.Keys {
-		if token.Header["kid"] == jwks.Keys[k].Kid {
-			cert = "-----BEGIN CERTIFICATE-----\n" + jwks.Keys[k].X5c[0] + "\n-----END CERTIFICATE-----"
-		}
+// resolveUser adds the user id claim to a token without one, when the user
+// of its subject exists.
+func (a *Auth) resolveUser(ctx context.Context, token *jwt.Token) error {
+	claims := token.Claims.(jwt.MapClaims)
+	if _, ok := claims[identity.ClaimUserID]; ok || a.Users == nil {
+		return nil
 	}
 
-	if cert == "" {
-		err := errors.New("Unable to find appropriate key.")
-		return cert, err
+	sub, _ := claims["sub"].(string)
+	u, err := a.Users.RetrieveMeBySubject(ctx, sub)
+	switch err {
+	case nil:
+		claims[identity.ClaimUserID] = u.ID
+		return nil
+	case user.ErrNotFound:
+		return nil
+	default:
+		return errors.Wrapf(err, "looking for user of subject %q", sub)
 	}
-	return cert, nil
 }
 
-func (a0 *Auth0) GetUserBySubject(r *http.Request) string {
+func (a *Auth) GetUserBySubject(r *http.Request) string {
 	claims := r.Context().Value("user").(*jwt.Token).Claims.(jwt.MapClaims)
 	return fmt.Sprintf("%v", claims["sub"])
 }
 
-func (a0 *Auth0) GetUserById(r *http.Request) string {
+func (a *Auth) GetUserById(r *http.Request) string {
 	claims := r.Context().Value("user").(*jwt.Token).Claims.(jwt.MapClaims)
-	if _, ok := claims["https://client.devpie.io/claims/user_id"]; !ok {
+	if _, ok := claims[identity.ClaimUserID]; !ok {
 		return ""
 	}
-	return fmt.Sprintf("%v", claims["https://client.devpie.io/claims/user_id"])
+	return fmt.Sprintf("%v", claims[identity.ClaimUserID])
+}
+
+// Expires returns when the access token of the request expires. It reports
+// false for tokens without an expiry.
+func (a *Auth) Expires(r *http.Request) (time.Time, bool) {
+	claims := r.Context().Value("user").(*jwt.Token).Claims.(jwt.MapClaims)
+	switch exp := claims["exp"].(type) {
+	case float64:
+		return time.Unix(int64(exp), 0), true
+	case json.Number:
+		n, err := exp.Int64()
+		return time.Unix(n, 0), err == nil
+	default:
+		return time.Time{}, false
+	}
 }
@@ -0,0 +1,129 @@
+package mid
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/ivorscott/devpie-client-backend-go/internal/identity"
+	"github.com/ivorscott/devpie-client-backend-go/internal/platform/auth"
+	"github.com/ivorscott/devpie-client-backend-go/internal/platform/web"
+	"github.com/ivorscott/devpie-client-backend-go/internal/user"
+)
+
+func TestAuthenticate(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer := auth.NewIssuer(key, "https://idp.test/", "https://api.devpie.test")
+
+	users := user.NewMemory()
+	jane, err := users.Create(context.Background(), user.NewUser{Email: "jane@example.com"}, "idp|jane", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sign := func(claims jwt.MapClaims) string {
+		s, err := issuer.Sign(claims, time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return "Bearer " + s
+	}
+
+	tests := []struct {
+		name   string
+		header string
+		status int
+		uid    string
+	}{
+		{"no token", "", http.StatusUnauthorized, ""},
+		{"malformed header", "Token abc", http.StatusUnauthorized, ""},
+		{"invalid token", "Bearer abc", http.StatusUnauthorized, ""},
+		{"user id claim", sign(jwt.MapClaims{"sub": "idp|jane", identity.ClaimUserID: "7"}), http.StatusOK, "7"},
+		{"user of subject", sign(jwt.MapClaims{"sub": "idp|jane"}), http.StatusOK, jane.ID},
+		{"unknown subject", sign(jwt.MapClaims{"sub": "idp|john"}), http.StatusOK, ""},
+	}
+
+	a := &Auth{Provider: identity.NewDev(issuer), Users: users}
+	logger := log.New(ioutil.Discard, "", 0)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var uid string
+			h := func(w http.ResponseWriter, r *http.Request) error {
+				uid = a.GetUserById(r)
+				return web.Respond(r.Context(), w, nil, http.StatusOK)
+			}
+
+			app := web.NewApp(nil, logger, Errors(logger), a.Authenticate())
+			app.Handle(http.MethodGet, "/", h)
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			app.ServeHTTP(w, r)
+
+			if w.Code != tt.status {
+				t.Fatalf("status: got %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if uid != tt.uid {
+				t.Errorf("user id: got %q, want %q", uid, tt.uid)
+			}
+		})
+	}
+}
+
+func TestQueryToken(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer := auth.NewIssuer(key, "https://idp.test/", "https://api.devpie.test")
+
+	token, err := issuer.Sign(jwt.MapClaims{"sub": "idp|jane", identity.ClaimUserID: "7"}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := &Auth{Provider: identity.NewDev(issuer)}
+	logger := log.New(ioutil.Discard, "", 0)
+
+	h := func(w http.ResponseWriter, r *http.Request) error {
+		return web.Respond(r.Context(), w, nil, http.StatusOK)
+	}
+
+	app := web.NewApp(nil, logger, Errors(logger))
+	app.Handle(http.MethodGet, "/events", h, QueryToken(), a.Authenticate())
+	app.Handle(http.MethodGet, "/projects", h, a.Authenticate())
+
+	tests := []struct {
+		path   string
+		status int
+	}{
+		{"/events?access_token=" + token, http.StatusOK},
+		{"/events", http.StatusUnauthorized},
+		{"/projects?access_token=" + token, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		r.Header.Set("Accept", "text/event-stream")
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, r)
+
+		if w.Code != tt.status {
+			t.Errorf("%s: got %d, want %d: %s", tt.path, w.Code, tt.status, w.Body)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package mid
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/ivorscott/devpie-client-backend-go/internal/member"
+	"github.com/ivorscott/devpie-client-backend-go/internal/platform/database"
+	"github.com/ivorscott/devpie-client-backend-go/internal/platform/web"
+	"github.com/ivorscott/devpie-client-backend-go/internal/project"
+	"github.com/pkg/errors"
+)
+
+// ctxKey represents the type of value for the context key.
+type ctxKey int
+
+// KeyProject is how the loaded project is stored/retrieved.
+const KeyProject ctxKey = 1
+
+// ProjectValues carries the project of the request and the caller's membership.
+type ProjectValues struct {
+	Project *project.Project
+	Member  *member.Member
+}
+
+// Project loads the project identified by the {pid} URL parameter once per
+// request. Callers that aren't accepted members get a 404 so project ids
+// aren't leaked, members without at least the min role get a 403.
+func Project(repo *database.Repository, a *Auth, min member.Role) web.Middleware {
+
+	// This is the actual middleware function to be executed.
+	f := func(after web.Handler) web.Handler {
+
+		// Create the handler that will be attached in the middleware chain.
+		h := func(w http.ResponseWriter, r *http.Request) error {
+			pid := chi.URLParam(r, "pid")
+			uid := a.GetUserById(r)
+
+			m, err := member.Retrieve(r.Context(), repo, pid, uid)
+			if err != nil {
+				switch err {
+				case member.ErrNotFound:
+					// Access turns strangers away.
+				case member.ErrInvalidID:
+					return web.NewRequestError(project.ErrInvalidID, http.StatusBadRequest)
+				default:
+					return errors.Wrapf(err, "looking for member %q of project %q", uid, pid)
+				}
+			}
+
+			switch err := member.Access(m, min); err {
+			case nil:
+			case member.ErrNotFound:
+				return web.NewRequestError(project.ErrNotFound, http.StatusNotFound)
+			default:
+				return web.NewRequestError(err, http.StatusForbidden)
+			}
+
+			p, err := project.Retrieve(r.Context(), repo, pid, uid)
+			if err != nil {
+				switch err {
+				case project.ErrNotFound:
+					return web.NewRequestError(err, http.StatusNotFound)
+				case project.ErrInvalidID:
+					return web.NewRequestError(err, http.StatusBadRequest)
+				default:
+					return errors.Wrapf(err, "looking for project %q", pid)
+				}
+			}
+
+			ctx := context.WithValue(r.Context(), KeyProject, &ProjectValues{Project: p, Member: m})
+
+			return after(w, r.WithContext(ctx))
+		}
+
+		return h
+	}
+
+	return f
+}
+
+// CurrentProject returns the project loaded by the Project middleware.
+func CurrentProject(ctx context.Context) *project.Project {
+	v, ok := ctx.Value(KeyProject).(*ProjectValues)
+	if !ok {
+		return nil
+	}
+	return v.Project
+}
+
+// CurrentMember returns the caller's membership loaded by the Project middleware.
+func CurrentMember(ctx context.Context) *member.Member {
+	v, ok := ctx.Value(KeyProject).(*ProjectValues)
+	if !ok {
+		return nil
+	}
+	return v.Member
+}
@@ -0,0 +1,94 @@
+package mid
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/ivorscott/devpie-client-backend-go/internal/platform/web"
+	"github.com/pkg/errors"
+)
+
+// Scopes an access token can grant. Admin grants every other scope.
+const (
+	ScopeReadProjects  = "read:projects"
+	ScopeWriteProjects = "write:projects"
+	ScopeReadTasks     = "read:tasks"
+	ScopeWriteTasks    = "write:tasks"
+	ScopeReadUsers     = "read:users"
+	ScopeWriteUsers    = "write:users"
+	ScopeAdmin         = "admin"
+)
+
+// Scopes lists every scope but admin.
+var Scopes = []string{
+	ScopeReadProjects, ScopeWriteProjects,
+	ScopeReadTasks, ScopeWriteTasks,
+	ScopeReadUsers, ScopeWriteUsers,
+}
+
+// ErrInsufficientScope is returned for tokens lacking a scope a route requires.
+var ErrInsufficientScope = errors.New("your access token does not grant this action")
+
+// Granted returns the scopes of the request's access token: those of the
+// space separated scope claim and of the permissions claim Auth0 adds for
+// role based access control. Providers name other claims these, see
+// identity.Claims.
+func Granted(r *http.Request) []string {
+	token, ok := r.Context().Value("user").(*jwt.Token)
+	if !ok {
+		return nil
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil
+	}
+
+	var granted []string
+	if s, ok := claims["scope"].(string); ok {
+		granted = append(granted, strings.Fields(s)...)
+	}
+	if ps, ok := claims["permissions"].([]interface{}); ok {
+		for _, p := range ps {
+			if s, ok := p.(string); ok {
+				granted = append(granted, s)
+			}
+		}
+	}
+
+	return granted
+}
+
+// HasScope reports whether the request's access token grants scope.
+func HasScope(r *http.Request, scope string) bool {
+	for _, s := range Granted(r) {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// Require middleware lets a request through when its access token, already
+// verified by Authenticate, grants every one of scopes.
+func Require(scopes ...string) web.Middleware {
+
+	// This is the actual middleware function to be executed.
+	f := func(after web.Handler) web.Handler {
+
+		// Create the handler that will be attached in the middleware chain.
+		h := func(w http.ResponseWriter, r *http.Request) error {
+			for _, s := range scopes {
+				if !HasScope(r, s) {
+					return web.NewRequestError(errors.Wrapf(ErrInsufficientScope, "missing scope %s", s), http.StatusForbidden)
+				}
+			}
+
+			return after(w, r)
+		}
+
+		return h
+	}
+
+	return f
+}
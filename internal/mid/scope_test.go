@@ -0,0 +1,57 @@
+package mid
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/ivorscott/devpie-client-backend-go/internal/platform/web"
+)
+
+func TestRequire(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims jwt.MapClaims
+		scopes []string
+		status int
+	}{
+		{"scope claim", jwt.MapClaims{"scope": "read:tasks write:tasks"}, []string{ScopeWriteTasks}, http.StatusNoContent},
+		{"permissions claim", jwt.MapClaims{"permissions": []interface{}{"write:tasks"}}, []string{ScopeWriteTasks}, http.StatusNoContent},
+		{"every scope", jwt.MapClaims{"scope": "read:tasks"}, []string{ScopeReadTasks, ScopeWriteTasks}, http.StatusForbidden},
+		{"admin", jwt.MapClaims{"permissions": []interface{}{"admin"}}, []string{ScopeWriteProjects}, http.StatusNoContent},
+		{"missing", jwt.MapClaims{"scope": "read:projects"}, []string{ScopeWriteProjects}, http.StatusForbidden},
+		{"no claims", jwt.MapClaims{}, []string{ScopeReadProjects}, http.StatusForbidden},
+		{"nothing required", jwt.MapClaims{}, nil, http.StatusNoContent},
+	}
+
+	ok := func(w http.ResponseWriter, r *http.Request) error {
+		return web.Respond(r.Context(), w, nil, http.StatusNoContent)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := web.NewApp(nil, log.New(ioutil.Discard, "", 0), Errors(log.New(ioutil.Discard, "", 0)))
+			app.Handle(http.MethodGet, "/", Require(tt.scopes...)(ok))
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r = r.WithContext(context.WithValue(r.Context(), "user", &jwt.Token{Claims: tt.claims}))
+			w := httptest.NewRecorder()
+			app.ServeHTTP(w, r)
+
+			if w.Code != tt.status {
+				t.Fatalf("status: got %d, want %d", w.Code, tt.status)
+			}
+			if tt.status == http.StatusForbidden {
+				var er web.ErrorResponse
+				if err := json.Unmarshal(w.Body.Bytes(), &er); err != nil || er.Error == "" {
+					t.Errorf("body: got %s, want an error response", w.Body)
+				}
+			}
+		})
+	}
+}
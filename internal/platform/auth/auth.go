@@ -0,0 +1,96 @@
+// Package auth verifies the signed access tokens clients authenticate with.
+package auth
+
+import (
+	"context"
+	"crypto"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrUnknownKey is returned for a token signed with a key that isn't in
+	// the key set.
+	ErrUnknownKey = errors.New("unknown signing key")
+
+	// ErrInvalidToken is the cause of every token Verify rejects.
+	ErrInvalidToken = errors.New("invalid token")
+)
+
+// Algorithms are the signing methods tokens can use.
+var Algorithms = []string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodES256.Alg()}
+
+// TokenVerifier checks access tokens.
+type TokenVerifier interface {
+	// Verify parses a signed token and checks its signature and claims.
+	Verify(ctx context.Context, token string) (*jwt.Token, error)
+}
+
+// KeySet finds the public key a token was signed with by the key id of its
+// header.
+type KeySet interface {
+	Key(ctx context.Context, kid string) (crypto.PublicKey, error)
+}
+
+// Verifier is a TokenVerifier accepting RS256 and ES256 tokens signed with a
+// key of its key set. Their audience has to include Audience, the issuer has
+// to be Issuer and they have to expire.
+type Verifier struct {
+	Keys     KeySet
+	Audience string
+	Issuer   string
+}
+
+// NewVerifier returns a Verifier of tokens for audience issued by issuer.
+func NewVerifier(keys KeySet, audience, issuer string) *Verifier {
+	return &Verifier{Keys: keys, Audience: audience, Issuer: issuer}
+}
+
+func (v *Verifier) Verify(ctx context.Context, token string) (*jwt.Token, error) {
+	p := jwt.Parser{ValidMethods: Algorithms}
+
+	t, err := p.Parse(token, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return v.Keys.Key(ctx, kid)
+	})
+	if err != nil {
+		// A key set that can't be reached is our failure, not the token's.
+		if ve, ok := err.(*jwt.ValidationError); ok && ve.Errors&jwt.ValidationErrorUnverifiable != 0 &&
+			ve.Inner != nil && ve.Inner != ErrUnknownKey {
+			return nil, errors.Wrap(ve.Inner, "looking up signing key")
+		}
+		return nil, errors.Wrap(ErrInvalidToken, err.Error())
+	}
+
+	claims := t.Claims.(jwt.MapClaims)
+
+	if !claims.VerifyExpiresAt(time.Now().Unix(), true) {
+		return nil, errors.Wrap(ErrInvalidToken, "token does not expire")
+	}
+	if !hasAudience(claims, v.Audience) {
+		return nil, errors.Wrap(ErrInvalidToken, "invalid audience")
+	}
+	if !claims.VerifyIssuer(v.Issuer, true) {
+		return nil, errors.Wrap(ErrInvalidToken, "invalid issuer")
+	}
+
+	return t, nil
+}
+
+// hasAudience reports whether the aud claim, a string or a list of them,
+// names aud. MapClaims.VerifyAudience only understands a single string.
+func hasAudience(claims jwt.MapClaims, aud string) bool {
+	switch v := claims["aud"].(type) {
+	case string:
+		return v == aud
+	case []interface{}:
+		for _, a := range v {
+			if s, ok := a.(string); ok && s == aud {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,151 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/pkg/errors"
+)
+
+const (
+	testAudience = "https://api.devpie.test"
+	testIssuer   = "https://devpie.test/"
+)
+
+// sign returns a token with valid claims, changed by edit, signed with key.
+func sign(t *testing.T, method jwt.SigningMethod, key crypto.PrivateKey, kid string, edit func(jwt.MapClaims)) string {
+	t.Helper()
+
+	claims := jwt.MapClaims{
+		"iss": testIssuer,
+		"aud": testAudience,
+		"sub": "auth0|123",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+	if edit != nil {
+		edit(claims)
+	}
+
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+
+	s, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestVerify(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v := NewVerifier(StaticKeys{"rsa": &rsaKey.PublicKey, "ec": &ecKey.PublicKey}, testAudience, testIssuer)
+
+	tests := []struct {
+		name  string
+		token string
+		ok    bool
+	}{
+		{"RS256", sign(t, jwt.SigningMethodRS256, rsaKey, "rsa", nil), true},
+		{"ES256", sign(t, jwt.SigningMethodES256, ecKey, "ec", nil), true},
+		{"audience list", sign(t, jwt.SigningMethodRS256, rsaKey, "rsa", func(c jwt.MapClaims) {
+			c["aud"] = []string{"https://devpie.test/userinfo", testAudience}
+		}), true},
+		{"other audience", sign(t, jwt.SigningMethodRS256, rsaKey, "rsa", func(c jwt.MapClaims) {
+			c["aud"] = "https://elsewhere"
+		}), false},
+		{"no audience", sign(t, jwt.SigningMethodRS256, rsaKey, "rsa", func(c jwt.MapClaims) {
+			delete(c, "aud")
+		}), false},
+		{"other issuer", sign(t, jwt.SigningMethodRS256, rsaKey, "rsa", func(c jwt.MapClaims) {
+			c["iss"] = "https://elsewhere/"
+		}), false},
+		{"expired", sign(t, jwt.SigningMethodRS256, rsaKey, "rsa", func(c jwt.MapClaims) {
+			c["exp"] = time.Now().Add(-time.Minute).Unix()
+		}), false},
+		{"no expiry", sign(t, jwt.SigningMethodRS256, rsaKey, "rsa", func(c jwt.MapClaims) {
+			delete(c, "exp")
+		}), false},
+		{"other key", sign(t, jwt.SigningMethodRS256, otherKey, "rsa", nil), false},
+		{"unknown key", sign(t, jwt.SigningMethodRS256, rsaKey, "gone", nil), false},
+		{"key of other type", sign(t, jwt.SigningMethodRS256, rsaKey, "ec", nil), false},
+		{"HS256", sign(t, jwt.SigningMethodHS256, []byte("secret"), "rsa", nil), false},
+		{"none", sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, "rsa", nil), false},
+		{"malformed", "not.a.token", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := v.Verify(context.Background(), tt.token)
+			if tt.ok {
+				if err != nil {
+					t.Fatalf("got %v, want a valid token", err)
+				}
+				if sub := token.Claims.(jwt.MapClaims)["sub"]; sub != "auth0|123" {
+					t.Errorf("subject: got %v", sub)
+				}
+				return
+			}
+			if errors.Cause(err) != ErrInvalidToken {
+				t.Errorf("got %v, want %v", err, ErrInvalidToken)
+			}
+		})
+	}
+}
+
+func TestVerifyKeySetFailure(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	down := errors.New("key set unavailable")
+	v := NewVerifier(keySetFunc(func(ctx context.Context, kid string) (crypto.PublicKey, error) {
+		return nil, down
+	}), testAudience, testIssuer)
+
+	_, err = v.Verify(context.Background(), sign(t, jwt.SigningMethodRS256, key, "rsa", nil))
+	if errors.Cause(err) != down {
+		t.Errorf("got %v, want %v", err, down)
+	}
+}
+
+func TestStaticVerifier(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v := NewStaticVerifier(&key.PublicKey, testAudience, testIssuer)
+	for _, kid := range []string{"", "any"} {
+		if _, err := v.Verify(context.Background(), sign(t, jwt.SigningMethodES256, key, kid, nil)); err != nil {
+			t.Errorf("kid %q: %v", kid, err)
+		}
+	}
+}
+
+type keySetFunc func(ctx context.Context, kid string) (crypto.PublicKey, error)
+
+func (f keySetFunc) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	return f(ctx, kid)
+}
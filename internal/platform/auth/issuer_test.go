@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// keyFile returns the path of a key file in a temporary directory, removed
+// at the end of the test.
+func keyFile(t *testing.T) string {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	return filepath.Join(dir, "dev-auth.pem")
+}
+
+func TestLoadKey(t *testing.T) {
+	path := keyFile(t)
+
+	created, err := LoadKey(path)
+	if err != nil {
+		t.Fatalf("creating key: %v", err)
+	}
+
+	loaded, err := LoadKey(path)
+	if err != nil {
+		t.Fatalf("loading key: %v", err)
+	}
+	if created.D.Cmp(loaded.D) != 0 || created.N.Cmp(loaded.N) != 0 {
+		t.Error("loaded key differs from the created key")
+	}
+}
+
+func TestIssuer(t *testing.T) {
+	key, err := LoadKey(keyFile(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	i := NewIssuer(key, "https://localhost:4000/", "https://api.devpie.test")
+
+	raw, err := i.Sign(jwt.MapClaims{"sub": "dev|jane", "iss": "https://evil.test/"}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The published key set verifies the tokens, the same as its keys.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(i.JWKS())
+	}))
+	defer srv.Close()
+
+	for name, keys := range map[string]KeySet{"keys": i.Keys(), "jwks": NewJWKS(srv.URL, time.Hour)} {
+		token, err := NewVerifier(keys, i.Audience, i.Name).Verify(context.Background(), raw)
+		if err != nil {
+			t.Fatalf("%s: verifying: %v", name, err)
+		}
+		if sub := token.Claims.(jwt.MapClaims)["sub"]; sub != "dev|jane" {
+			t.Errorf("%s: sub: got %v, want dev|jane", name, sub)
+		}
+	}
+
+	other := NewIssuer(key, "https://localhost:4000/", "https://other.test")
+	raw, err = other.Sign(jwt.MapClaims{"sub": "dev|jane"}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewVerifier(i.Keys(), i.Audience, i.Name).Verify(context.Background(), raw); err == nil {
+		t.Error("verified a token for another audience")
+	}
+}
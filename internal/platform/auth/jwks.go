@@ -0,0 +1,185 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// MinRefresh is the least time between two fetches of a key set, so tokens
+// with unknown key ids can't make a JWKS fetch its keys on every request.
+const MinRefresh = 30 * time.Second
+
+// JWKS is a KeySet fetching its keys from a JSON Web Key Set URL, such as
+// https://<domain>/.well-known/jwks.json. Keys are cached for TTL and fetched
+// again early when a token names a key id that isn't cached, for instance
+// after the issuer rotated its keys. When a fetch fails the cached keys are
+// used until it succeeds. Create one with NewJWKS.
+type JWKS struct {
+	URL    string
+	TTL    time.Duration
+	Client *http.Client
+
+	mu      sync.Mutex
+	keys    map[string]crypto.PublicKey
+	fetched time.Time // last successful fetch
+	checked time.Time // last fetch attempt
+	now     func() time.Time
+}
+
+// NewJWKS returns a JWKS of the key set at url, caching keys for ttl.
+func NewJWKS(url string, ttl time.Duration) *JWKS {
+	return &JWKS{
+		URL:    url,
+		TTL:    ttl,
+		Client: &http.Client{Timeout: 10 * time.Second},
+		now:    time.Now,
+	}
+}
+
+func (s *JWKS) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	// Holding the lock while fetching makes concurrent callers share a fetch.
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	k, ok := s.keys[kid]
+
+	switch {
+	case ok && now.Sub(s.fetched) < s.TTL:
+		return k, nil
+	case now.Sub(s.checked) < MinRefresh:
+		if ok {
+			return k, nil
+		}
+		return nil, ErrUnknownKey
+	}
+
+	s.checked = now
+	keys, err := s.fetch(ctx)
+	if err != nil {
+		if ok {
+			return k, nil
+		}
+		return nil, err
+	}
+	s.keys = keys
+	s.fetched = now
+
+	if k, ok := s.keys[kid]; ok {
+		return k, nil
+	}
+	return nil, ErrUnknownKey
+}
+
+// jwk is a JSON Web Key as defined by RFC 7517.
+type jwk struct {
+	Kty string   `json:"kty"`
+	Kid string   `json:"kid"`
+	Use string   `json:"use"`
+	N   string   `json:"n"`
+	E   string   `json:"e"`
+	Crv string   `json:"crv"`
+	X   string   `json:"x"`
+	Y   string   `json:"y"`
+	X5c []string `json:"x5c"`
+}
+
+// fetch downloads the key set. Keys that aren't for signatures or of an
+// unsupported type are left out.
+func (s *JWKS) fetch(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	req, err := http.NewRequest(http.MethodGet, s.URL, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetching key set")
+	}
+
+	resp, err := s.Client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, errors.Wrap(err, "fetching key set")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("fetching key set: %s", resp.Status)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, errors.Wrap(err, "decoding key set")
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		if key, err := k.publicKey(); err == nil {
+			keys[k.Kid] = key
+		}
+	}
+
+	return keys, nil
+}
+
+// publicKey decodes an RSA or P-256 elliptic curve key, preferring its
+// parameters over its certificate chain.
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch {
+	case k.Kty == "RSA" && k.N != "" && k.E != "":
+		n, err := decodeInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case k.Kty == "EC" && k.Crv == "P-256":
+		x, err := decodeInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+
+	case len(k.X5c) > 0:
+		der, err := base64.StdEncoding.DecodeString(k.X5c[0])
+		if err != nil {
+			return nil, err
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, err
+		}
+		return cert.PublicKey, nil
+	}
+
+	return nil, errors.Errorf("unsupported key type %q", k.Kty)
+}
+
+// decodeInt decodes a base64url encoded big-endian integer.
+func decodeInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,191 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// keyServer serves a JSON web key set and counts its requests.
+type keyServer struct {
+	mu       sync.Mutex
+	keys     []jwk
+	requests int
+	down     bool
+}
+
+func (s *keyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests++
+	if s.down {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string][]jwk{"keys": s.keys})
+}
+
+func (s *keyServer) set(keys ...jwk) {
+	s.mu.Lock()
+	s.keys = keys
+	s.mu.Unlock()
+}
+
+func (s *keyServer) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+func rsaJWK(kid string, k *rsa.PublicKey) jwk {
+	return jwk{
+		Kty: "RSA",
+		Kid: kid,
+		Use: "sig",
+		N:   base64.RawURLEncoding.EncodeToString(k.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes()),
+	}
+}
+
+func ecJWK(kid string, k *ecdsa.PublicKey) jwk {
+	return jwk{
+		Kty: "EC",
+		Kid: kid,
+		Crv: "P-256",
+		X:   base64.RawURLEncoding.EncodeToString(k.X.Bytes()),
+		Y:   base64.RawURLEncoding.EncodeToString(k.Y.Bytes()),
+	}
+}
+
+// certJWK returns a key only described by a self-signed certificate, the
+// way Auth0 publishes them alongside the key parameters.
+func certJWK(t *testing.T, kid string, k *rsa.PrivateKey) jwk {
+	t.Helper()
+
+	tmpl := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "devpie.test"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &tmpl, &tmpl, &k.PublicKey, k)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return jwk{Kty: "RSA", Kid: kid, X5c: []string{base64.StdEncoding.EncodeToString(der)}}
+}
+
+func TestJWKS(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ks := &keyServer{}
+	ks.set(
+		rsaJWK("rsa", &rsaKey.PublicKey),
+		certJWK(t, "cert", certKey),
+		ecJWK("ec", &ecKey.PublicKey),
+		jwk{Kty: "RSA", Kid: "enc", Use: "enc", N: "AQAB", E: "AQAB"},
+	)
+	srv := httptest.NewServer(ks)
+	defer srv.Close()
+
+	now := time.Now()
+	jwks := NewJWKS(srv.URL, time.Hour)
+	jwks.now = func() time.Time { return now }
+
+	v := NewVerifier(jwks, testAudience, testIssuer)
+	ctx := context.Background()
+
+	tokens := map[string]string{
+		"rsa":  sign(t, jwt.SigningMethodRS256, rsaKey, "rsa", nil),
+		"cert": sign(t, jwt.SigningMethodRS256, certKey, "cert", nil),
+		"ec":   sign(t, jwt.SigningMethodES256, ecKey, "ec", nil),
+	}
+	for kid, token := range tokens {
+		if _, err := v.Verify(ctx, token); err != nil {
+			t.Errorf("%s: %v", kid, err)
+		}
+	}
+	if n := ks.count(); n != 1 {
+		t.Errorf("fetches for cached keys: got %d, want 1", n)
+	}
+
+	if _, err := jwks.Key(ctx, "enc"); err != ErrUnknownKey {
+		t.Errorf("encryption key: got %v, want %v", err, ErrUnknownKey)
+	}
+
+	// Unknown key ids don't fetch the set more than once per MinRefresh.
+	if _, err := jwks.Key(ctx, "new"); err != ErrUnknownKey {
+		t.Errorf("unknown key: got %v, want %v", err, ErrUnknownKey)
+	}
+	if n := ks.count(); n != 1 {
+		t.Errorf("fetches within MinRefresh: got %d, want 1", n)
+	}
+
+	// A rotated key is found by fetching the set again.
+	rotated, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks.set(rsaJWK("rsa", &rsaKey.PublicKey), rsaJWK("new", &rotated.PublicKey))
+	now = now.Add(MinRefresh)
+
+	if _, err := v.Verify(ctx, sign(t, jwt.SigningMethodRS256, rotated, "new", nil)); err != nil {
+		t.Errorf("rotated key: %v", err)
+	}
+	if n := ks.count(); n != 2 {
+		t.Errorf("fetches after rotation: got %d, want 2", n)
+	}
+
+	// Expired keys are fetched again, and kept while the set is down.
+	ks.mu.Lock()
+	ks.down = true
+	ks.mu.Unlock()
+	now = now.Add(time.Hour)
+
+	if _, err := v.Verify(ctx, tokens["rsa"]); err != nil {
+		t.Errorf("stale key: %v", err)
+	}
+	if n := ks.count(); n != 3 {
+		t.Errorf("fetches after expiry: got %d, want 3", n)
+	}
+	if _, err := jwks.Key(ctx, "ec"); err != ErrUnknownKey {
+		t.Errorf("key removed from the set: got %v, want %v", err, ErrUnknownKey)
+	}
+}
+
+func TestJWKSUnavailable(t *testing.T) {
+	srv := httptest.NewServer(&keyServer{down: true})
+	defer srv.Close()
+
+	jwks := NewJWKS(srv.URL, time.Hour)
+	if _, err := jwks.Key(context.Background(), "rsa"); err == nil || err == ErrUnknownKey {
+		t.Errorf("got %v, want the fetch error", err)
+	}
+}
@@ -20,6 +20,9 @@ type Config struct {
 	DisableTLS bool
 }
 
+// Repository gives access to the database. Statements run through its
+// methods and its statement builder SQ join the unit of work of their
+// context, see Begin.
 type Repository struct {
 	DB  *sqlx.DB
 	SQ  squirrel.StatementBuilderType
@@ -54,11 +57,10 @@ func NewRepository(cfg Config) (*Repository, error) {
 		return nil, errors.Wrap(err, "connecting to database")
 	}
 
-	return &Repository{
-		DB:  db,
-		SQ:  squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar).RunWith(db),
-		URL: u,
-	}, nil
+	repo := Repository{DB: db, URL: u}
+	repo.SQ = squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar).RunWith(runner{&repo})
+
+	return &repo, nil
 
 }
 
@@ -0,0 +1,164 @@
+package database
+
+import (
+	"strings"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+)
+
+// ErrInvalidPage is returned when the position of a page doesn't fit the
+// order of the listing.
+var ErrInvalidPage = errors.New("page position does not match the order of the listing")
+
+// Key is a column or expression a listing is ordered by. Rows without a value
+// for a Nullable key come last whatever the direction.
+type Key struct {
+	Expr     string
+	Desc     bool
+	Nullable bool
+}
+
+// Page limits a listing to Limit rows following the row whose key values are
+// After, one value per key of the listing's order. A zero Limit selects every
+// row, a nil After starts at the first row. Key values are strings, times,
+// ints or nil. Unlike an offset, the position is unaffected by rows added or
+// removed before it.
+type Page struct {
+	Limit int
+	After []interface{}
+}
+
+// Check reports ErrInvalidPage unless After has a value for every key and
+// values for the keys that can't be missing.
+func (p Page) Check(keys []Key) error {
+	if p.After == nil {
+		return nil
+	}
+	if len(p.After) != len(keys) {
+		return ErrInvalidPage
+	}
+	for i, k := range keys {
+		switch p.After[i].(type) {
+		case nil:
+			if !k.Nullable {
+				return ErrInvalidPage
+			}
+		case string, time.Time, int:
+		default:
+			return ErrInvalidPage
+		}
+	}
+	return nil
+}
+
+// Apply orders a select statement by keys and adds the page bounds.
+func (p Page) Apply(stmt squirrel.SelectBuilder, keys []Key) (squirrel.SelectBuilder, error) {
+	if err := p.Check(keys); err != nil {
+		return stmt, err
+	}
+
+	order := make([]string, len(keys))
+	for i, k := range keys {
+		order[i] = k.Expr
+		if k.Desc {
+			order[i] += " DESC"
+		}
+		if k.Nullable {
+			order[i] += " NULLS LAST"
+		}
+	}
+	stmt = stmt.OrderBy(order...)
+
+	if p.After != nil {
+		stmt = stmt.Where(after(keys, p.After))
+	}
+	if p.Limit > 0 {
+		stmt = stmt.Limit(uint64(p.Limit))
+	}
+	return stmt, nil
+}
+
+// after matches the rows ordered after the key values of a row: those equal
+// on the first keys and after it on the next one.
+func after(keys []Key, values []interface{}) squirrel.Sqlizer {
+	var or squirrel.Or
+	var equal squirrel.And
+
+	for i, k := range keys {
+		v := values[i]
+
+		// Nothing comes after a missing value but other missing values.
+		if v != nil {
+			op := " > ?"
+			if k.Desc {
+				op = " < ?"
+			}
+			next := squirrel.Expr(k.Expr+op, v)
+			if k.Nullable {
+				next = squirrel.Expr("("+k.Expr+op+" OR "+k.Expr+" IS NULL)", v)
+			}
+			or = append(or, append(append(squirrel.And{}, equal...), next))
+		}
+
+		if v == nil {
+			equal = append(equal, squirrel.Expr(k.Expr+" IS NULL"))
+		} else {
+			equal = append(equal, squirrel.Expr(k.Expr+" = ?", v))
+		}
+	}
+
+	return or
+}
+
+// Compare orders the key values a and b of two rows the way Apply orders the
+// rows. It returns a negative number when a comes first, a positive one when
+// b does and 0 when they are equal.
+func Compare(keys []Key, a, b []interface{}) int {
+	for i, k := range keys {
+		switch {
+		case a[i] == nil && b[i] == nil:
+			continue
+		case a[i] == nil:
+			return 1
+		case b[i] == nil:
+			return -1
+		}
+
+		c := compare(a[i], b[i])
+		if k.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// compare compares two values of the same type. Values of different types
+// are equal.
+func compare(a, b interface{}) int {
+	switch a := a.(type) {
+	case string:
+		if b, ok := b.(string); ok {
+			return strings.Compare(a, b)
+		}
+	case int:
+		if b, ok := b.(int); ok && a != b {
+			if a < b {
+				return -1
+			}
+			return 1
+		}
+	case time.Time:
+		if b, ok := b.(time.Time); ok && !a.Equal(b) {
+			if a.Before(b) {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
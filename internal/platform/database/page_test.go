@@ -0,0 +1,79 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+)
+
+func TestPageApply(t *testing.T) {
+	keys := []Key{{Expr: "due_date", Desc: true, Nullable: true}, {Expr: "created"}, {Expr: "task_id"}}
+	created := time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC)
+	sq := squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar)
+
+	tests := []struct {
+		after []interface{}
+		sql   string
+		args  []interface{}
+	}{
+		{
+			nil,
+			"SELECT task_id FROM tasks ORDER BY due_date DESC NULLS LAST, created, task_id LIMIT 3",
+			nil,
+		},
+		{
+			[]interface{}{created, created, "a"},
+			"SELECT task_id FROM tasks WHERE (((due_date < $1 OR due_date IS NULL)) OR (due_date = $2 AND created > $3) OR (due_date = $4 AND created = $5 AND task_id > $6)) ORDER BY due_date DESC NULLS LAST, created, task_id LIMIT 3",
+			[]interface{}{created, created, created, created, created, "a"},
+		},
+		{
+			[]interface{}{nil, created, "a"},
+			"SELECT task_id FROM tasks WHERE ((due_date IS NULL AND created > $1) OR (due_date IS NULL AND created = $2 AND task_id > $3)) ORDER BY due_date DESC NULLS LAST, created, task_id LIMIT 3",
+			[]interface{}{created, created, "a"},
+		},
+	}
+
+	for _, tt := range tests {
+		stmt, err := Page{Limit: 3, After: tt.after}.Apply(sq.Select("task_id").From("tasks"), keys)
+		if err != nil {
+			t.Fatalf("after %v: %v", tt.after, err)
+		}
+		sql, args, err := stmt.ToSql()
+		if err != nil {
+			t.Fatalf("after %v: %v", tt.after, err)
+		}
+		if sql != tt.sql || !reflect.DeepEqual(args, tt.args) {
+			t.Errorf("after %v: got %s %v, want %s %v", tt.after, sql, args, tt.sql, tt.args)
+		}
+	}
+}
+
+func TestPageCheck(t *testing.T) {
+	keys := []Key{{Expr: "due_date", Nullable: true}, {Expr: "task_id"}}
+
+	for _, after := range [][]interface{}{{"a"}, {nil, nil}, {"a", 1.5}} {
+		if err := (Page{After: after}).Check(keys); err != ErrInvalidPage {
+			t.Errorf("after %v: got %v, want %v", after, err, ErrInvalidPage)
+		}
+	}
+	if err := (Page{After: []interface{}{nil, "a"}}).Check(keys); err != nil {
+		t.Errorf("missing nullable value: got %v", err)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	keys := []Key{{Expr: "estimate", Desc: true, Nullable: true}, {Expr: "task_id"}}
+
+	// In the order of the keys.
+	rows := [][]interface{}{{3, "b"}, {1, "a"}, {1, "b"}, {nil, "a"}, {nil, "c"}}
+	for i := range rows {
+		for j := range rows {
+			got := Compare(keys, rows[i], rows[j])
+			if (got < 0) != (i < j) || (got == 0) != (i == j) {
+				t.Errorf("Compare(%v, %v): got %d", rows[i], rows[j], got)
+			}
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// ErrNoUnit is returned when committing or rolling back a context that is not
+// part of a unit of work.
+var ErrNoUnit = errors.New("context is not part of a unit of work")
+
+// Unit begins, commits and rolls back units of work. The Repository is the
+// Unit of the API, see Begin.
+type Unit interface {
+	Begin(ctx context.Context) (context.Context, error)
+	Commit(ctx context.Context) error
+	Rollback(ctx context.Context) error
+}
+
+// unitKey is the context key of the current unit of work.
+type unitKey struct{}
+
+// unit is a unit of work. Nested units share the transaction of the
+// outermost one, which alone commits or rolls it back.
+type unit struct {
+	tx    *sqlx.Tx
+	outer bool
+}
+
+// executor runs statements against the database or a transaction.
+type executor interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+}
+
+// Begin starts a unit of work. Every statement the repository runs with the
+// returned context is part of it until Commit or Rollback. When ctx is
+// already part of a unit of work Begin joins it, and Commit and Rollback of
+// the inner unit leave the transaction to the outer one.
+func (r *Repository) Begin(ctx context.Context) (context.Context, error) {
+	if u, ok := ctx.Value(unitKey{}).(*unit); ok {
+		return context.WithValue(ctx, unitKey{}, &unit{tx: u.tx}), nil
+	}
+
+	tx, err := r.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return ctx, errors.Wrap(err, "beginning transaction")
+	}
+
+	return context.WithValue(ctx, unitKey{}, &unit{tx: tx, outer: true}), nil
+}
+
+// Commit commits the unit of work of ctx.
+func (r *Repository) Commit(ctx context.Context) error {
+	u, ok := ctx.Value(unitKey{}).(*unit)
+	if !ok {
+		return ErrNoUnit
+	}
+	if !u.outer {
+		return nil
+	}
+	return errors.Wrap(u.tx.Commit(), "committing transaction")
+}
+
+// Rollback aborts the unit of work of ctx. It does nothing once the unit has
+// been committed, so it is safe to defer right after Begin.
+func (r *Repository) Rollback(ctx context.Context) error {
+	u, ok := ctx.Value(unitKey{}).(*unit)
+	if !ok {
+		return ErrNoUnit
+	}
+	if !u.outer {
+		return nil
+	}
+	if err := u.tx.Rollback(); err != nil && err != sql.ErrTxDone {
+		return errors.Wrap(err, "rolling back transaction")
+	}
+	return nil
+}
+
+// executor returns the transaction of the unit of work of ctx, or the
+// database outside of one.
+func (r *Repository) executor(ctx context.Context) executor {
+	if u, ok := ctx.Value(unitKey{}).(*unit); ok {
+		return u.tx
+	}
+	return r.DB
+}
+
+// ExecContext executes a statement within the unit of work of ctx, if any.
+func (r *Repository) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return r.executor(ctx).ExecContext(ctx, query, args...)
+}
+
+// QueryContext runs a query within the unit of work of ctx, if any.
+func (r *Repository) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return r.executor(ctx).QueryContext(ctx, query, args...)
+}
+
+// QueryRowContext runs a query expected to return at most one row within the
+// unit of work of ctx, if any.
+func (r *Repository) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return r.executor(ctx).QueryRowContext(ctx, query, args...)
+}
+
+// GetContext scans a single row into dest within the unit of work of ctx, if
+// any. It returns sql.ErrNoRows when there is no row.
+func (r *Repository) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return r.executor(ctx).GetContext(ctx, dest, query, args...)
+}
+
+// SelectContext scans every row into the slice dest within the unit of work
+// of ctx, if any.
+func (r *Repository) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return r.executor(ctx).SelectContext(ctx, dest, query, args...)
+}
+
+// runner runs the statements of the repository's statement builder within
+// the unit of work of their context, if any.
+type runner struct {
+	repo *Repository
+}
+
+func (r runner) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return r.repo.DB.Exec(query, args...)
+}
+
+func (r runner) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return r.repo.DB.Query(query, args...)
+}
+
+func (r runner) QueryRow(query string, args ...interface{}) *sql.Row {
+	return r.repo.DB.QueryRow(query, args...)
+}
+
+func (r runner) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return r.repo.ExecContext(ctx, query, args...)
+}
+
+func (r runner) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return r.repo.QueryContext(ctx, query, args...)
+}
+
+func (r runner) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return r.repo.QueryRowContext(ctx, query, args...)
+}
@@ -0,0 +1,52 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func TestUnitOutsideOfUnit(t *testing.T) {
+	db, err := sqlx.Open("postgres", "postgres://localhost/test?sslmode=disable")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	repo := Repository{DB: db}
+	ctx := context.Background()
+
+	if err := repo.Commit(ctx); err != ErrNoUnit {
+		t.Errorf("Commit: got %v, want %v", err, ErrNoUnit)
+	}
+	if err := repo.Rollback(ctx); err != ErrNoUnit {
+		t.Errorf("Rollback: got %v, want %v", err, ErrNoUnit)
+	}
+	if repo.executor(ctx) != db {
+		t.Error("executor outside of a unit of work is not the database")
+	}
+}
+
+func TestUnitNested(t *testing.T) {
+	outer := &unit{tx: &sqlx.Tx{}, outer: true}
+	repo := Repository{}
+	ctx := context.WithValue(context.Background(), unitKey{}, outer)
+
+	inner, err := repo.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if repo.executor(inner) != outer.tx {
+		t.Error("nested unit of work does not share the outer transaction")
+	}
+	// The inner unit leaves the transaction to the outer one, so neither
+	// touches the zero transaction.
+	if err := repo.Commit(inner); err != nil {
+		t.Errorf("Commit: %v", err)
+	}
+	if err := repo.Rollback(inner); err != nil {
+		t.Errorf("Rollback: %v", err)
+	}
+}
@@ -0,0 +1,19 @@
+package database
+
+// Versions are the versions of a row a change is conditional on, one of them
+// must be the stored version. Clients list them in an If-Match header. Empty
+// Versions match every version.
+type Versions []int
+
+// Match reports whether version is one of vs.
+func (vs Versions) Match(version int) bool {
+	if len(vs) == 0 {
+		return true
+	}
+	for _, v := range vs {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
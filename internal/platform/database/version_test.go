@@ -0,0 +1,23 @@
+package database
+
+import "testing"
+
+func TestVersionsMatch(t *testing.T) {
+	tests := []struct {
+		vs      Versions
+		version int
+		want    bool
+	}{
+		{nil, 3, true},
+		{Versions{3}, 3, true},
+		{Versions{3}, 4, false},
+		{Versions{3, 4}, 4, true},
+		{Versions{3, 4}, 5, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.vs.Match(tt.version); got != tt.want {
+			t.Errorf("%v.Match(%d): got %v, want %v", tt.vs, tt.version, got, tt.want)
+		}
+	}
+}
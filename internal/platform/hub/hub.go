@@ -0,0 +1,94 @@
+package hub
+
+import (
+	"sync"
+)
+
+// buffer is the number of messages a subscriber can fall behind before it is
+// dropped.
+const buffer = 32
+
+// Message is published to the subscribers of a topic.
+type Message struct {
+	ID   string
+	Name string
+	Data []byte
+}
+
+// Hub fans out messages to the subscribers of a topic. A subscriber that
+// does not keep up has its channel closed so it can resubscribe and catch up
+// instead of silently missing messages.
+type Hub struct {
+	mu     sync.Mutex
+	subs   map[string]map[chan Message]struct{}
+	closed bool
+}
+
+// New creates an empty Hub.
+func New() *Hub {
+	return &Hub{subs: make(map[string]map[chan Message]struct{})}
+}
+
+// Subscribe returns a channel receiving the messages published to topic and
+// a function to unsubscribe. The channel is closed when the subscriber is
+// dropped or the Hub is closed.
+func (h *Hub) Subscribe(topic string) (<-chan Message, func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ch := make(chan Message, buffer)
+	if h.closed {
+		close(ch)
+		return ch, func() {}
+	}
+
+	if h.subs[topic] == nil {
+		h.subs[topic] = make(map[chan Message]struct{})
+	}
+	h.subs[topic][ch] = struct{}{}
+
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.remove(topic, ch)
+	}
+}
+
+// Publish sends msg to every subscriber of topic without blocking.
+func (h *Hub) Publish(topic string, msg Message) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch := range h.subs[topic] {
+		select {
+		case ch <- msg:
+		default:
+			h.remove(topic, ch)
+		}
+	}
+}
+
+// Close closes the channels of all subscribers.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for topic, subs := range h.subs {
+		for ch := range subs {
+			h.remove(topic, ch)
+		}
+	}
+	h.closed = true
+}
+
+// remove closes and forgets a subscriber. The caller holds the lock.
+func (h *Hub) remove(topic string, ch chan Message) {
+	if _, ok := h.subs[topic][ch]; !ok {
+		return
+	}
+	delete(h.subs[topic], ch)
+	close(ch)
+	if len(h.subs[topic]) == 0 {
+		delete(h.subs, topic)
+	}
+}
@@ -0,0 +1,61 @@
+package hub
+
+import (
+	"testing"
+)
+
+func TestPublish(t *testing.T) {
+	h := New()
+
+	a, unsubscribe := h.Subscribe("p1")
+	b, _ := h.Subscribe("p2")
+
+	h.Publish("p1", Message{ID: "1"})
+
+	if msg := <-a; msg.ID != "1" {
+		t.Fatalf("got message %q, want 1", msg.ID)
+	}
+	select {
+	case msg := <-b:
+		t.Fatalf("other topic got message %q", msg.ID)
+	default:
+	}
+
+	unsubscribe()
+	if _, ok := <-a; ok {
+		t.Fatal("channel open after unsubscribing")
+	}
+	unsubscribe()
+}
+
+func TestSlowSubscriber(t *testing.T) {
+	h := New()
+	ch, _ := h.Subscribe("p1")
+
+	for i := 0; i <= buffer; i++ {
+		h.Publish("p1", Message{})
+	}
+
+	n := 0
+	for range ch {
+		n++
+	}
+	if n != buffer {
+		t.Fatalf("got %d messages before the channel closed, want %d", n, buffer)
+	}
+}
+
+func TestClose(t *testing.T) {
+	h := New()
+	ch, _ := h.Subscribe("p1")
+
+	h.Close()
+	if _, ok := <-ch; ok {
+		t.Fatal("channel open after closing the hub")
+	}
+
+	ch, _ = h.Subscribe("p1")
+	if _, ok := <-ch; ok {
+		t.Fatal("subscribed to a closed hub")
+	}
+}
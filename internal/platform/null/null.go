@@ -0,0 +1,45 @@
+// Package null provides optional values for partial updates. Each type
+// remembers whether its key was present in the decoded JSON document, so an
+// omitted field can be left untouched while an explicit null clears it.
+package null
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// String is an optional, nullable string.
+type String struct {
+	Set   bool
+	Value *string
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (s *String) UnmarshalJSON(b []byte) error {
+	s.Set = true
+	return json.Unmarshal(b, &s.Value)
+}
+
+// Int is an optional, nullable int.
+type Int struct {
+	Set   bool
+	Value *int
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (i *Int) UnmarshalJSON(b []byte) error {
+	i.Set = true
+	return json.Unmarshal(b, &i.Value)
+}
+
+// Time is an optional, nullable time.
+type Time struct {
+	Set   bool
+	Value *time.Time
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (t *Time) UnmarshalJSON(b []byte) error {
+	t.Set = true
+	return json.Unmarshal(b, &t.Value)
+}
@@ -0,0 +1,55 @@
+package web
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ErrPreconditionFailed is returned when the version in an If-Match header
+// is not the current version of a resource.
+var ErrPreconditionFailed = NewRequestError(errors.New("resource was changed since it was last read"), http.StatusPreconditionFailed)
+
+// Versioned is implemented by resources with a version number that is
+// incremented on every change. Respond sends it as the ETag of the resource.
+type Versioned interface {
+	CurrentVersion() int
+}
+
+// ETag formats a version as an entity tag.
+func ETag(version int) string {
+	return strconv.Quote(strconv.Itoa(version))
+}
+
+// IfMatch returns the versions a request is conditional on, those of the
+// comma separated entity tags of its If-Match headers. Without an If-Match
+// header, or with "*", it returns none and the request applies to any version.
+func IfMatch(r *http.Request) ([]int, error) {
+	var versions []int
+	for _, h := range r.Header.Values("If-Match") {
+		for _, tag := range strings.Split(h, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "" {
+				continue
+			}
+			if tag == "*" {
+				return nil, nil
+			}
+
+			v, err := strconv.Unquote(strings.TrimPrefix(tag, "W/"))
+			if err != nil {
+				return nil, ErrPreconditionFailed
+			}
+
+			version, err := strconv.Atoi(v)
+			if err != nil || version < 1 {
+				return nil, ErrPreconditionFailed
+			}
+			versions = append(versions, version)
+		}
+	}
+
+	return versions, nil
+}
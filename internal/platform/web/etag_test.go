@@ -0,0 +1,40 @@
+package web
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestIfMatch(t *testing.T) {
+	tests := []struct {
+		header   string
+		versions []int
+		ok       bool
+	}{
+		{"", nil, true},
+		{"*", nil, true},
+		{`"3"`, []int{3}, true},
+		{`W/"3"`, []int{3}, true},
+		{ETag(12), []int{12}, true},
+		{`"3", "4"`, []int{3, 4}, true},
+		{`"3",W/"4"`, []int{3, 4}, true},
+		{`"3", *`, nil, true},
+		{"3", nil, false},
+		{`"3", 4`, nil, false},
+		{`"x"`, nil, false},
+		{`"0"`, nil, false},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest("PATCH", "/", nil)
+		if tt.header != "" {
+			r.Header.Set("If-Match", tt.header)
+		}
+
+		versions, err := IfMatch(r)
+		if (err == nil) != tt.ok || !reflect.DeepEqual(versions, tt.versions) {
+			t.Errorf("%q: got %v, %v, want %v, ok %v", tt.header, versions, err, tt.versions, tt.ok)
+		}
+	}
+}
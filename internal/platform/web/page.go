@@ -0,0 +1,138 @@
+package web
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Bounds of the number of items in a page.
+const (
+	DefaultLimit = 50
+	MaxLimit     = 200
+)
+
+// PageRequest is the page of a listing asked for by a client with the limit
+// and cursor query parameters. After holds the sort key of the last item of
+// the previous page, see database.Page.
+type PageRequest struct {
+	Limit int
+	After []interface{}
+
+	// query is the digest of the other query parameters of the request.
+	query string
+}
+
+// Page is the response envelope of a paginated listing. NextCursor is null on
+// the last page.
+type Page struct {
+	Data       interface{} `json:"data"`
+	NextCursor *string     `json:"nextCursor"`
+}
+
+// cursor is the position in a listing: the sort key of the last item of a
+// page, and the digest of the query it was listed with. Clients receive it
+// base64 encoded and must treat it as opaque.
+type cursor struct {
+	Key   []keyValue `json:"k"`
+	Query string     `json:"q"`
+}
+
+// keyValue is a typed value of a sort key, all fields are nil for a missing
+// value.
+type keyValue struct {
+	S *string    `json:"s,omitempty"`
+	I *int       `json:"i,omitempty"`
+	T *time.Time `json:"t,omitempty"`
+}
+
+// ParsePage reads the limit and cursor query parameters of a request. A
+// cursor is only valid with the query parameters of the page it came from.
+func ParsePage(r *http.Request) (PageRequest, error) {
+	query := r.URL.Query()
+	pr := PageRequest{Limit: DefaultLimit, query: digest(query)}
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > MaxLimit {
+			return pr, NewRequestError(errors.Errorf("limit must be between 1 and %d", MaxLimit), http.StatusBadRequest)
+		}
+		pr.Limit = limit
+	}
+
+	if v := query.Get("cursor"); v != "" {
+		var c cursor
+		b, err := base64.RawURLEncoding.DecodeString(v)
+		if err == nil {
+			err = json.Unmarshal(b, &c)
+		}
+		if err != nil || len(c.Key) == 0 || c.Query != pr.query {
+			return pr, NewRequestError(errors.New("cursor is not valid"), http.StatusBadRequest)
+		}
+
+		pr.After = make([]interface{}, len(c.Key))
+		for i, kv := range c.Key {
+			switch {
+			case kv.S != nil:
+				pr.After[i] = *kv.S
+			case kv.I != nil:
+				pr.After[i] = *kv.I
+			case kv.T != nil:
+				pr.After[i] = *kv.T
+			}
+		}
+	}
+
+	return pr, nil
+}
+
+// NewPage wraps list in a Page. The list must be a slice queried with one
+// item more than the page limit, the extra item tells there is a next page
+// and is left out of the response. key returns the sort key of the i-th item
+// of the list, the next page starts after the last item of this one.
+func NewPage(pr PageRequest, list interface{}, key func(i int) []interface{}) Page {
+	v := reflect.ValueOf(list)
+	if v.Len() <= pr.Limit {
+		return Page{Data: list}
+	}
+
+	c := cursor{Query: pr.query}
+	for _, k := range key(pr.Limit - 1) {
+		var kv keyValue
+		switch k := k.(type) {
+		case string:
+			kv.S = &k
+		case int:
+			kv.I = &k
+		case time.Time:
+			kv.T = &k
+		}
+		c.Key = append(c.Key, kv)
+	}
+
+	b, _ := json.Marshal(c)
+	next := base64.RawURLEncoding.EncodeToString(b)
+
+	return Page{Data: v.Slice(0, pr.Limit).Interface(), NextCursor: &next}
+}
+
+// digest returns a short digest of the query parameters other than limit and
+// cursor.
+func digest(query url.Values) string {
+	q := url.Values{}
+	for k, v := range query {
+		if k != "limit" && k != "cursor" {
+			q[k] = v
+		}
+	}
+
+	sum := sha256.Sum256([]byte(q.Encode()))
+	return base64.RawURLEncoding.EncodeToString(sum[:8])
+}
@@ -0,0 +1,54 @@
+package web
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPage(t *testing.T) {
+	created := time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC)
+	key := func(i int) []interface{} {
+		return []interface{}{"c", i, created.Add(time.Duration(i) * time.Hour), nil}
+	}
+	pr := PageRequest{Limit: 2, query: digest(url.Values{"sort": {"title"}})}
+
+	last := NewPage(pr, []int{1, 2}, key)
+	if last.NextCursor != nil {
+		t.Fatalf("last page: got cursor %q, want none", *last.NextCursor)
+	}
+
+	page := NewPage(pr, []int{1, 2, 3}, key)
+	if got := page.Data.([]int); len(got) != 2 {
+		t.Fatalf("page data: got %v, want 2 items", got)
+	}
+	if page.NextCursor == nil {
+		t.Fatal("page: got no cursor, want one")
+	}
+
+	r := httptest.NewRequest("GET", "/?sort=title&limit=2&cursor="+*page.NextCursor, nil)
+	next, err := ParsePage(r)
+	if err != nil {
+		t.Fatalf("parsing page: %v", err)
+	}
+	if next.Limit != 2 || !reflect.DeepEqual(next.After, key(1)) {
+		t.Fatalf("next page: got %+v, want limit 2 after %v", next, key(1))
+	}
+
+	// The cursor of a listing is of no use in another.
+	r = httptest.NewRequest("GET", "/?sort=-title&limit=2&cursor="+*page.NextCursor, nil)
+	if _, err := ParsePage(r); err == nil {
+		t.Fatal("cursor with another sort: got no error")
+	}
+}
+
+func TestParsePageErrors(t *testing.T) {
+	for _, query := range []string{"limit=0", "limit=201", "limit=x", "cursor=!!", "cursor=e30x"} {
+		r := httptest.NewRequest("GET", "/?"+query, nil)
+		if _, err := ParsePage(r); err == nil {
+			t.Errorf("%s: got no error", query)
+		}
+	}
+}
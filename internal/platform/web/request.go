@@ -7,6 +7,8 @@ import (
 	"reflect"
 	"strings"
 
+	"github.com/ivorscott/devpie-client-backend-go/internal/platform/null"
+
 	en "github.com/go-playground/locales/en"
 	ut "github.com/go-playground/universal-translator"
 	validator "gopkg.in/go-playground/validator.v9"
@@ -32,6 +34,27 @@ func init() {
 	lang, _ := translator.GetTranslator("en")
 	en_translations.RegisterDefaultTranslations(validate, lang)
 
+	// Validate optional values by what they hold. An explicit null or an
+	// omitted field is treated like an empty value.
+	validate.RegisterCustomTypeFunc(func(v reflect.Value) interface{} {
+		if s := v.Interface().(null.String); s.Value != nil {
+			return *s.Value
+		}
+		return nil
+	}, null.String{})
+	validate.RegisterCustomTypeFunc(func(v reflect.Value) interface{} {
+		if i := v.Interface().(null.Int); i.Value != nil {
+			return *i.Value
+		}
+		return nil
+	}, null.Int{})
+	validate.RegisterCustomTypeFunc(func(v reflect.Value) interface{} {
+		if t := v.Interface().(null.Time); t.Value != nil {
+			return *t.Value
+		}
+		return nil
+	}, null.Time{})
+
 	// Use JSON tag names for errors instead of Go struct names.
 	validate.RegisterTagNameFunc(func(fld reflect.StructField) string {
 		name := strings.SplitN(fld.Tag.Get("json"), ",", 2)[0]
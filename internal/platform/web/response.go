@@ -12,6 +12,10 @@ func Respond(ctx context.Context, w http.ResponseWriter, val interface{}, status
 	v := ctx.Value(KeyValues).(*Values)
 	v.StatusCode = statusCode
 
+	if vv, ok := val.(Versioned); ok {
+		w.Header().Set("ETag", ETag(vv.CurrentVersion()))
+	}
+
 	if statusCode == http.StatusNoContent {
 		w.WriteHeader(statusCode)
 		return nil
@@ -0,0 +1,127 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Stream is a server-sent events response.
+//
+// HTTP/1 connections are hijacked so the stream is not cut by the server's
+// write timeout. Other connections are flushed through the ResponseWriter and
+// end at the write timeout; clients reconnect and resume with Last-Event-ID.
+type Stream struct {
+	w     io.Writer
+	flush func() error
+	close func()
+	done  <-chan struct{}
+}
+
+// NewStream starts a server-sent events response to r.
+func NewStream(w http.ResponseWriter, r *http.Request) (*Stream, error) {
+	if v, ok := r.Context().Value(KeyValues).(*Values); ok {
+		v.StatusCode = http.StatusOK
+	}
+
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("X-Accel-Buffering", "no")
+
+	if hj, ok := w.(http.Hijacker); ok && r.ProtoMajor == 1 {
+		return hijack(hj, h)
+	}
+
+	f, ok := w.(http.Flusher)
+	if !ok {
+		return nil, errors.New("streaming is not supported")
+	}
+
+	w.WriteHeader(http.StatusOK)
+	f.Flush()
+
+	return &Stream{
+		w:     w,
+		flush: func() error { f.Flush(); return nil },
+		close: func() {},
+		done:  r.Context().Done(),
+	}, nil
+}
+
+func hijack(hj http.Hijacker, h http.Header) (*Stream, error) {
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		return nil, errors.Wrap(err, "hijacking connection")
+	}
+
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		conn.Close()
+		return nil, errors.Wrap(err, "clearing connection deadline")
+	}
+
+	// The stream has no length, it ends when the connection closes.
+	h.Set("Connection", "close")
+	fmt.Fprint(rw, "HTTP/1.1 200 OK\r\n")
+	h.Write(rw)
+	fmt.Fprint(rw, "\r\n")
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, errors.Wrap(err, "writing stream headers")
+	}
+
+	// The client sends nothing more, a read only returns when it goes away.
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		io.Copy(ioutil.Discard, rw)
+		cancel()
+	}()
+
+	return &Stream{
+		w:     rw,
+		flush: rw.Flush,
+		close: func() { conn.Close() },
+		done:  ctx.Done(),
+	}, nil
+}
+
+// Send writes an event to the stream.
+func (s *Stream) Send(id, event string, data []byte) error {
+	if id != "" {
+		fmt.Fprintf(s.w, "id: %s\n", id)
+	}
+	if event != "" {
+		fmt.Fprintf(s.w, "event: %s\n", event)
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fmt.Fprintf(s.w, "data: %s\n", line)
+	}
+	if _, err := fmt.Fprint(s.w, "\n"); err != nil {
+		return err
+	}
+	return s.flush()
+}
+
+// Ping writes a comment to keep the connection open through idle proxies.
+func (s *Stream) Ping() error {
+	if _, err := fmt.Fprint(s.w, ": ping\n\n"); err != nil {
+		return err
+	}
+	return s.flush()
+}
+
+// Done is closed when the client goes away.
+func (s *Stream) Done() <-chan struct{} {
+	return s.done
+}
+
+// Close ends the stream.
+func (s *Stream) Close() {
+	s.close()
+}
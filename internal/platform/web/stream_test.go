@@ -0,0 +1,60 @@
+package web
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStream(t *testing.T) {
+	sent := make(chan struct{})
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = r.WithContext(context.WithValue(r.Context(), KeyValues, &Values{}))
+
+		s, err := NewStream(w, r)
+		if err != nil {
+			t.Errorf("starting stream: %v", err)
+			return
+		}
+		defer s.Close()
+
+		// Write after the server's write timeout passed.
+		time.Sleep(100 * time.Millisecond)
+		if err := s.Send("1", "task.moved", []byte("{\"a\":1}\n{}")); err != nil {
+			t.Errorf("sending: %v", err)
+		}
+		close(sent)
+		<-s.Done()
+	}))
+	srv.Config.WriteTimeout = 50 * time.Millisecond
+	srv.Start()
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	if ct := res.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("got content type %q", ct)
+	}
+
+	<-sent
+
+	var lines []string
+	sc := bufio.NewScanner(res.Body)
+	for len(lines) < 4 && sc.Scan() {
+		lines = append(lines, sc.Text())
+	}
+
+	want := "id: 1|event: task.moved|data: {\"a\":1}|data: {}"
+	if got := strings.Join(lines, "|"); got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
@@ -44,11 +44,13 @@ func NewApp(shutdown chan os.Signal, logger *log.Logger, mw ...Middleware) *App
 }
 
 // Handle associates a handler function with an HTTP Method and URL pattern.
+// The middleware of the route runs after the middleware of the app.
 //
 // It converts our custom handler type to the std lib Handler type. It captures
 // errors from the handler and serves them to the client in a uniform way.
-func (a *App) Handle(method, url string, h Handler) {
+func (a *App) Handle(method, url string, h Handler, mw ...Middleware) {
 
+	h = wrapMiddleware(mw, h)
 	h = wrapMiddleware(a.mw, h)
 
 	fn := func(w http.ResponseWriter, r *http.Request) {
@@ -73,6 +75,28 @@ func (a *App) Handle(method, url string, h Handler) {
 	a.mux.MethodFunc(method, url, fn)
 }
 
+// Group returns an App adding its routes to the routes of a. Its middleware
+// runs after the middleware of a, so routes can share a stack of their own,
+// such as authentication, while others stay public.
+func (a *App) Group(mw ...Middleware) *App {
+	stack := make([]Middleware, 0, len(a.mw)+len(mw))
+	stack = append(stack, a.mw...)
+	stack = append(stack, mw...)
+
+	return &App{
+		log:      a.log,
+		mux:      a.mux,
+		mw:       stack,
+		shutdown: a.shutdown,
+	}
+}
+
+// Mount serves every request below pattern with h, which can be another App
+// or any http.Handler. The middleware of a doesn't apply to it.
+func (a *App) Mount(pattern string, h http.Handler) {
+	a.mux.Mount(pattern, h)
+}
+
 func (a *App) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	a.mux.ServeHTTP(w, r)
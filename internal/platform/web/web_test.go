@@ -0,0 +1,78 @@
+package web
+
+import (
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+)
+
+// trace returns a middleware adding name to the X-Trace header of the
+// response, recording the order middleware runs in.
+func trace(name string) Middleware {
+	return func(after Handler) Handler {
+		return func(w http.ResponseWriter, r *http.Request) error {
+			w.Header().Add("X-Trace", name)
+			return after(w, r)
+		}
+	}
+}
+
+func ok(w http.ResponseWriter, r *http.Request) error {
+	return Respond(r.Context(), w, chi.URLParam(r, "id"), http.StatusOK)
+}
+
+func TestAppGroups(t *testing.T) {
+	app := NewApp(nil, log.New(ioutil.Discard, "", 0), trace("app"))
+	app.Handle(http.MethodGet, "/public", ok)
+	app.Handle(http.MethodGet, "/route", ok, trace("route"), trace("route2"))
+
+	private := app.Group(trace("group"))
+	private.Handle(http.MethodGet, "/private", ok, trace("route"))
+
+	nested := private.Group(trace("nested"))
+	nested.Handle(http.MethodGet, "/nested", ok)
+
+	sub := NewApp(nil, log.New(ioutil.Discard, "", 0), trace("sub"))
+	sub.Handle(http.MethodGet, "/items/{id}", ok)
+	app.Mount("/sub", sub)
+
+	tests := []struct {
+		path  string
+		trace string
+		body  string
+	}{
+		{"/public", "app", ""},
+		{"/route", "app,route,route2", ""},
+		{"/private", "app,group,route", ""},
+		{"/nested", "app,group,nested", ""},
+		{"/sub/items/7", "sub", `"7"`},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s: status %d, want %d", tt.path, rec.Code, http.StatusOK)
+			continue
+		}
+		if got := strings.Join(rec.Header()["X-Trace"], ","); got != tt.trace {
+			t.Errorf("%s: middleware ran %q, want %q", tt.path, got, tt.trace)
+		}
+		if tt.body != "" && strings.TrimSpace(rec.Body.String()) != tt.body {
+			t.Errorf("%s: body %s, want %s", tt.path, rec.Body, tt.body)
+		}
+	}
+
+	// Adding to a group doesn't change the stack of its parent.
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/public", nil))
+	if got := rec.Header()["X-Trace"]; len(got) != 1 {
+		t.Errorf("public route after grouping: middleware ran %v", got)
+	}
+}
@@ -0,0 +1,266 @@
+package project
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ivorscott/devpie-client-backend-go/internal/column"
+	"github.com/ivorscott/devpie-client-backend-go/internal/platform/database"
+)
+
+// Memory is a Store keeping projects in memory. It doesn't know about
+// project members, the creator of a project is its only member and owner.
+// Column orders are checked against the columns of a column store, but
+// creating or deleting a column there doesn't change them.
+type Memory struct {
+	mu       sync.Mutex
+	projects map[string]*Project
+	columns  column.Store
+}
+
+// NewMemory returns an empty Memory store whose projects hold the columns of
+// columns.
+func NewMemory(columns column.Store) *Memory {
+	return &Memory{projects: make(map[string]*Project), columns: columns}
+}
+
+func (s *Memory) Retrieve(ctx context.Context, pid, uid string) (*Project, error) {
+	if _, err := uuid.Parse(pid); err != nil {
+		return nil, ErrInvalidID
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.projects[pid]
+	if !ok || p.UserID != uid || p.Deleted != nil {
+		return nil, ErrNotFound
+	}
+
+	return clone(p), nil
+}
+
+func (s *Memory) List(ctx context.Context, uid string, archived bool, page database.Page) ([]Project, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var ps = make([]Project, 0)
+	for _, p := range s.projects {
+		if p.UserID == uid && p.Open == !archived && p.Deleted == nil {
+			ps = append(ps, *clone(p))
+		}
+	}
+
+	if err := page.Check(Keys); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(ps, func(i, j int) bool {
+		return database.Compare(Keys, ps[i].Key(), ps[j].Key()) < 0
+	})
+
+	if page.After != nil {
+		i := sort.Search(len(ps), func(i int) bool { return database.Compare(Keys, ps[i].Key(), page.After) > 0 })
+		ps = ps[i:]
+	}
+	if page.Limit > 0 && page.Limit < len(ps) {
+		ps = ps[:page.Limit]
+	}
+
+	return ps, nil
+}
+
+func (s *Memory) ListTrash(ctx context.Context, uid string) ([]Project, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var ps = make([]Project, 0)
+	for _, p := range s.projects {
+		if p.UserID == uid && p.Deleted != nil {
+			ps = append(ps, *clone(p))
+		}
+	}
+
+	sort.Slice(ps, func(i, j int) bool {
+		if !ps[i].Deleted.Equal(*ps[j].Deleted) {
+			return ps[i].Deleted.After(*ps[j].Deleted)
+		}
+		return ps[i].ID < ps[j].ID
+	})
+
+	return ps, nil
+}
+
+func (s *Memory) Create(ctx context.Context, np NewProject, uid string, now time.Time) (*Project, error) {
+	p := Project{
+		ID:          uuid.New().String(),
+		Name:        np.Name,
+		Open:        true,
+		UserID:      uid,
+		ColumnOrder: make([]string, 0),
+		Version:     1,
+		Created:     now.UTC(),
+	}
+
+	s.mu.Lock()
+	s.projects[p.ID] = clone(&p)
+	s.mu.Unlock()
+
+	return &p, nil
+}
+
+func (s *Memory) Update(ctx context.Context, pid string, update UpdateProject, uid string, versions database.Versions) (*Project, error) {
+	if _, err := s.Retrieve(ctx, pid, uid); err != nil {
+		return nil, err
+	}
+
+	if len(update.ColumnOrder) != 0 {
+		if err := s.checkColumnOrder(ctx, pid, update.ColumnOrder); err != nil {
+			return nil, err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, err := s.lock(pid, versions)
+	if err != nil {
+		return nil, err
+	}
+	before := *p
+
+	p.Name = update.Name
+	if len(update.ColumnOrder) != 0 {
+		p.ColumnOrder = append([]string(nil), update.ColumnOrder...)
+	}
+	p.Version++
+
+	return &before, nil
+}
+
+func (s *Memory) ReorderColumns(ctx context.Context, pid string, order []string, versions database.Versions) (*Project, error) {
+	if _, err := uuid.Parse(pid); err != nil {
+		return nil, ErrInvalidID
+	}
+
+	if err := s.checkColumnOrder(ctx, pid, order); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, err := s.lock(pid, versions)
+	if err != nil {
+		return nil, err
+	}
+	before := *p
+
+	p.ColumnOrder = append([]string(nil), order...)
+	p.Version++
+
+	return &before, nil
+}
+
+func (s *Memory) Archive(ctx context.Context, pid string, versions database.Versions) error {
+	if _, err := uuid.Parse(pid); err != nil {
+		return ErrInvalidID
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, err := s.lock(pid, versions)
+	if err != nil {
+		return err
+	}
+
+	p.Open = false
+	p.Version++
+
+	return nil
+}
+
+func (s *Memory) Restore(ctx context.Context, pid, uid string, versions database.Versions) error {
+	if _, err := uuid.Parse(pid); err != nil {
+		return ErrInvalidID
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.projects[pid]
+	if !ok || p.UserID != uid {
+		return ErrNotFound
+	}
+	if !versions.Match(p.Version) {
+		return ErrConflict
+	}
+
+	p.Open = true
+	p.Deleted = nil
+	p.Version++
+
+	return nil
+}
+
+func (s *Memory) Delete(ctx context.Context, pid string, versions database.Versions, now time.Time) error {
+	if _, err := uuid.Parse(pid); err != nil {
+		return ErrInvalidID
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, err := s.lock(pid, versions)
+	if err != nil {
+		return err
+	}
+
+	deleted := now.UTC()
+	p.Deleted = &deleted
+	p.Version++
+
+	return nil
+}
+
+// lock is the Memory version of lock. The caller holds s.mu.
+func (s *Memory) lock(pid string, versions database.Versions) (*Project, error) {
+	p, ok := s.projects[pid]
+	if !ok || p.Deleted != nil {
+		return nil, ErrNotFound
+	}
+	if !versions.Match(p.Version) {
+		return nil, ErrConflict
+	}
+	return p, nil
+}
+
+// checkColumnOrder is the Memory version of checkColumnOrder.
+func (s *Memory) checkColumnOrder(ctx context.Context, pid string, order []string) error {
+	cs, err := s.columns.List(ctx, pid)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, len(cs))
+	for i, c := range cs {
+		names[i] = c.ColumnName
+	}
+
+	if !samePermutation(names, order) {
+		return ErrInvalidColumnOrder
+	}
+
+	return nil
+}
+
+// clone copies a project so callers can't change the stored one.
+func clone(p *Project) *Project {
+	c := *p
+	c.ColumnOrder = append(make([]string, 0, len(p.ColumnOrder)), p.ColumnOrder...)
+	return &c
+}
@@ -4,21 +4,44 @@ import (
 	"time"
 )
 
+// Project is a board shared by its members. A project that is not Open is
+// archived, a project with a Deleted time is in the owner's trash.
 type Project struct {
-	ID          string    `db:"project_id" json:"id"`
-	UserID      string    `db:"user_id" json:"userId"`
-	Name        string    `db:"name" json:"name"`
-	Open        bool      `db:"open" json:"open"`
-	ColumnOrder []string  `db:"column_order" json:"columnOrder"`
-	Created     time.Time `db:"created" json:"created"`
+	ID          string     `db:"project_id" json:"id"`
+	UserID      string     `db:"user_id" json:"userId"`
+	Name        string     `db:"name" json:"name"`
+	Open        bool       `db:"open" json:"open"`
+	ColumnOrder []string   `db:"column_order" json:"columnOrder"`
+	Version     int        `db:"version" json:"version"`
+	Deleted     *time.Time `db:"deleted" json:"deleted"`
+	Created     time.Time  `db:"created" json:"created"`
 }
 
+// Key returns the values of the project for the Keys of listings.
+func (p Project) Key() []interface{} {
+	return []interface{}{p.Created, p.ID}
+}
+
+// CurrentVersion returns the version of the project, it changes whenever the
+// project or its column order changes.
+func (p Project) CurrentVersion() int {
+	return p.Version
+}
+
+// NewProject creates a project board from a template. The default template
+// is used when TemplateID is omitted.
 type NewProject struct {
-	Name string `db:"name" json:"name"`
+	Name       string  `db:"name" json:"name" validate:"required,max=36"`
+	TemplateID *string `db:"-" json:"templateId"`
 }
 
+// UpdateProject renames a project and optionally reorders its columns. A
+// project is only archived and restored by its owner, see Archive and Restore.
 type UpdateProject struct {
-	Name        string   `db:"name" json:"name"`
-	Open        bool     `db:"open" json:"open"`
+	Name        string   `db:"name" json:"name" validate:"required,max=36"`
 	ColumnOrder []string `db:"column_order" json:"columnOrder"`
 }
+
+type NewColumnOrder struct {
+	ColumnOrder []string `json:"columnOrder" validate:"required"`
+}
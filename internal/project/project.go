@@ -14,11 +14,13 @@ import (
 // The Project package shouldn't know anything about http
 // While it may identify common know errors, how to respond is left to the handlers
 var (
-	ErrNotFound         = errors.New("project not found")
-	ErrInvalidID        = errors.New("id provided was not a valid UUID")
-	ErrEmptyColumnOrder = errors.New("project column order provided was empty")
+	ErrNotFound           = errors.New("project not found")
+	ErrInvalidID          = errors.New("id provided was not a valid UUID")
+	ErrInvalidColumnOrder = errors.New("column order must list every column of the project exactly once")
+	ErrConflict           = errors.New("project changed since it was last read")
 )
 
+// Retrieve finds a Project the user is an accepted member of.
 func Retrieve(ctx context.Context, repo *database.Repository, pid string, uid string) (*Project, error) {
 	var p Project
 
@@ -27,23 +29,27 @@ func Retrieve(ctx context.Context, repo *database.Repository, pid string, uid st
 	}
 
 	stmt := repo.SQ.Select(
-		"project_id",
-		"name",
-		"open",
-		"user_id",
-		"column_order",
-		"created",
+		"p.project_id",
+		"p.name",
+		"p.open",
+		"p.user_id",
+		"p.column_order",
+		"p.version",
+		"p.deleted",
+		"p.created",
 	).From(
-		"projects",
-	).Where(sq.Eq{"project_id": "?", "user_id": "?"})
+		"projects p",
+	).Join(
+		"project_members m ON m.project_id = p.project_id",
+	).Where(sq.Eq{"p.project_id": pid, "m.user_id": uid, "m.accepted": true, "p.deleted": nil})
 
 	q, args, err := stmt.ToSql()
 	if err != nil {
 		return nil, errors.Wrapf(err, "building query: %v", args)
 	}
 
-	row := repo.DB.QueryRowContext(ctx, q, pid, uid)
-	err = row.Scan(&p.ID, &p.Name, &p.Open, &p.UserID, (*pq.StringArray)(&p.ColumnOrder), &p.Created)
+	row := repo.QueryRowContext(ctx, q, args...)
+	err = row.Scan(&p.ID, &p.Name, &p.Open, &p.UserID, (*pq.StringArray)(&p.ColumnOrder), &p.Version, &p.Deleted, &p.Created)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, ErrNotFound
@@ -54,36 +60,35 @@ func Retrieve(ctx context.Context, repo *database.Repository, pid string, uid st
 	return &p, nil
 }
 
-func List(ctx context.Context, repo *database.Repository, uid string) ([]Project, error) {
-	var p Project
-	var ps = make([]Project, 0)
+// Keys order the listings of projects, oldest first. A Project's values of
+// the keys are those of Key.
+var Keys = []database.Key{{Expr: "p.created"}, {Expr: "p.project_id"}}
 
-	stmt := repo.SQ.Select(
-		"project_id",
-		"name",
-		"open",
-		"user_id",
-		"column_order",
-		"created",
-	).From("projects").Where(sq.Eq{"user_id": "?"})
-	q, args, err := stmt.ToSql()
-	if err != nil {
-		return nil, errors.Wrapf(err, "building query: %v", args)
-	}
+// List returns the open Projects the user is an accepted member of, or the
+// archived ones.
+func List(ctx context.Context, repo *database.Repository, uid string, archived bool, page database.Page) ([]Project, error) {
+	where := sq.Eq{"m.user_id": uid, "m.accepted": true, "p.open": !archived, "p.deleted": nil}
 
-	rows, err := repo.DB.QueryContext(ctx, q, uid)
-	if err != nil {
-		return nil, errors.Wrap(err, "selecting projects")
-	}
-	for rows.Next() {
-		err = rows.Scan(&p.ID, &p.Name, &p.Open, &p.UserID, (*pq.StringArray)(&p.ColumnOrder), &p.Created)
-		if err != nil {
-			return nil, errors.Wrap(err, "scanning row into Struct")
-		}
-		ps = append(ps, p)
+	return list(ctx, repo, where, Keys, page)
+}
+
+// ListTrash returns the deleted Projects the user owns, most recently
+// deleted first.
+func ListTrash(ctx context.Context, repo *database.Repository, uid string) ([]Project, error) {
+	where := sq.And{
+		sq.Eq{"m.user_id": uid, "m.role": "owner"},
+		sq.NotEq{"p.deleted": nil},
 	}
 
-	return ps, nil
+	return list(ctx, repo, where, []database.Key{{Expr: "p.deleted", Desc: true}, {Expr: "p.project_id"}}, database.Page{})
+}
+
+// ListOwned returns every Project the user owns, archived and deleted ones
+// included.
+func ListOwned(ctx context.Context, repo *database.Repository, uid string) ([]Project, error) {
+	where := sq.Eq{"m.user_id": uid, "m.role": "owner"}
+
+	return list(ctx, repo, where, Keys, database.Page{})
 }
 
 // Create adds a new Project
@@ -93,7 +98,8 @@ func Create(ctx context.Context, repo *database.Repository, np NewProject, uid s
 		Name:        np.Name,
 		Open:        true,
 		UserID:      uid,
-		ColumnOrder: []string{"column-1", "column-2", "column-3", "column-4"},
+		ColumnOrder: make([]string, 0),
+		Version:     1,
 		Created:     now.UTC(),
 	}
 
@@ -116,51 +122,389 @@ func Create(ctx context.Context, repo *database.Repository, np NewProject, uid s
 }
 
 // Update modifies data about a Project. It will error if the specified ID is
-// invalid or does not reference an existing Project.
-func Update(ctx context.Context, repo *database.Repository, pid string, update UpdateProject, uid string) error {
-	p, err := Retrieve(ctx, repo, pid, uid)
+// invalid or does not reference an existing Project. The column order is only
+// changed when one is provided. Unless the stored version matches versions
+// ErrConflict is returned. It returns the project as it was before
+// the update.
+func Update(ctx context.Context, repo *database.Repository, pid string, update UpdateProject, uid string, versions database.Versions) (*Project, error) {
+	if _, err := Retrieve(ctx, repo, pid, uid); err != nil {
+		return nil, err
+	}
+
+	ctx, err := repo.Begin(ctx)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	defer repo.Rollback(ctx)
+
+	before, err := lock(ctx, repo, pid, versions)
+	if err != nil {
+		return nil, err
 	}
 
-	p.Name = update.Name
-	p.Open = update.Open
+	fields := map[string]interface{}{
+		"name":    update.Name,
+		"version": sq.Expr("version + 1"),
+	}
 
 	if len(update.ColumnOrder) != 0 {
-		return ErrEmptyColumnOrder
+		if err := checkColumnOrder(ctx, repo, pid, update.ColumnOrder); err != nil {
+			return nil, err
+		}
+		fields["column_order"] = pq.Array(update.ColumnOrder)
 	}
 
-	p.ColumnOrder = update.ColumnOrder
+	stmt := repo.SQ.Update(
+		"projects",
+	).SetMap(fields).Where(sq.Eq{"project_id": pid})
+
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return nil, errors.Wrap(err, "updating project")
+	}
+
+	if err := repo.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return before, nil
+}
+
+// ReorderColumns replaces the column order of a Project. The order must be a
+// permutation of the names of the project's columns. Unless the stored
+// version matches versions ErrConflict is returned. It returns the project
+// as it was before the reorder.
+func ReorderColumns(ctx context.Context, repo *database.Repository, pid string, order []string, versions database.Versions) (*Project, error) {
+	if _, err := uuid.Parse(pid); err != nil {
+		return nil, ErrInvalidID
+	}
+
+	ctx, err := repo.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer repo.Rollback(ctx)
+
+	before, err := lock(ctx, repo, pid, versions)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkColumnOrder(ctx, repo, pid, order); err != nil {
+		return nil, err
+	}
+
+	update := repo.SQ.Update(
+		"projects",
+	).SetMap(map[string]interface{}{
+		"column_order": pq.Array(order),
+		"version":      sq.Expr("version + 1"),
+	}).Where(sq.Eq{"project_id": pid})
+
+	if _, err := update.ExecContext(ctx); err != nil {
+		return nil, errors.Wrap(err, "updating column order")
+	}
+
+	if err := repo.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return before, nil
+}
+
+// Delete moves the Project identified by a given ID to its owner's trash.
+// Unless the stored version matches versions ErrConflict is returned.
+func Delete(ctx context.Context, repo *database.Repository, pid string, versions database.Versions, now time.Time) error {
+	if _, err := uuid.Parse(pid); err != nil {
+		return ErrInvalidID
+	}
+
+	ctx, err := repo.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer repo.Rollback(ctx)
+
+	if _, err := lock(ctx, repo, pid, versions); err != nil {
+		return err
+	}
 
 	stmt := repo.SQ.Update(
-		"project",
+		"projects",
 	).SetMap(map[string]interface{}{
-		"name":         p.Name,
-		"open":         p.Open,
-		"column_order": p.ColumnOrder,
-	}).Where(sq.Eq{"project_id": pid,"user_id": uid})
+		"deleted": now.UTC(),
+		"version": sq.Expr("version + 1"),
+	}).Where(sq.Eq{"project_id": pid})
 
-	_, err = stmt.ExecContext(ctx)
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return errors.Wrapf(err, "deleting project %s", pid)
+	}
+
+	return repo.Commit(ctx)
+}
+
+// Archive closes a Project, hiding it from the project listing. Unless the
+// stored version matches versions ErrConflict is returned.
+func Archive(ctx context.Context, repo *database.Repository, pid string, versions database.Versions) error {
+	if _, err := uuid.Parse(pid); err != nil {
+		return ErrInvalidID
+	}
+
+	ctx, err := repo.Begin(ctx)
 	if err != nil {
-		return errors.Wrap(err, "updating project")
+		return err
+	}
+	defer repo.Rollback(ctx)
+
+	if _, err := lock(ctx, repo, pid, versions); err != nil {
+		return err
+	}
+
+	stmt := repo.SQ.Update(
+		"projects",
+	).SetMap(map[string]interface{}{
+		"open":    false,
+		"version": sq.Expr("version + 1"),
+	}).Where(sq.Eq{"project_id": pid})
+
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return errors.Wrapf(err, "archiving project %s", pid)
+	}
+
+	return repo.Commit(ctx)
+}
+
+// Restore reopens an archived or deleted Project. Only its owner can
+// restore it. Unless the stored version matches versions ErrConflict is
+// returned.
+func Restore(ctx context.Context, repo *database.Repository, pid, uid string, versions database.Versions) error {
+	if _, err := uuid.Parse(pid); err != nil {
+		return ErrInvalidID
+	}
+
+	ctx, err := repo.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer repo.Rollback(ctx)
+
+	where := sq.And{
+		sq.Eq{"project_id": pid},
+		sq.Expr("EXISTS (SELECT 1 FROM project_members m WHERE m.project_id = projects.project_id AND m.user_id = ? AND m.role = 'owner')", uid),
+	}
+
+	q, args, err := repo.SQ.Select("version").From("projects").Where(where).Suffix("FOR UPDATE").ToSql()
+	if err != nil {
+		return errors.Wrapf(err, "building query: %v", args)
+	}
+
+	var version int
+	if err := repo.GetContext(ctx, &version, q, args...); err != nil {
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		return err
+	}
+	if !versions.Match(version) {
+		return ErrConflict
+	}
+
+	stmt := repo.SQ.Update(
+		"projects",
+	).SetMap(map[string]interface{}{
+		"open":    true,
+		"deleted": nil,
+		"version": sq.Expr("version + 1"),
+	}).Where(sq.Eq{"project_id": pid})
+
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return errors.Wrapf(err, "restoring project %s", pid)
+	}
+
+	return repo.Commit(ctx)
+}
+
+// Transfer records the user uid as the owner of the Project identified by
+// pid. The roles of its members are left to the member package.
+func Transfer(ctx context.Context, repo *database.Repository, pid, uid string) error {
+	if _, err := uuid.Parse(pid); err != nil {
+		return ErrInvalidID
+	}
+
+	stmt := repo.SQ.Update(
+		"projects",
+	).Set("user_id", uid).Where(sq.Eq{"project_id": pid})
+
+	res, err := stmt.ExecContext(ctx)
+	if err != nil {
+		return errors.Wrapf(err, "transferring project %s", pid)
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
 	}
 
 	return nil
 }
 
-// Delete removes the Project identified by a given ID.
-func Delete(ctx context.Context, repo *database.Repository, pid, uid string) error {
+// Expired returns the ids of the Projects deleted before a given time.
+func Expired(ctx context.Context, repo *database.Repository, before time.Time) ([]string, error) {
+	var ids []string
+
+	stmt := repo.SQ.Select("project_id").From("projects").Where(sq.Lt{"deleted": before.UTC()})
+
+	q, args, err := stmt.ToSql()
+	if err != nil {
+		return nil, errors.Wrapf(err, "building query: %v", args)
+	}
+
+	if err := repo.SelectContext(ctx, &ids, q, args...); err != nil {
+		return nil, errors.Wrap(err, "selecting expired projects")
+	}
+
+	return ids, nil
+}
+
+// Purge permanently removes the Project identified by a given ID. Everything
+// referencing the project must be removed first.
+func Purge(ctx context.Context, repo *database.Repository, pid string) error {
 	if _, err := uuid.Parse(pid); err != nil {
 		return ErrInvalidID
 	}
 
 	stmt := repo.SQ.Delete(
 		"projects",
-	).Where(sq.Eq{"project_id": pid, "user_id": uid})
+	).Where(sq.Eq{"project_id": pid})
 
 	if _, err := stmt.ExecContext(ctx); err != nil {
-		return errors.Wrapf(err, "deleting project %s", pid)
+		return errors.Wrapf(err, "purging project %s", pid)
+	}
+
+	return nil
+}
+
+// list selects the projects of the members matching where.
+func list(ctx context.Context, repo *database.Repository, where sq.Sqlizer, keys []database.Key, page database.Page) ([]Project, error) {
+	var p Project
+	var ps = make([]Project, 0)
+
+	stmt := repo.SQ.Select(
+		"p.project_id",
+		"p.name",
+		"p.open",
+		"p.user_id",
+		"p.column_order",
+		"p.version",
+		"p.deleted",
+		"p.created",
+	).From("projects p").Join(
+		"project_members m ON m.project_id = p.project_id",
+	).Where(where)
+
+	stmt, err := page.Apply(stmt, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	q, args, err := stmt.ToSql()
+	if err != nil {
+		return nil, errors.Wrapf(err, "building query: %v", args)
+	}
+
+	rows, err := repo.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "selecting projects")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		err = rows.Scan(&p.ID, &p.Name, &p.Open, &p.UserID, (*pq.StringArray)(&p.ColumnOrder), &p.Version, &p.Deleted, &p.Created)
+		if err != nil {
+			return nil, errors.Wrap(err, "scanning row into Struct")
+		}
+		ps = append(ps, p)
+	}
+
+	return ps, rows.Err()
+}
+
+// lock locks the project row for the rest of the unit of work and checks it
+// is at one of versions. It returns the locked project.
+func lock(ctx context.Context, repo *database.Repository, pid string, versions database.Versions) (*Project, error) {
+	var p Project
+
+	stmt := repo.SQ.Select(
+		"project_id",
+		"name",
+		"open",
+		"user_id",
+		"column_order",
+		"version",
+		"deleted",
+		"created",
+	).From(
+		"projects",
+	).Where(sq.Eq{"project_id": pid, "deleted": nil}).Suffix("FOR UPDATE")
+
+	q, args, err := stmt.ToSql()
+	if err != nil {
+		return nil, errors.Wrapf(err, "building query: %v", args)
+	}
+
+	row := repo.QueryRowContext(ctx, q, args...)
+	err = row.Scan(&p.ID, &p.Name, &p.Open, &p.UserID, (*pq.StringArray)(&p.ColumnOrder), &p.Version, &p.Deleted, &p.Created)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	if !versions.Match(p.Version) {
+		return nil, ErrConflict
+	}
+
+	return &p, nil
+}
+
+// checkColumnOrder verifies order is a permutation of the project's columns.
+func checkColumnOrder(ctx context.Context, repo *database.Repository, pid string, order []string) error {
+	var names []string
+
+	stmt := repo.SQ.Select("column_name").From("columns").Where(sq.Eq{"project_id": pid})
+
+	q, args, err := stmt.ToSql()
+	if err != nil {
+		return errors.Wrapf(err, "building query: %v", args)
+	}
+
+	if err := repo.SelectContext(ctx, &names, q, args...); err != nil {
+		return errors.Wrap(err, "selecting column names")
+	}
+
+	if !samePermutation(names, order) {
+		return ErrInvalidColumnOrder
 	}
 
 	return nil
 }
+
+// samePermutation reports whether order lists every name exactly once.
+func samePermutation(names, order []string) bool {
+	if len(names) != len(order) {
+		return false
+	}
+
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		seen[name] = false
+	}
+	for _, name := range order {
+		used, ok := seen[name]
+		if !ok || used {
+			return false
+		}
+		seen[name] = true
+	}
+
+	return true
+}
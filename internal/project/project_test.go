@@ -0,0 +1,57 @@
+package project
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSamePermutation(t *testing.T) {
+	names := []string{"column-1", "column-2", "column-3"}
+
+	tests := []struct {
+		order []string
+		want  bool
+	}{
+		{[]string{"column-3", "column-1", "column-2"}, true},
+		{[]string{"column-1", "column-2"}, false},
+		{[]string{"column-1", "column-1", "column-2"}, false},
+		{[]string{"column-1", "column-2", "column-4"}, false},
+	}
+
+	for _, tt := range tests {
+		if got := samePermutation(names, tt.order); got != tt.want {
+			t.Errorf("samePermutation(%v): got %v, want %v", tt.order, got, tt.want)
+		}
+	}
+}
+
+func TestKey(t *testing.T) {
+	p := Project{ID: "p1", Created: time.Unix(0, 0)}
+
+	if got, want := p.Key(), []interface{}{p.Created, "p1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if len(p.Key()) != len(Keys) {
+		t.Errorf("got %d values for %d keys", len(p.Key()), len(Keys))
+	}
+}
+
+func TestUpdateProjectIgnoresOpen(t *testing.T) {
+	// A project is only archived through Archive, a client sending the
+	// project back can't close it.
+	var up UpdateProject
+	if err := json.Unmarshal([]byte(`{"name":"Board","open":false}`), &up); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := json.Marshal(up)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), "open") {
+		t.Errorf("update carries open: %s", b)
+	}
+}
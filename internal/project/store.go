@@ -0,0 +1,68 @@
+package project
+
+import (
+	"context"
+	"time"
+
+	"github.com/ivorscott/devpie-client-backend-go/internal/platform/database"
+)
+
+// Store persists project boards. Postgres is the implementation used by the
+// API, Memory stands in for it in tests.
+type Store interface {
+	Retrieve(ctx context.Context, pid, uid string) (*Project, error)
+	List(ctx context.Context, uid string, archived bool, page database.Page) ([]Project, error)
+	ListTrash(ctx context.Context, uid string) ([]Project, error)
+	Create(ctx context.Context, np NewProject, uid string, now time.Time) (*Project, error)
+	Update(ctx context.Context, pid string, update UpdateProject, uid string, versions database.Versions) (*Project, error)
+	ReorderColumns(ctx context.Context, pid string, order []string, versions database.Versions) (*Project, error)
+	Archive(ctx context.Context, pid string, versions database.Versions) error
+	Restore(ctx context.Context, pid, uid string, versions database.Versions) error
+	Delete(ctx context.Context, pid string, versions database.Versions, now time.Time) error
+}
+
+// Postgres is the Store backed by the repository's database.
+type Postgres struct {
+	repo *database.Repository
+}
+
+// NewPostgres returns a Store keeping projects in the repository's database.
+func NewPostgres(repo *database.Repository) *Postgres {
+	return &Postgres{repo: repo}
+}
+
+func (s *Postgres) Retrieve(ctx context.Context, pid, uid string) (*Project, error) {
+	return Retrieve(ctx, s.repo, pid, uid)
+}
+
+func (s *Postgres) List(ctx context.Context, uid string, archived bool, page database.Page) ([]Project, error) {
+	return List(ctx, s.repo, uid, archived, page)
+}
+
+func (s *Postgres) ListTrash(ctx context.Context, uid string) ([]Project, error) {
+	return ListTrash(ctx, s.repo, uid)
+}
+
+func (s *Postgres) Create(ctx context.Context, np NewProject, uid string, now time.Time) (*Project, error) {
+	return Create(ctx, s.repo, np, uid, now)
+}
+
+func (s *Postgres) Update(ctx context.Context, pid string, update UpdateProject, uid string, versions database.Versions) (*Project, error) {
+	return Update(ctx, s.repo, pid, update, uid, versions)
+}
+
+func (s *Postgres) ReorderColumns(ctx context.Context, pid string, order []string, versions database.Versions) (*Project, error) {
+	return ReorderColumns(ctx, s.repo, pid, order, versions)
+}
+
+func (s *Postgres) Archive(ctx context.Context, pid string, versions database.Versions) error {
+	return Archive(ctx, s.repo, pid, versions)
+}
+
+func (s *Postgres) Restore(ctx context.Context, pid, uid string, versions database.Versions) error {
+	return Restore(ctx, s.repo, pid, uid, versions)
+}
+
+func (s *Postgres) Delete(ctx context.Context, pid string, versions database.Versions, now time.Time) error {
+	return Delete(ctx, s.repo, pid, versions, now)
+}
@@ -0,0 +1,88 @@
+// Package provision links the users of the API to their accounts of the
+// identity provider. Links wait in an outbox until the provider accepts
+// them, failed ones are retried with backoff.
+package provision
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/ivorscott/devpie-client-backend-go/internal/identity"
+	"github.com/ivorscott/devpie-client-backend-go/internal/user"
+	"github.com/pkg/errors"
+)
+
+// The wait after a failed link starts at MinBackoff and doubles with every
+// further failure, up to MaxBackoff.
+const (
+	MinBackoff = 30 * time.Second
+	MaxBackoff = time.Hour
+)
+
+// batch is how many links Pending attempts at most.
+const batch = 100
+
+// Run links the pending users every interval until ctx is done.
+func Run(ctx context.Context, users user.Store, provider identity.Provider, log *log.Logger, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		if err := Pending(ctx, users, provider, time.Now()); err != nil {
+			log.Printf("provision : %+v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// Pending attempts the links due at now. Every link is attempted, the error
+// of the last failure is returned.
+func Pending(ctx context.Context, users user.Store, provider identity.Provider, now time.Time) error {
+	links, err := users.PendingLinks(ctx, now, batch)
+	if err != nil {
+		return err
+	}
+
+	var failed error
+	for _, l := range links {
+		if err := Link(ctx, users, provider, l, now); err != nil {
+			failed = err
+		}
+	}
+
+	return failed
+}
+
+// Link syncs the user of l to the provider. On success the link leaves the
+// outbox, a failure schedules the next attempt.
+func Link(ctx context.Context, users user.Store, provider identity.Provider, l user.Link, now time.Time) error {
+	err := provider.SyncUser(ctx, l.Auth0ID, l.UserID)
+	if err == nil {
+		return users.Linked(ctx, l.UserID)
+	}
+
+	if err := users.LinkFailed(ctx, l.UserID, err.Error(), now.Add(Backoff(l.Attempts))); err != nil {
+		return err
+	}
+
+	return errors.Wrapf(err, "linking user %s", l.UserID)
+}
+
+// Backoff returns the wait before the next attempt of a link that failed
+// attempts times before.
+func Backoff(attempts int) time.Duration {
+	d := MinBackoff
+	for i := 0; i < attempts && d < MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > MaxBackoff {
+		d = MaxBackoff
+	}
+	return d
+}
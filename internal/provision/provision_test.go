@@ -0,0 +1,111 @@
+package provision
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/ivorscott/devpie-client-backend-go/internal/user"
+	"github.com/pkg/errors"
+)
+
+// provider syncs users until it is told to fail.
+type provider struct {
+	synced map[string]string
+	err    error
+}
+
+func (p *provider) Verify(ctx context.Context, token string) (*jwt.Token, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (p *provider) SyncUser(ctx context.Context, subject, userID string) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.synced[subject] = userID
+	return nil
+}
+
+func (p *provider) UnlinkUser(ctx context.Context, subject string) error {
+	if p.err != nil {
+		return p.err
+	}
+	delete(p.synced, subject)
+	return nil
+}
+
+func TestPending(t *testing.T) {
+	ctx := context.Background()
+	users := user.NewMemory()
+	p := &provider{synced: make(map[string]string), err: errors.New("provider unavailable")}
+	now := time.Now()
+
+	u, _, err := users.Provision(ctx, user.NewUser{Email: "jane@example.com"}, "auth0|jane", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Failed links wait longer after every attempt.
+	for i, wait := range []time.Duration{MinBackoff, 2 * MinBackoff} {
+		if err := Pending(ctx, users, p, now); errors.Cause(err) != p.err {
+			t.Fatalf("attempt %d: got %v, want the provider error", i+1, err)
+		}
+
+		links, err := users.PendingLinks(ctx, now.Add(time.Hour), batch)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(links) != 1 || links[0].Attempts != i+1 || !links[0].NextAttempt.Equal(now.Add(wait)) {
+			t.Fatalf("attempt %d: got %+v, want next attempt in %v", i+1, links, wait)
+		}
+		now = now.Add(wait)
+	}
+
+	// Links that aren't due are left alone.
+	p.err = nil
+	if err := Pending(ctx, users, p, now.Add(-time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.synced) != 0 {
+		t.Fatalf("synced before due: %v", p.synced)
+	}
+
+	if err := Pending(ctx, users, p, now); err != nil {
+		t.Fatal(err)
+	}
+	if p.synced["auth0|jane"] != u.ID {
+		t.Errorf("synced: got %v, want jane", p.synced)
+	}
+
+	got, err := users.RetrieveMeById(ctx, u.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.LinkStatus != user.LinkLinked {
+		t.Errorf("link status: got %q, want %q", got.LinkStatus, user.LinkLinked)
+	}
+	if links, _ := users.PendingLinks(ctx, now.Add(time.Hour), batch); len(links) != 0 {
+		t.Errorf("pending links: got %+v, want none", links)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, MinBackoff},
+		{1, time.Minute},
+		{3, 4 * time.Minute},
+		{7, MaxBackoff},
+		{100, MaxBackoff},
+	}
+
+	for _, tt := range tests {
+		if got := Backoff(tt.attempts); got != tt.want {
+			t.Errorf("Backoff(%d): got %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
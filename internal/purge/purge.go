@@ -0,0 +1,83 @@
+// Package purge permanently removes the projects and tasks that stayed in the
+// trash longer than the retention period.
+package purge
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/ivorscott/devpie-client-backend-go/internal/activity"
+	"github.com/ivorscott/devpie-client-backend-go/internal/column"
+	"github.com/ivorscott/devpie-client-backend-go/internal/label"
+	"github.com/ivorscott/devpie-client-backend-go/internal/member"
+	"github.com/ivorscott/devpie-client-backend-go/internal/platform/database"
+	"github.com/ivorscott/devpie-client-backend-go/internal/project"
+	"github.com/ivorscott/devpie-client-backend-go/internal/task"
+	"github.com/pkg/errors"
+)
+
+// Run purges the trash every interval until ctx is done.
+func Run(ctx context.Context, repo *database.Repository, log *log.Logger, retention, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		if err := Trash(ctx, repo, time.Now().Add(-retention)); err != nil {
+			log.Printf("purge : %+v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// Trash permanently removes the projects and tasks deleted before a given time.
+func Trash(ctx context.Context, repo *database.Repository, before time.Time) error {
+	pids, err := project.Expired(ctx, repo, before)
+	if err != nil {
+		return err
+	}
+
+	for _, pid := range pids {
+		if err := Project(ctx, repo, pid); err != nil {
+			return errors.Wrapf(err, "purging project %s", pid)
+		}
+	}
+
+	if _, err := task.Purge(ctx, repo, before); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// cascade removes the data of a project, rows before the rows they reference.
+var cascade = []func(context.Context, *database.Repository, string) error{
+	task.DeleteAll,
+	column.DeleteAll,
+	label.DeleteAll,
+	member.DeleteAll,
+	activity.DeleteAll,
+	project.Purge,
+}
+
+// Project permanently removes a project and everything in it. Nothing is
+// removed unless everything is.
+func Project(ctx context.Context, repo *database.Repository, pid string) error {
+	ctx, err := repo.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer repo.Rollback(ctx)
+
+	for _, del := range cascade {
+		if err := del(ctx, repo, pid); err != nil {
+			return err
+		}
+	}
+	return repo.Commit(ctx)
+}
@@ -0,0 +1,44 @@
+package purge
+
+import (
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestCascade(t *testing.T) {
+	// The package of each step of the cascade, in order.
+	var steps []string
+	for _, del := range cascade {
+		name := runtime.FuncForPC(reflect.ValueOf(del).Pointer()).Name()
+		name = name[strings.LastIndex(name, "/")+1:]
+		steps = append(steps, name[:strings.Index(name, ".")])
+	}
+
+	at := func(pkg string) int {
+		for i, s := range steps {
+			if s == pkg {
+				return i
+			}
+		}
+		t.Fatalf("%s is not purged: %v", pkg, steps)
+		return -1
+	}
+
+	// Tasks reference columns and, through their labels, labels. Everything
+	// references the project.
+	for _, ref := range [][2]string{
+		{"task", "column"},
+		{"task", "label"},
+		{"task", "project"},
+		{"column", "project"},
+		{"label", "project"},
+		{"member", "project"},
+		{"activity", "project"},
+	} {
+		if at(ref[0]) > at(ref[1]) {
+			t.Errorf("%s purged after %s: %v", ref[0], ref[1], steps)
+		}
+	}
+}
@@ -3,7 +3,6 @@ package schema
 import (
 	"errors"
 	"fmt"
-	"log"
 
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
@@ -21,15 +20,18 @@ var (
 
 const dest = "/migrations"
 
+// Migrate applies every pending migration to the database at url. A database
+// that is already up to date is not an error.
 func Migrate(dbname string, url string) error {
 	src := fmt.Sprintf("file://%s%s", RootDir(), dest)
 	m, err := migrate.New(src, url)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
+	defer m.Close()
 
-	if err := m.Up(); err != nil && err != ErrNoChange {
-		log.Fatal(err)
+	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
+		return err
 	}
 	return nil
 }
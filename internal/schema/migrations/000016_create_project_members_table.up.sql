@@ -0,0 +1,21 @@
+CREATE TABLE project_members (
+    member_id UUID PRIMARY KEY,
+    project_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    role varchar(16) NOT NULL,
+    accepted boolean NOT NULL DEFAULT false,
+    invited_by UUID,
+    created timestamp without time zone default (now() at time zone 'utc'),
+    UNIQUE (project_id, user_id),
+    CONSTRAINT fk_project
+        FOREIGN KEY(project_id)
+            REFERENCES projects(project_id),
+    CONSTRAINT fk_user
+        FOREIGN KEY(user_id)
+            REFERENCES users(user_id)
+);
+
+INSERT INTO project_members (member_id, project_id, user_id, role, accepted, created)
+SELECT md5(random()::text || project_id::text)::uuid, project_id, user_id, 'owner', true, created
+FROM projects
+WHERE user_id IS NOT NULL;
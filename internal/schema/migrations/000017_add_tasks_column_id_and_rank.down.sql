@@ -0,0 +1,15 @@
+ALTER TABLE columns ADD COLUMN task_ids text[];
+
+UPDATE columns c
+SET task_ids = ARRAY(
+    SELECT t.task_id::text FROM tasks t
+    WHERE t.column_id = c.column_id
+    ORDER BY t.rank, t.created
+);
+
+DROP INDEX IF EXISTS tasks_column_id_rank_idx;
+
+ALTER TABLE tasks
+DROP CONSTRAINT fk_column,
+DROP COLUMN rank,
+DROP COLUMN column_id;
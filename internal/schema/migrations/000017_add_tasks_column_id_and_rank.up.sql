@@ -0,0 +1,50 @@
+ALTER TABLE tasks
+ADD COLUMN column_id UUID,
+ADD COLUMN rank text COLLATE "C";
+
+-- Carry over the position of every task listed in a column's task_ids.
+UPDATE tasks t
+SET column_id = c.column_id,
+    rank = lpad(to_hex(u.pos::int), 6, '0')
+FROM columns c, unnest(c.task_ids) WITH ORDINALITY AS u(task_id, pos)
+WHERE u.task_id = t.task_id::text;
+
+-- Projects with tasks but no column get a column to hold them.
+WITH added AS (
+    INSERT INTO columns (column_id, project_id, title, column_name, created)
+    SELECT md5(random()::text || p.project_id::text)::uuid, p.project_id, 'To Do', 'column-1', now() at time zone 'utc'
+    FROM projects p
+    WHERE EXISTS (SELECT 1 FROM tasks t WHERE t.project_id = p.project_id AND t.column_id IS NULL)
+    AND NOT EXISTS (SELECT 1 FROM columns c WHERE c.project_id = p.project_id)
+    RETURNING project_id
+)
+UPDATE projects p
+SET column_order = ARRAY['column-1']
+FROM added a
+WHERE a.project_id = p.project_id;
+
+-- Tasks missing from every column go to the end of their project's first column.
+UPDATE tasks t
+SET column_id = (
+        SELECT c.column_id FROM columns c
+        JOIN projects p ON p.project_id = c.project_id
+        WHERE c.project_id = t.project_id
+        ORDER BY array_position(p.column_order, c.column_name::text), c.column_name
+        LIMIT 1
+    ),
+    rank = 'z'
+WHERE t.column_id IS NULL;
+
+-- Tasks of no project can't be placed on a board.
+DELETE FROM tasks WHERE column_id IS NULL AND project_id IS NULL;
+
+ALTER TABLE tasks
+ALTER COLUMN column_id SET NOT NULL,
+ALTER COLUMN rank SET NOT NULL,
+ADD CONSTRAINT fk_column
+FOREIGN KEY(column_id)
+REFERENCES columns(column_id);
+
+CREATE INDEX tasks_column_id_rank_idx ON tasks (column_id, rank);
+
+ALTER TABLE columns DROP COLUMN task_ids;
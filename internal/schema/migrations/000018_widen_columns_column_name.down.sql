@@ -0,0 +1,5 @@
+ALTER TABLE columns
+DROP CONSTRAINT columns_project_id_column_name_key;
+
+ALTER TABLE columns
+ALTER COLUMN column_name SET DATA TYPE varchar(8);
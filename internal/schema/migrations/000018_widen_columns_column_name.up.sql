@@ -0,0 +1,5 @@
+ALTER TABLE columns
+ALTER COLUMN column_name SET DATA TYPE varchar(36);
+
+ALTER TABLE columns
+ADD CONSTRAINT columns_project_id_column_name_key UNIQUE (project_id, column_name);
@@ -0,0 +1,10 @@
+CREATE TABLE templates (
+    template_id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    name varchar(64) NOT NULL,
+    columns jsonb NOT NULL,
+    created timestamp without time zone default (now() at time zone 'utc'),
+    CONSTRAINT fk_user
+        FOREIGN KEY(user_id)
+            REFERENCES users(user_id)
+);
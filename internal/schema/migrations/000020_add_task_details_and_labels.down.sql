@@ -0,0 +1,10 @@
+DROP TABLE IF EXISTS task_labels;
+
+DROP TABLE IF EXISTS labels;
+
+ALTER TABLE tasks
+DROP CONSTRAINT fk_assignee,
+DROP COLUMN estimate,
+DROP COLUMN priority,
+DROP COLUMN due_date,
+DROP COLUMN assignee_id;
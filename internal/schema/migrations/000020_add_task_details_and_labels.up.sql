@@ -0,0 +1,34 @@
+ALTER TABLE tasks
+ADD COLUMN assignee_id UUID,
+ADD COLUMN due_date timestamp without time zone,
+ADD COLUMN priority varchar(8),
+ADD COLUMN estimate integer,
+ADD CONSTRAINT fk_assignee
+FOREIGN KEY(assignee_id)
+REFERENCES users(user_id);
+
+CREATE TABLE labels (
+    label_id UUID PRIMARY KEY,
+    project_id UUID NOT NULL,
+    name varchar(36) NOT NULL,
+    color varchar(7) NOT NULL,
+    created timestamp without time zone default (now() at time zone 'utc'),
+    UNIQUE (project_id, name),
+    CONSTRAINT fk_project
+        FOREIGN KEY(project_id)
+            REFERENCES projects(project_id)
+);
+
+CREATE TABLE task_labels (
+    task_id UUID NOT NULL,
+    label_id UUID NOT NULL,
+    PRIMARY KEY (task_id, label_id),
+    CONSTRAINT fk_task
+        FOREIGN KEY(task_id)
+            REFERENCES tasks(task_id)
+            ON DELETE CASCADE,
+    CONSTRAINT fk_label
+        FOREIGN KEY(label_id)
+            REFERENCES labels(label_id)
+            ON DELETE CASCADE
+);
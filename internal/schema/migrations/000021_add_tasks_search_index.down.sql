@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS tasks_due_date_idx;
+
+DROP INDEX IF EXISTS tasks_assignee_id_idx;
+
+DROP INDEX IF EXISTS tasks_search_idx;
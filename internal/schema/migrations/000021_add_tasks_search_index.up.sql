@@ -0,0 +1,6 @@
+CREATE INDEX tasks_search_idx ON tasks
+USING GIN (to_tsvector('english', title || ' ' || coalesce(content, '')));
+
+CREATE INDEX tasks_assignee_id_idx ON tasks (assignee_id);
+
+CREATE INDEX tasks_due_date_idx ON tasks (due_date);
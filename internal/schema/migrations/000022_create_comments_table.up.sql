@@ -0,0 +1,23 @@
+CREATE TABLE comments (
+    comment_id UUID PRIMARY KEY,
+    task_id UUID NOT NULL,
+    parent_id UUID,
+    user_id UUID NOT NULL,
+    content text NOT NULL,
+    edited timestamp without time zone,
+    deleted timestamp without time zone,
+    created timestamp without time zone default (now() at time zone 'utc'),
+    CONSTRAINT fk_task
+        FOREIGN KEY(task_id)
+            REFERENCES tasks(task_id)
+            ON DELETE CASCADE,
+    CONSTRAINT fk_parent
+        FOREIGN KEY(parent_id)
+            REFERENCES comments(comment_id)
+            ON DELETE CASCADE,
+    CONSTRAINT fk_user
+        FOREIGN KEY(user_id)
+            REFERENCES users(user_id)
+);
+
+CREATE INDEX comments_task_id_idx ON comments (task_id, created);
@@ -0,0 +1,21 @@
+CREATE TABLE activity (
+    event_id UUID PRIMARY KEY,
+    project_id UUID NOT NULL,
+    task_id UUID,
+    column_id UUID,
+    user_id UUID NOT NULL,
+    action varchar(32) NOT NULL,
+    before jsonb NOT NULL DEFAULT '{}',
+    after jsonb NOT NULL DEFAULT '{}',
+    created timestamp without time zone default (now() at time zone 'utc'),
+    CONSTRAINT fk_project
+        FOREIGN KEY(project_id)
+            REFERENCES projects(project_id),
+    CONSTRAINT fk_user
+        FOREIGN KEY(user_id)
+            REFERENCES users(user_id)
+);
+
+CREATE INDEX activity_project_id_idx ON activity (project_id, created DESC);
+
+CREATE INDEX activity_task_id_idx ON activity (task_id, created DESC);
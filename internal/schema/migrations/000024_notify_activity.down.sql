@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS activity_notify ON activity;
+
+DROP FUNCTION IF EXISTS notify_activity();
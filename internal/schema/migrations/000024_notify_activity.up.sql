@@ -0,0 +1,10 @@
+CREATE OR REPLACE FUNCTION notify_activity() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('activity', json_build_object('id', NEW.event_id, 'projectId', NEW.project_id)::text);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER activity_notify
+AFTER INSERT ON activity
+FOR EACH ROW EXECUTE PROCEDURE notify_activity();
@@ -0,0 +1,8 @@
+ALTER TABLE tasks
+DROP COLUMN version;
+
+ALTER TABLE columns
+DROP COLUMN version;
+
+ALTER TABLE projects
+DROP COLUMN version;
@@ -0,0 +1,8 @@
+ALTER TABLE projects
+ADD COLUMN version integer NOT NULL DEFAULT 1;
+
+ALTER TABLE columns
+ADD COLUMN version integer NOT NULL DEFAULT 1;
+
+ALTER TABLE tasks
+ADD COLUMN version integer NOT NULL DEFAULT 1;
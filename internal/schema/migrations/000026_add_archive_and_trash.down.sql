@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS tasks_deleted_idx;
+
+DROP INDEX IF EXISTS projects_deleted_idx;
+
+ALTER TABLE tasks
+DROP COLUMN deleted,
+DROP COLUMN archived;
+
+ALTER TABLE projects
+DROP COLUMN deleted;
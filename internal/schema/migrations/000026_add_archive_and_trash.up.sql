@@ -0,0 +1,10 @@
+ALTER TABLE projects
+ADD COLUMN deleted timestamp without time zone;
+
+ALTER TABLE tasks
+ADD COLUMN archived timestamp without time zone,
+ADD COLUMN deleted timestamp without time zone;
+
+CREATE INDEX projects_deleted_idx ON projects (deleted) WHERE deleted IS NOT NULL;
+
+CREATE INDEX tasks_deleted_idx ON tasks (deleted) WHERE deleted IS NOT NULL;
@@ -0,0 +1,2 @@
+ALTER TABLE ma_token
+DROP COLUMN expires;
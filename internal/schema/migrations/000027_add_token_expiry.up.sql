@@ -0,0 +1,2 @@
+ALTER TABLE ma_token
+ADD COLUMN expires timestamp without time zone NOT NULL DEFAULT (now() at time zone 'utc');
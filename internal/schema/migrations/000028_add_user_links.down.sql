@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS user_links;
+
+ALTER TABLE users
+DROP COLUMN link_status;
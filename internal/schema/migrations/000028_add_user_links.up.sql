@@ -0,0 +1,17 @@
+ALTER TABLE users
+ADD COLUMN link_status varchar(16) NOT NULL DEFAULT 'linked';
+
+CREATE TABLE user_links (
+    user_id UUID PRIMARY KEY,
+    auth0_id varchar(128) NOT NULL,
+    attempts integer NOT NULL DEFAULT 0,
+    last_error text,
+    next_attempt timestamp without time zone NOT NULL,
+    created timestamp without time zone default (now() at time zone 'utc'),
+    CONSTRAINT fk_user
+        FOREIGN KEY(user_id)
+            REFERENCES users(user_id)
+            ON DELETE CASCADE
+);
+
+CREATE INDEX user_links_next_attempt_idx ON user_links (next_attempt);
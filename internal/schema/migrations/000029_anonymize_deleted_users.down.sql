@@ -0,0 +1,11 @@
+DELETE FROM comments
+WHERE user_id IS NULL;
+
+DELETE FROM activity
+WHERE user_id IS NULL;
+
+ALTER TABLE activity
+ALTER COLUMN user_id SET NOT NULL;
+
+ALTER TABLE comments
+ALTER COLUMN user_id SET NOT NULL;
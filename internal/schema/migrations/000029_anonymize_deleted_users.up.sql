@@ -0,0 +1,5 @@
+ALTER TABLE comments
+ALTER COLUMN user_id DROP NOT NULL;
+
+ALTER TABLE activity
+ALTER COLUMN user_id DROP NOT NULL;
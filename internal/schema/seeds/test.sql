@@ -0,0 +1,7 @@
+-- Users of the integration tests, see internal/tests.
+
+INSERT INTO users (user_id, auth0_id, email, email_verified, first_name, last_name) VALUES
+    ('5cf37266-3473-4006-984f-9325122678b7', 'auth0|5cf37266-3473-4006-984f-9325122678b7', 'owner@devpie.io', true, 'Olive', 'Owner'),
+    ('45b5fbd3-755f-4379-8f07-a58d4a30fa2f', 'auth0|45b5fbd3-755f-4379-8f07-a58d4a30fa2f', 'editor@devpie.io', true, 'Eddie', 'Editor'),
+    ('0b2a0d4e-8a43-4c51-a4ba-4a7f1f0f5d55', 'auth0|0b2a0d4e-8a43-4c51-a4ba-4a7f1f0f5d55', 'stranger@devpie.io', true, 'Sam', 'Stranger')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,393 @@
+package task
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ivorscott/devpie-client-backend-go/internal/platform/database"
+)
+
+// Memory is a Store keeping tasks in memory. It doesn't know about the
+// columns, members and labels of a project, so any column id is accepted and
+// assignees and labels aren't checked. Search matches substrings.
+type Memory struct {
+	mu    sync.Mutex
+	tasks map[string]*Task
+}
+
+// NewMemory returns an empty Memory store.
+func NewMemory() *Memory {
+	return &Memory{tasks: make(map[string]*Task)}
+}
+
+func (s *Memory) Retrieve(ctx context.Context, pid, tid string) (*Task, error) {
+	if _, err := uuid.Parse(tid); err != nil {
+		return nil, ErrInvalidID
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.tasks[tid]
+	if !ok || t.ProjectID != pid || t.Deleted != nil {
+		return nil, ErrNotFound
+	}
+
+	c := *t
+	return &c, nil
+}
+
+func (s *Memory) List(ctx context.Context, pid string, f Filter, page database.Page) ([]Task, error) {
+	keys, err := SortKeys(f.Sort)
+	if err != nil {
+		return nil, err
+	}
+	if err := page.Check(keys); err != nil {
+		return nil, err
+	}
+
+	for _, id := range []string{f.AssigneeID, f.LabelID, f.ColumnID} {
+		if _, err := uuid.Parse(id); id != "" && err != nil {
+			return nil, ErrInvalidFilter
+		}
+	}
+	switch f.Priority {
+	case "", PriorityLow, PriorityMedium, PriorityHigh, PriorityUrgent:
+	default:
+		return nil, ErrInvalidFilter
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := make([]Task, 0)
+	for _, t := range s.tasks {
+		if t.ProjectID != pid || t.Deleted != nil || (t.Archived != nil) != f.Archived {
+			continue
+		}
+		if f.AssigneeID != "" && (t.AssigneeID == nil || *t.AssigneeID != f.AssigneeID) {
+			continue
+		}
+		if f.LabelID != "" && !contains(t.LabelIDs, f.LabelID) {
+			continue
+		}
+		if f.ColumnID != "" && t.ColumnID != f.ColumnID {
+			continue
+		}
+		if f.Priority != "" && (t.Priority == nil || *t.Priority != f.Priority) {
+			continue
+		}
+		if f.DueBefore != nil && (t.DueDate == nil || !t.DueDate.Before(*f.DueBefore)) {
+			continue
+		}
+		if f.DueAfter != nil && (t.DueDate == nil || !t.DueDate.After(*f.DueAfter)) {
+			continue
+		}
+		if f.Search != "" && !matches(*t, f.Search) {
+			continue
+		}
+		list = append(list, *t)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return database.Compare(keys, list[i].Key(f.Sort), list[j].Key(f.Sort)) < 0
+	})
+
+	if page.After != nil {
+		i := sort.Search(len(list), func(i int) bool { return database.Compare(keys, list[i].Key(f.Sort), page.After) > 0 })
+		list = list[i:]
+	}
+	if page.Limit > 0 && page.Limit < len(list) {
+		list = list[:page.Limit]
+	}
+
+	return list, nil
+}
+
+func (s *Memory) ListTrash(ctx context.Context, pid string) ([]Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := make([]Task, 0)
+	for _, t := range s.tasks {
+		if t.ProjectID == pid && t.Deleted != nil {
+			list = append(list, *t)
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].Deleted.Equal(*list[j].Deleted) {
+			return list[i].Deleted.After(*list[j].Deleted)
+		}
+		return list[i].ID < list[j].ID
+	})
+
+	return list, nil
+}
+
+func (s *Memory) Create(ctx context.Context, nt NewTask, pid, cid string, now time.Time) (*Task, error) {
+	if _, err := uuid.Parse(cid); err != nil {
+		return nil, ErrInvalidID
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ps := s.positions(cid, "")
+	var last string
+	if len(ps) > 0 {
+		last = ps[len(ps)-1].Rank
+	}
+	rank, ok := Between(last, "")
+	if !ok {
+		ranks := Rebalance(len(ps) + 1)
+		for i, p := range ps {
+			s.tasks[p.ID].Rank = ranks[i]
+		}
+		rank = ranks[len(ps)]
+	}
+
+	t := Task{
+		ID:         uuid.New().String(),
+		Title:      nt.Title,
+		Content:    nt.Content,
+		ProjectID:  pid,
+		ColumnID:   cid,
+		Rank:       rank,
+		AssigneeID: nt.AssigneeID,
+		DueDate:    utc(nt.DueDate),
+		Priority:   nt.Priority,
+		Estimate:   nt.Estimate,
+		LabelIDs:   unique(nt.LabelIDs),
+		Version:    1,
+		Created:    now.UTC(),
+	}
+	s.tasks[t.ID] = &t
+
+	c := t
+	return &c, nil
+}
+
+func (s *Memory) Update(ctx context.Context, pid, tid string, ut UpdateTask, versions database.Versions) (*Task, error) {
+	if _, err := uuid.Parse(tid); err != nil {
+		return nil, ErrInvalidID
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, err := s.lock(pid, tid, versions)
+	if err != nil {
+		return nil, err
+	}
+	before := *t
+
+	if ut.Title != nil {
+		t.Title = *ut.Title
+	}
+	if ut.Content.Set {
+		t.Content = ut.Content.Value
+	}
+	if ut.AssigneeID.Set {
+		t.AssigneeID = ut.AssigneeID.Value
+	}
+	if ut.DueDate.Set {
+		t.DueDate = utc(ut.DueDate.Value)
+	}
+	if ut.Priority.Set {
+		t.Priority = ut.Priority.Value
+	}
+	if ut.Estimate.Set {
+		t.Estimate = ut.Estimate.Value
+	}
+	if ut.LabelIDs != nil {
+		t.LabelIDs = unique(*ut.LabelIDs)
+	}
+	t.Version++
+
+	return &before, nil
+}
+
+func (s *Memory) Move(ctx context.Context, pid, tid string, mt MoveTask, versions database.Versions) (*Task, error) {
+	for _, id := range []string{pid, tid, mt.From, mt.To} {
+		if _, err := uuid.Parse(id); err != nil {
+			return nil, ErrInvalidID
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.tasks[tid]
+	if !ok || t.ProjectID != pid || t.Deleted != nil {
+		return nil, ErrNotFound
+	}
+	if t.ColumnID != mt.From {
+		return nil, ErrOrderChanged
+	}
+	if !versions.Match(t.Version) {
+		return nil, ErrConflict
+	}
+	before := *t
+
+	dest := s.positions(mt.To, tid)
+
+	if mt.TaskIds != nil {
+		ids := make([]string, len(dest))
+		for i := range dest {
+			ids[i] = dest[i].ID
+		}
+		if !equal(mt.TaskIds, ids) {
+			return nil, ErrOrderChanged
+		}
+	}
+	if mt.Index > len(dest) {
+		return nil, ErrInvalidIndex
+	}
+
+	var prev, next string
+	if mt.Index > 0 {
+		prev = dest[mt.Index-1].Rank
+	}
+	if mt.Index < len(dest) {
+		next = dest[mt.Index].Rank
+	}
+
+	rank, ok := Between(prev, next)
+	if !ok {
+		ranks := Rebalance(len(dest) + 1)
+		rank = ranks[mt.Index]
+		for i, p := range dest {
+			r := ranks[i]
+			if i >= mt.Index {
+				r = ranks[i+1]
+			}
+			s.tasks[p.ID].Rank = r
+			s.tasks[p.ID].Version++
+		}
+	}
+
+	t.ColumnID = mt.To
+	t.Rank = rank
+	t.Version++
+
+	return &before, nil
+}
+
+func (s *Memory) Archive(ctx context.Context, pid, tid string, versions database.Versions, now time.Time) error {
+	if _, err := uuid.Parse(tid); err != nil {
+		return ErrInvalidID
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.tasks[tid]
+	if !ok || t.ProjectID != pid || t.Archived != nil || t.Deleted != nil {
+		return ErrNotFound
+	}
+	if !versions.Match(t.Version) {
+		return ErrConflict
+	}
+
+	archived := now.UTC()
+	t.Archived = &archived
+	t.Version++
+
+	return nil
+}
+
+func (s *Memory) Restore(ctx context.Context, pid, tid string, versions database.Versions) error {
+	if _, err := uuid.Parse(tid); err != nil {
+		return ErrInvalidID
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.tasks[tid]
+	if !ok || t.ProjectID != pid || (t.Archived == nil && t.Deleted == nil) {
+		return ErrNotFound
+	}
+	if !versions.Match(t.Version) {
+		return ErrConflict
+	}
+
+	t.Archived = nil
+	t.Deleted = nil
+	t.Version++
+
+	return nil
+}
+
+func (s *Memory) Delete(ctx context.Context, pid, tid string, versions database.Versions, now time.Time) (*Task, error) {
+	if _, err := uuid.Parse(tid); err != nil {
+		return nil, ErrInvalidID
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, err := s.lock(pid, tid, versions)
+	if err != nil {
+		return nil, err
+	}
+	before := *t
+
+	deleted := now.UTC()
+	t.Deleted = &deleted
+	t.Version++
+
+	return &before, nil
+}
+
+// lock is the Memory version of lock. The caller holds s.mu.
+func (s *Memory) lock(pid, tid string, versions database.Versions) (*Task, error) {
+	t, ok := s.tasks[tid]
+	if !ok || t.ProjectID != pid || t.Deleted != nil {
+		return nil, ErrNotFound
+	}
+	if !versions.Match(t.Version) {
+		return nil, ErrConflict
+	}
+	return t, nil
+}
+
+// positions is the Memory version of positions. The caller holds s.mu.
+func (s *Memory) positions(cid, skip string) []position {
+	var ps []position
+	var created = make(map[string]time.Time)
+
+	for _, t := range s.tasks {
+		if t.ColumnID != cid || t.ID == skip || t.Archived != nil || t.Deleted != nil {
+			continue
+		}
+		ps = append(ps, position{ID: t.ID, Rank: t.Rank})
+		created[t.ID] = t.Created
+	}
+
+	sort.Slice(ps, func(i, j int) bool {
+		if ps[i].Rank != ps[j].Rank {
+			return ps[i].Rank < ps[j].Rank
+		}
+		if !created[ps[i].ID].Equal(created[ps[j].ID]) {
+			return created[ps[i].ID].Before(created[ps[j].ID])
+		}
+		return ps[i].ID < ps[j].ID
+	})
+
+	return ps
+}
+
+// matches reports whether the title or content of t contains search.
+func matches(t Task, search string) bool {
+	text := t.Title
+	if t.Content != nil {
+		text += " " + *t.Content
+	}
+	return strings.Contains(strings.ToLower(text), strings.ToLower(search))
+}
@@ -2,28 +2,87 @@ package task
 
 import (
 	"time"
+
+	"github.com/ivorscott/devpie-client-backend-go/internal/platform/null"
+	"github.com/lib/pq"
+)
+
+// Task priorities from least to most pressing.
+const (
+	PriorityLow    = "low"
+	PriorityMedium = "medium"
+	PriorityHigh   = "high"
+	PriorityUrgent = "urgent"
 )
 
 type Task struct {
-	ID        string    `db:"task_id" json:"id"`
-	Title     string    `db:"title" json:"title"`
-	Content   *string   `db:"content" json:"content"`
-	ProjectID string    `db:"project_id" json:"projectId"`
-	Created   time.Time `db:"created" json:"created"`
+	ID         string         `db:"task_id" json:"id"`
+	Title      string         `db:"title" json:"title"`
+	Content    *string        `db:"content" json:"content"`
+	ProjectID  string         `db:"project_id" json:"projectId"`
+	ColumnID   string         `db:"column_id" json:"columnId"`
+	Rank       string         `db:"rank" json:"rank"`
+	AssigneeID *string        `db:"assignee_id" json:"assigneeId"`
+	DueDate    *time.Time     `db:"due_date" json:"dueDate"`
+	Priority   *string        `db:"priority" json:"priority"`
+	Estimate   *int           `db:"estimate" json:"estimate"`
+	LabelIDs   pq.StringArray `db:"label_ids" json:"labelIds"`
+	Version    int            `db:"version" json:"version"`
+	Archived   *time.Time     `db:"archived" json:"archived"`
+	Deleted    *time.Time     `db:"deleted" json:"deleted"`
+	Created    time.Time      `db:"created" json:"created"`
+}
+
+// CurrentVersion returns the version of the task, it changes whenever the
+// task is edited or moved.
+func (t Task) CurrentVersion() int {
+	return t.Version
 }
 
 type NewTask struct {
-	Title   string  `json:"title"`
-	Content *string `json:"content"`
+	Title      string     `json:"title" validate:"required,max=48"`
+	Content    *string    `json:"content"`
+	AssigneeID *string    `json:"assigneeId" validate:"omitempty,uuid"`
+	DueDate    *time.Time `json:"dueDate"`
+	Priority   *string    `json:"priority" validate:"omitempty,oneof=low medium high urgent"`
+	Estimate   *int       `json:"estimate" validate:"omitempty,min=0,max=100"`
+	LabelIDs   []string   `json:"labelIds" validate:"omitempty,dive,uuid"`
 }
 
+// UpdateTask only changes the fields present in the request. Nullable fields
+// are cleared with an explicit null and LabelIDs replaces the task's labels.
 type UpdateTask struct {
-	Title   *string `json:"title" validate:"required"`
-	Content *string `json:"content"`
+	Title      *string     `json:"title" validate:"omitempty,min=1,max=48"`
+	Content    null.String `json:"content"`
+	AssigneeID null.String `json:"assigneeId" validate:"omitempty,uuid"`
+	DueDate    null.Time   `json:"dueDate"`
+	Priority   null.String `json:"priority" validate:"omitempty,oneof=low medium high urgent"`
+	Estimate   null.Int    `json:"estimate" validate:"omitempty,min=0,max=100"`
+	LabelIDs   *[]string   `json:"labelIds" validate:"omitempty,dive,uuid"`
+}
+
+// Filter narrows and orders the tasks returned by List. Empty fields are
+// ignored. Search matches words in the title and content. Sort names one of
+// the sortable fields, prefixed with "-" for descending order. Archived lists
+// the archived tasks instead of the active ones.
+type Filter struct {
+	AssigneeID string
+	LabelID    string
+	Priority   string
+	ColumnID   string
+	DueBefore  *time.Time
+	DueAfter   *time.Time
+	Search     string
+	Sort       string
+	Archived   bool
 }
 
+// MoveTask moves a task to Index in the To column. TaskIds is optional and
+// holds the To column's order as the client last saw it, without the moved
+// task. When it no longer matches the stored order the move is rejected.
 type MoveTask struct {
-	To string	`json:"to"`
-	From string	`json:"from"`
+	To      string   `json:"to" validate:"required"`
+	From    string   `json:"from" validate:"required"`
+	Index   int      `json:"index" validate:"min=0"`
 	TaskIds []string `json:"taskIds"`
-}
\ No newline at end of file
+}
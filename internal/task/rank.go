@@ -0,0 +1,76 @@
+package task
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Ranks are base36 strings compared byte by byte, like fractions written
+// after a decimal point: "i" sorts between "" and "z", "ii" between "i" and "j".
+// This lets a task move by rewriting its own rank instead of the whole column.
+const digits = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+const base = len(digits)
+
+// rankWidth is the width of the evenly spaced ranks produced by Rebalance.
+const rankWidth = 6
+
+// Between returns a rank that sorts strictly after prev and strictly before
+// next. An empty prev means the start of the column and an empty next means
+// the end of it. The second result is false when there is no room between
+// prev and next and the column needs to be rebalanced.
+func Between(prev, next string) (string, bool) {
+	if next != "" && (prev >= next || strings.Trim(next, "0") == "") {
+		return "", false
+	}
+
+	var rank []byte
+	for i := 0; ; i++ {
+		lo := 0
+		if i < len(prev) {
+			lo = strings.IndexByte(digits, prev[i])
+		}
+		hi := base
+		if next != "" {
+			// The rank so far equals next, and next followed by zeros is
+			// worth next: nothing fits below it.
+			if i >= len(next) {
+				return "", false
+			}
+			hi = strings.IndexByte(digits, next[i])
+		}
+
+		if lo == hi {
+			rank = append(rank, digits[lo])
+			continue
+		}
+
+		if mid := (lo + hi) / 2; mid > lo {
+			return string(append(rank, digits[mid])), true
+		}
+
+		// The digits are adjacent, keep the lower one and look for room in the
+		// following position where next no longer constrains the rank.
+		rank = append(rank, digits[lo])
+		next = ""
+	}
+}
+
+// Rebalance returns n evenly spaced ranks in ascending order.
+func Rebalance(n int) []string {
+	ranks := make([]string, n)
+	step := 1
+	for i := 0; i < rankWidth-1; i++ {
+		step *= base
+	}
+	step = step * base / (n + 1)
+	if step == 0 {
+		step = 1
+	}
+
+	for i := range ranks {
+		r := strconv.FormatInt(int64((i+1)*step), base)
+		ranks[i] = strings.Repeat("0", rankWidth-len(r)) + r
+	}
+	return ranks
+}
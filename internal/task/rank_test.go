@@ -0,0 +1,76 @@
+package task
+
+import (
+	"testing"
+)
+
+func TestBetween(t *testing.T) {
+	tests := []struct {
+		prev, next string
+	}{
+		{"", ""},
+		{"", "i"},
+		{"i", ""},
+		{"i", "j"},
+		{"i", "i1"},
+		{"000001", "000002"},
+		{"z", ""},
+		{"zz", ""},
+		{"", "0001"},
+		{"", "000001"},
+		{"", "o00000"},
+	}
+
+	for _, tt := range tests {
+		r, ok := Between(tt.prev, tt.next)
+		if !ok {
+			t.Fatalf("Between(%q, %q) found no room", tt.prev, tt.next)
+		}
+		if r <= tt.prev || (tt.next != "" && r >= tt.next) {
+			t.Errorf("Between(%q, %q) = %q, want a rank in between", tt.prev, tt.next, r)
+		}
+	}
+}
+
+func TestBetweenNoRoom(t *testing.T) {
+	for _, tt := range [][2]string{{"i", "i"}, {"j", "i"}, {"", "00"}, {"c", "c00000"}} {
+		if r, ok := Between(tt[0], tt[1]); ok {
+			t.Errorf("Between(%q, %q) = %q, want no room", tt[0], tt[1], r)
+		}
+	}
+}
+
+func TestBetweenRepeatedInserts(t *testing.T) {
+	prev, next := "i", "j"
+
+	// Keep inserting right after the same rank, the worst case for growth.
+	for i := 0; i < 200; i++ {
+		r, ok := Between(prev, next)
+		if !ok {
+			t.Fatalf("insert %d: no room between %q and %q", i, prev, next)
+		}
+		if r <= prev || r >= next {
+			t.Fatalf("insert %d: Between(%q, %q) = %q, want a rank in between", i, prev, next, r)
+		}
+		next = r
+	}
+}
+
+func TestRebalance(t *testing.T) {
+	for _, n := range []int{0, 1, 4, 1000} {
+		ranks := Rebalance(n)
+		if len(ranks) != n {
+			t.Fatalf("Rebalance(%d) returned %d ranks", n, len(ranks))
+		}
+		for i := 1; i < len(ranks); i++ {
+			if ranks[i-1] >= ranks[i] {
+				t.Fatalf("Rebalance(%d): %q is not before %q", n, ranks[i-1], ranks[i])
+			}
+		}
+		if n > 0 {
+			if _, ok := Between("", ranks[0]); !ok {
+				t.Fatalf("Rebalance(%d) left no room before %q", n, ranks[0])
+			}
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package task
+
+import (
+	"context"
+	"time"
+
+	"github.com/ivorscott/devpie-client-backend-go/internal/platform/database"
+)
+
+// Store persists the tasks of project boards. Postgres is the implementation
+// used by the API, Memory stands in for it in tests.
+type Store interface {
+	Retrieve(ctx context.Context, pid, tid string) (*Task, error)
+	List(ctx context.Context, pid string, f Filter, page database.Page) ([]Task, error)
+	ListTrash(ctx context.Context, pid string) ([]Task, error)
+	Create(ctx context.Context, nt NewTask, pid, cid string, now time.Time) (*Task, error)
+	Update(ctx context.Context, pid, tid string, ut UpdateTask, versions database.Versions) (*Task, error)
+	Move(ctx context.Context, pid, tid string, mt MoveTask, versions database.Versions) (*Task, error)
+	Archive(ctx context.Context, pid, tid string, versions database.Versions, now time.Time) error
+	Restore(ctx context.Context, pid, tid string, versions database.Versions) error
+	Delete(ctx context.Context, pid, tid string, versions database.Versions, now time.Time) (*Task, error)
+}
+
+// Postgres is the Store backed by the repository's database.
+type Postgres struct {
+	repo *database.Repository
+}
+
+// NewPostgres returns a Store keeping tasks in the repository's database.
+func NewPostgres(repo *database.Repository) *Postgres {
+	return &Postgres{repo: repo}
+}
+
+func (s *Postgres) Retrieve(ctx context.Context, pid, tid string) (*Task, error) {
+	return Retrieve(ctx, s.repo, pid, tid)
+}
+
+func (s *Postgres) List(ctx context.Context, pid string, f Filter, page database.Page) ([]Task, error) {
+	return List(ctx, s.repo, pid, f, page)
+}
+
+func (s *Postgres) ListTrash(ctx context.Context, pid string) ([]Task, error) {
+	return ListTrash(ctx, s.repo, pid)
+}
+
+func (s *Postgres) Create(ctx context.Context, nt NewTask, pid, cid string, now time.Time) (*Task, error) {
+	return Create(ctx, s.repo, nt, pid, cid, now)
+}
+
+func (s *Postgres) Update(ctx context.Context, pid, tid string, ut UpdateTask, versions database.Versions) (*Task, error) {
+	return Update(ctx, s.repo, pid, tid, ut, versions)
+}
+
+func (s *Postgres) Move(ctx context.Context, pid, tid string, mt MoveTask, versions database.Versions) (*Task, error) {
+	return Move(ctx, s.repo, pid, tid, mt, versions)
+}
+
+func (s *Postgres) Archive(ctx context.Context, pid, tid string, versions database.Versions, now time.Time) error {
+	return Archive(ctx, s.repo, pid, tid, versions, now)
+}
+
+func (s *Postgres) Restore(ctx context.Context, pid, tid string, versions database.Versions) error {
+	return Restore(ctx, s.repo, pid, tid, versions)
+}
+
+func (s *Postgres) Delete(ctx context.Context, pid, tid string, versions database.Versions, now time.Time) (*Task, error) {
+	return Delete(ctx, s.repo, pid, tid, versions, now)
+}
@@ -7,17 +7,35 @@ import (
 	"github.com/google/uuid"
 	"github.com/ivorscott/devpie-client-backend-go/internal/platform/database"
 	"github.com/pkg/errors"
+	"strings"
 	"time"
 )
 
 // The Task package shouldn't know anything about http
 // While it may identify common know errors, how to respond is left to the handlers
 var (
-	ErrNotFound  = errors.New("task not found")
-	ErrInvalidID = errors.New("id provided was not a valid UUID")
+	ErrNotFound        = errors.New("task not found")
+	ErrInvalidID       = errors.New("id provided was not a valid UUID")
+	ErrColumnNotFound  = errors.New("column not found")
+	ErrInvalidIndex    = errors.New("index provided is out of range")
+	ErrOrderChanged    = errors.New("column order changed since it was last read")
+	ErrConflict        = errors.New("task changed since it was last read")
+	ErrInvalidAssignee = errors.New("assignee is not a member of the project")
+	ErrInvalidLabel    = errors.New("label does not belong to the project")
+	ErrInvalidFilter   = errors.New("filter provided is not valid")
+	ErrInvalidSort     = errors.New("tasks cannot be sorted by this field")
 )
 
-func Retrieve(ctx context.Context, repo *database.Repository, tid string) (*Task, error) {
+// labelIDs selects the ids of a task's labels.
+const labelIDs = "ARRAY(SELECT tl.label_id::text FROM task_labels tl WHERE tl.task_id = tasks.task_id) AS label_ids"
+
+// Retrieve finds the Task identified by tid within the project pid.
+func Retrieve(ctx context.Context, repo *database.Repository, pid, tid string) (*Task, error) {
+	return retrieve(ctx, repo, pid, tid, "")
+}
+
+// retrieve is Retrieve adding suffix to the query, e.g. to lock the task.
+func retrieve(ctx context.Context, repo *database.Repository, pid, tid, suffix string) (*Task, error) {
 	var t Task
 
 	if _, err := uuid.Parse(tid); err != nil {
@@ -29,17 +47,27 @@ func Retrieve(ctx context.Context, repo *database.Repository, tid string) (*Task
 		"title",
 		"content",
 		"project_id",
+		"column_id",
+		"rank",
+		"assignee_id",
+		"due_date",
+		"priority",
+		"estimate",
+		labelIDs,
+		"version",
+		"archived",
+		"deleted",
 		"created",
 	).From(
 		"tasks",
-	).Where(sq.Eq{"task_id": "?"})
+	).Where(sq.Eq{"task_id": tid, "project_id": pid, "deleted": nil}).Suffix(suffix)
 
 	q, args, err := stmt.ToSql()
 	if err != nil {
 		return nil, errors.Wrapf(err, "building query: %v", args)
 	}
 
-	if err := repo.DB.GetContext(ctx, &t, q, tid); err != nil {
+	if err := repo.GetContext(ctx, &t, q, args...); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, ErrNotFound
 		}
@@ -49,98 +77,427 @@ func Retrieve(ctx context.Context, repo *database.Repository, tid string) (*Task
 	return &t, nil
 }
 
-func List(ctx context.Context, repo *database.Repository, pid string) ([]Task, error) {
+// searchDocument is the text search document of a task. It must match the
+// expression of the tasks_search_idx index for the index to be used.
+const searchDocument = "to_tsvector('english', title || ' ' || coalesce(content, ''))"
+
+// sortColumns whitelists the fields tasks can be sorted by.
+var sortColumns = map[string]string{
+	"rank":     "rank",
+	"created":  "created",
+	"title":    "title",
+	"dueDate":  "due_date",
+	"priority": "array_position(ARRAY['low','medium','high','urgent']::varchar[], priority)",
+	"estimate": "estimate",
+}
+
+// List returns a page of the tasks of a project matching f. Without a sort
+// order they are ordered by column and rank.
+func List(ctx context.Context, repo *database.Repository, pid string, f Filter, page database.Page) ([]Task, error) {
 	var t = make([]Task, 0)
 
+	keys, err := SortKeys(f.Sort)
+	if err != nil {
+		return nil, err
+	}
+
 	stmt := repo.SQ.Select(
 		"task_id",
 		"title",
 		"content",
 		"project_id",
+		"column_id",
+		"rank",
+		"assignee_id",
+		"due_date",
+		"priority",
+		"estimate",
+		labelIDs,
+		"version",
+		"archived",
+		"deleted",
 		"created",
-	).From("tasks").Where(sq.Eq{"project_id": "?"})
+	).From("tasks").Where(sq.Eq{"project_id": pid, "deleted": nil})
+
+	if f.Archived {
+		stmt = stmt.Where(sq.NotEq{"archived": nil})
+	} else {
+		stmt = stmt.Where(sq.Eq{"archived": nil})
+	}
+
+	if f.AssigneeID != "" {
+		if _, err := uuid.Parse(f.AssigneeID); err != nil {
+			return nil, ErrInvalidFilter
+		}
+		stmt = stmt.Where(sq.Eq{"assignee_id": f.AssigneeID})
+	}
+	if f.LabelID != "" {
+		if _, err := uuid.Parse(f.LabelID); err != nil {
+			return nil, ErrInvalidFilter
+		}
+		stmt = stmt.Where("EXISTS (SELECT 1 FROM task_labels tl WHERE tl.task_id = tasks.task_id AND tl.label_id = ?)", f.LabelID)
+	}
+	if f.ColumnID != "" {
+		if _, err := uuid.Parse(f.ColumnID); err != nil {
+			return nil, ErrInvalidFilter
+		}
+		stmt = stmt.Where(sq.Eq{"column_id": f.ColumnID})
+	}
+	if f.Priority != "" {
+		switch f.Priority {
+		case PriorityLow, PriorityMedium, PriorityHigh, PriorityUrgent:
+			stmt = stmt.Where(sq.Eq{"priority": f.Priority})
+		default:
+			return nil, ErrInvalidFilter
+		}
+	}
+	if f.DueBefore != nil {
+		stmt = stmt.Where(sq.Lt{"due_date": f.DueBefore.UTC()})
+	}
+	if f.DueAfter != nil {
+		stmt = stmt.Where(sq.Gt{"due_date": f.DueAfter.UTC()})
+	}
+	if f.Search != "" {
+		stmt = stmt.Where(searchDocument+" @@ plainto_tsquery('english', ?)", f.Search)
+	}
+	if stmt, err = page.Apply(stmt, keys); err != nil {
+		return nil, err
+	}
+
 	q, args, err := stmt.ToSql()
 	if err != nil {
 		return nil, errors.Wrapf(err, "building query: %v", args)
 	}
 
-	if err := repo.DB.SelectContext(ctx, &t, q, pid); err != nil {
+	if err := repo.SelectContext(ctx, &t, q, args...); err != nil {
 		return nil, errors.Wrap(err, "selecting tasks")
 	}
 
 	return t, nil
 }
 
-// Create adds a new Task
-func Create(ctx context.Context, repo *database.Repository, nt NewTask, pid string, now time.Time) (*Task, error) {
+// Create adds a new Task at the end of the column cid.
+func Create(ctx context.Context, repo *database.Repository, nt NewTask, pid, cid string, now time.Time) (*Task, error) {
+	if _, err := uuid.Parse(cid); err != nil {
+		return nil, ErrInvalidID
+	}
+
+	ctx, err := repo.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer repo.Rollback(ctx)
+
+	locked, err := lockColumns(ctx, repo, pid, cid)
+	if err != nil {
+		return nil, err
+	}
+	if len(locked) == 0 {
+		return nil, ErrColumnNotFound
+	}
+
+	if err := checkAssignee(ctx, repo, pid, nt.AssigneeID); err != nil {
+		return nil, err
+	}
+
+	ps, err := positions(ctx, repo, cid, "")
+	if err != nil {
+		return nil, err
+	}
+
+	last := ""
+	if len(ps) > 0 {
+		last = ps[len(ps)-1].Rank
+	}
+	rank, _ := Between(last, "")
 
 	t := Task{
-		ID:        uuid.New().String(),
-		Title:     nt.Title,
-		Content:   nt.Content,
-		ProjectID: pid,
-		Created:   now.UTC(),
+		ID:         uuid.New().String(),
+		Title:      nt.Title,
+		Content:    nt.Content,
+		ProjectID:  pid,
+		ColumnID:   cid,
+		Rank:       rank,
+		AssigneeID: nt.AssigneeID,
+		DueDate:    utc(nt.DueDate),
+		Priority:   nt.Priority,
+		Estimate:   nt.Estimate,
+		LabelIDs:   make([]string, 0),
+		Version:    1,
+		Created:    now.UTC(),
 	}
 
 	stmt := repo.SQ.Insert(
 		"tasks",
 	).SetMap(map[string]interface{}{
-		"task_id":    t.ID,
-		"title":      t.Title,
-		"content":    t.Content,
-		"project_id": t.ProjectID,
-		"created":    now.UTC(),
+		"task_id":     t.ID,
+		"title":       t.Title,
+		"content":     t.Content,
+		"project_id":  t.ProjectID,
+		"column_id":   t.ColumnID,
+		"rank":        t.Rank,
+		"assignee_id": t.AssigneeID,
+		"due_date":    t.DueDate,
+		"priority":    t.Priority,
+		"estimate":    t.Estimate,
+		"created":     now.UTC(),
 	})
 
 	if _, err := stmt.ExecContext(ctx); err != nil {
 		return nil, errors.Wrapf(err, "inserting tasks: %v", nt)
 	}
 
+	if len(nt.LabelIDs) > 0 {
+		if err := setLabels(ctx, repo, pid, t.ID, nt.LabelIDs); err != nil {
+			return nil, err
+		}
+		t.LabelIDs = nt.LabelIDs
+	}
+
+	if err := repo.Commit(ctx); err != nil {
+		return nil, errors.Wrap(err, "committing task")
+	}
+
 	return &t, nil
 }
 
-// Update modifies data about a Task. It will error if the specified ID is
-// invalid or does not reference an existing Task.
-func Update(ctx context.Context, repo *database.Repository, pid, tid string, ut UpdateTask) error {
-	t, err := Retrieve(ctx, repo, tid)
+// Update modifies data about a Task. Only the fields present in ut are
+// changed. It will error if the specified ID is invalid or does not reference
+// an existing Task. Unless the stored version matches versions ErrConflict is
+// returned. It returns the task as it was before the update.
+func Update(ctx context.Context, repo *database.Repository, pid, tid string, ut UpdateTask, versions database.Versions) (*Task, error) {
+	if _, err := uuid.Parse(tid); err != nil {
+		return nil, ErrInvalidID
+	}
+
+	ctx, err := repo.Begin(ctx)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	defer repo.Rollback(ctx)
+
+	before, err := lock(ctx, repo, pid, tid, versions)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := map[string]interface{}{
+		"version": sq.Expr("version + 1"),
+	}
+	if ut.Title != nil {
+		fields["title"] = *ut.Title
+	}
+	if ut.Content.Set {
+		fields["content"] = ut.Content.Value
+	}
+	if ut.AssigneeID.Set {
+		if err := checkAssignee(ctx, repo, pid, ut.AssigneeID.Value); err != nil {
+			return nil, err
+		}
+		fields["assignee_id"] = ut.AssigneeID.Value
+	}
+	if ut.DueDate.Set {
+		fields["due_date"] = utc(ut.DueDate.Value)
+	}
+	if ut.Priority.Set {
+		fields["priority"] = ut.Priority.Value
+	}
+	if ut.Estimate.Set {
+		fields["estimate"] = ut.Estimate.Value
+	}
+
+	stmt := repo.SQ.Update(
+		"tasks",
+	).SetMap(fields).Where(sq.Eq{"task_id": tid, "project_id": pid})
+
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return nil, errors.Wrap(err, "updating task")
+	}
+
+	if ut.LabelIDs != nil {
+		if err := setLabels(ctx, repo, pid, tid, *ut.LabelIDs); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := repo.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return before, nil
+}
+
+// Delete moves the task identified by a given ID to the trash. A non zero
+// version must match the stored version or ErrConflict is returned. It
+// returns the task as it was before.
+func Delete(ctx context.Context, repo *database.Repository, pid, tid string, versions database.Versions, now time.Time) (*Task, error) {
+	if _, err := uuid.Parse(tid); err != nil {
+		return nil, ErrInvalidID
 	}
 
-	t.Title = *ut.Title
-	t.Content = ut.Content
+	ctx, err := repo.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer repo.Rollback(ctx)
+
+	before, err := lock(ctx, repo, pid, tid, versions)
+	if err != nil {
+		return nil, err
+	}
 
 	stmt := repo.SQ.Update(
 		"tasks",
 	).SetMap(map[string]interface{}{
-		"title":   t.Title,
-		"content": t.Content,
+		"deleted": now.UTC(),
+		"version": sq.Expr("version + 1"),
 	}).Where(sq.Eq{"task_id": tid, "project_id": pid})
 
-	_, err = stmt.ExecContext(ctx)
-	if err != nil {
-		return errors.Wrap(err, "updating task")
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return nil, errors.Wrapf(err, "deleting task %s", tid)
 	}
 
-	return nil
+	if err := repo.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return before, nil
 }
 
-// Delete removes the task identified by a given ID.
-func Delete(ctx context.Context, repo *database.Repository, pid, tid string) error {
+// Archive hides a task from the board and the task listing without deleting
+// it. Unless the stored version matches versions ErrConflict is returned.
+func Archive(ctx context.Context, repo *database.Repository, pid, tid string, versions database.Versions, now time.Time) error {
+	if _, err := uuid.Parse(tid); err != nil {
+		return ErrInvalidID
+	}
+
+	ctx, err := repo.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer repo.Rollback(ctx)
+
+	t, err := retrieve(ctx, repo, pid, tid, "FOR UPDATE")
+	if err != nil {
+		return err
+	}
+	if t.Archived != nil {
+		return ErrNotFound
+	}
+	if !versions.Match(t.Version) {
+		return ErrConflict
+	}
+
+	stmt := repo.SQ.Update(
+		"tasks",
+	).SetMap(map[string]interface{}{
+		"archived": now.UTC(),
+		"version":  sq.Expr("version + 1"),
+	}).Where(sq.Eq{"task_id": tid, "project_id": pid})
+
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return errors.Wrapf(err, "archiving task %s", tid)
+	}
+
+	return repo.Commit(ctx)
+}
 
+// Restore brings an archived or deleted task back to the board. Unless the
+// stored version matches versions ErrConflict is returned.
+func Restore(ctx context.Context, repo *database.Repository, pid, tid string, versions database.Versions) error {
 	if _, err := uuid.Parse(tid); err != nil {
 		return ErrInvalidID
 	}
 
-	stmt := repo.SQ.Delete(
+	ctx, err := repo.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer repo.Rollback(ctx)
+
+	where := sq.And{
+		sq.Eq{"task_id": tid, "project_id": pid},
+		sq.Or{sq.NotEq{"archived": nil}, sq.NotEq{"deleted": nil}},
+	}
+
+	q, args, err := repo.SQ.Select("version").From("tasks").Where(where).Suffix("FOR UPDATE").ToSql()
+	if err != nil {
+		return errors.Wrapf(err, "building query: %v", args)
+	}
+
+	var version int
+	if err := repo.GetContext(ctx, &version, q, args...); err != nil {
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		return err
+	}
+	if !versions.Match(version) {
+		return ErrConflict
+	}
+
+	stmt := repo.SQ.Update(
 		"tasks",
-	).Where(sq.Eq{"task_id": tid, "project_id": pid})
+	).SetMap(map[string]interface{}{
+		"archived": nil,
+		"deleted":  nil,
+		"version":  sq.Expr("version + 1"),
+	}).Where(sq.Eq{"task_id": tid, "project_id": pid})
 
 	if _, err := stmt.ExecContext(ctx); err != nil {
-		return errors.Wrapf(err, "deleting task %s", tid)
+		return errors.Wrapf(err, "restoring task %s", tid)
 	}
 
-	return nil
+	return repo.Commit(ctx)
+}
+
+// ListTrash returns the deleted tasks of a project, most recently deleted first.
+func ListTrash(ctx context.Context, repo *database.Repository, pid string) ([]Task, error) {
+	var t = make([]Task, 0)
+
+	stmt := repo.SQ.Select(
+		"task_id",
+		"title",
+		"content",
+		"project_id",
+		"column_id",
+		"rank",
+		"assignee_id",
+		"due_date",
+		"priority",
+		"estimate",
+		labelIDs,
+		"version",
+		"archived",
+		"deleted",
+		"created",
+	).From("tasks").Where(sq.And{
+		sq.Eq{"project_id": pid},
+		sq.NotEq{"deleted": nil},
+	}).OrderBy("deleted DESC", "task_id")
+
+	q, args, err := stmt.ToSql()
+	if err != nil {
+		return nil, errors.Wrapf(err, "building query: %v", args)
+	}
+
+	if err := repo.SelectContext(ctx, &t, q, args...); err != nil {
+		return nil, errors.Wrap(err, "selecting deleted tasks")
+	}
+
+	return t, nil
+}
+
+// Purge permanently removes the tasks deleted before a given time.
+func Purge(ctx context.Context, repo *database.Repository, before time.Time) (int64, error) {
+	stmt := repo.SQ.Delete(
+		"tasks",
+	).Where(sq.Lt{"deleted": before.UTC()})
+
+	res, err := stmt.ExecContext(ctx)
+	if err != nil {
+		return 0, errors.Wrap(err, "purging tasks")
+	}
+
+	return res.RowsAffected()
 }
 
 // Delete removes all tasks identified by pid
@@ -160,3 +517,403 @@ func DeleteAll(ctx context.Context, repo *database.Repository, pid string) error
 
 	return nil
 }
+
+// Unassign removes the user uid as assignee of every task, changing their
+// versions.
+func Unassign(ctx context.Context, repo *database.Repository, uid string) error {
+	stmt := repo.SQ.Update(
+		"tasks",
+	).SetMap(map[string]interface{}{
+		"assignee_id": nil,
+		"version":     sq.Expr("version + 1"),
+	}).Where(sq.Eq{"assignee_id": uid})
+
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return errors.Wrapf(err, "unassigning user %s", uid)
+	}
+
+	return nil
+}
+
+// UnassignMember removes the user uid as assignee of the tasks of project pid,
+// changing their versions.
+func UnassignMember(ctx context.Context, repo *database.Repository, pid, uid string) error {
+	stmt := repo.SQ.Update(
+		"tasks",
+	).SetMap(map[string]interface{}{
+		"assignee_id": nil,
+		"version":     sq.Expr("version + 1"),
+	}).Where(sq.Eq{"project_id": pid, "assignee_id": uid})
+
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return errors.Wrapf(err, "unassigning member %s of project %s", uid, pid)
+	}
+
+	return nil
+}
+
+// Move places a task at mt.Index of the destination column by giving it a
+// rank between its new neighbours. Both columns and the task are locked for
+// the duration of a single transaction so concurrent moves on the same board
+// are applied one after the other. Unless the stored version of the task
+// matches versions ErrConflict is returned. It returns the task as it
+// was before the move.
+func Move(ctx context.Context, repo *database.Repository, pid, tid string, mt MoveTask, versions database.Versions) (*Task, error) {
+	for _, id := range []string{pid, tid, mt.From, mt.To} {
+		if _, err := uuid.Parse(id); err != nil {
+			return nil, ErrInvalidID
+		}
+	}
+
+	ctx, err := repo.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer repo.Rollback(ctx)
+
+	locked, err := lockColumns(ctx, repo, pid, mt.From, mt.To)
+	if err != nil {
+		return nil, err
+	}
+	if !contains(locked, mt.From) || !contains(locked, mt.To) {
+		return nil, ErrColumnNotFound
+	}
+
+	before, err := lock(ctx, repo, pid, tid, versions)
+	if err != nil {
+		return nil, err
+	}
+
+	dest, err := positions(ctx, repo, mt.To, tid)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkMove(before.ColumnID, dest, mt); err != nil {
+		return nil, err
+	}
+
+	var prev, next string
+	if mt.Index > 0 {
+		prev = dest[mt.Index-1].Rank
+	}
+	if mt.Index < len(dest) {
+		next = dest[mt.Index].Rank
+	}
+
+	rank, ok := Between(prev, next)
+	if !ok {
+		// Neighbours share a rank, spread the whole column out again.
+		ranks := Rebalance(len(dest) + 1)
+		rank = ranks[mt.Index]
+		for i, p := range dest {
+			r := ranks[i]
+			if i >= mt.Index {
+				r = ranks[i+1]
+			}
+			if err := setRank(ctx, repo, p.ID, mt.To, r); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := setRank(ctx, repo, tid, mt.To, rank); err != nil {
+		return nil, err
+	}
+
+	if err := repo.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return before, nil
+}
+
+// position is where a task sits in its column.
+type position struct {
+	ID   string `db:"task_id"`
+	Rank string `db:"rank"`
+}
+
+// checkMove verifies a move of a task in the column cid fits the board: dest
+// are the positions of the other tasks of the To column. A client whose view
+// of the task's column, or of the To column when it sent mt.TaskIds, is stale
+// gets ErrOrderChanged.
+func checkMove(cid string, dest []position, mt MoveTask) error {
+	// The task left the column the client thinks it's in.
+	if cid != mt.From {
+		return ErrOrderChanged
+	}
+
+	if mt.TaskIds != nil {
+		ids := make([]string, len(dest))
+		for i := range dest {
+			ids[i] = dest[i].ID
+		}
+		if !equal(mt.TaskIds, ids) {
+			return ErrOrderChanged
+		}
+	}
+	if mt.Index > len(dest) {
+		return ErrInvalidIndex
+	}
+
+	return nil
+}
+
+// lockColumns locks the given columns of a project for update and returns the
+// ids of the ones that exist. Rows are locked in a stable order so two
+// transactions touching the same columns can't deadlock.
+func lockColumns(ctx context.Context, repo *database.Repository, pid string, cids ...string) ([]string, error) {
+	var locked []string
+
+	stmt := repo.SQ.Select(
+		"column_id",
+	).From(
+		"columns",
+	).Where(sq.Eq{"project_id": pid, "column_id": cids}).OrderBy("column_id").Suffix("FOR UPDATE")
+
+	q, args, err := stmt.ToSql()
+	if err != nil {
+		return nil, errors.Wrapf(err, "building query: %v", args)
+	}
+
+	if err := repo.SelectContext(ctx, &locked, q, args...); err != nil {
+		return nil, errors.Wrap(err, "locking columns")
+	}
+
+	return locked, nil
+}
+
+// positions returns the order of the tasks on the board in a column, leaving
+// out skip. Archived and deleted tasks keep their rank but are not on the board.
+func positions(ctx context.Context, repo *database.Repository, cid, skip string) ([]position, error) {
+	var ps []position
+
+	where := sq.And{sq.Eq{"column_id": cid, "archived": nil, "deleted": nil}}
+	if skip != "" {
+		where = append(where, sq.NotEq{"task_id": skip})
+	}
+
+	stmt := repo.SQ.Select(
+		"task_id",
+		"rank",
+	).From(
+		"tasks",
+	).Where(where).OrderBy("rank", "created")
+
+	q, args, err := stmt.ToSql()
+	if err != nil {
+		return nil, errors.Wrapf(err, "building query: %v", args)
+	}
+
+	if err := repo.SelectContext(ctx, &ps, q, args...); err != nil {
+		return nil, errors.Wrap(err, "selecting task positions")
+	}
+
+	return ps, nil
+}
+
+func setRank(ctx context.Context, repo *database.Repository, tid, cid, rank string) error {
+	stmt := repo.SQ.Update(
+		"tasks",
+	).SetMap(map[string]interface{}{
+		"column_id": cid,
+		"rank":      rank,
+		"version":   sq.Expr("version + 1"),
+	}).Where(sq.Eq{"task_id": tid})
+
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return errors.Wrapf(err, "ranking task %s", tid)
+	}
+
+	return nil
+}
+
+func contains(ids []string, id string) bool {
+	for i := range ids {
+		if ids[i] == id {
+			return true
+		}
+	}
+	return false
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// lock locks the task row for the rest of the unit of work and checks it is
+// at one of versions. It returns the locked task.
+func lock(ctx context.Context, repo *database.Repository, pid, tid string, versions database.Versions) (*Task, error) {
+	t, err := retrieve(ctx, repo, pid, tid, "FOR UPDATE")
+	if err != nil {
+		return nil, err
+	}
+
+	if !versions.Match(t.Version) {
+		return nil, ErrConflict
+	}
+
+	return t, nil
+}
+
+// checkAssignee verifies an assignee is an accepted member of the project.
+func checkAssignee(ctx context.Context, repo *database.Repository, pid string, uid *string) error {
+	if uid == nil {
+		return nil
+	}
+
+	stmt := repo.SQ.Select(
+		"count(*)",
+	).From(
+		"project_members",
+	).Where(sq.Eq{"project_id": pid, "user_id": *uid, "accepted": true})
+
+	q, args, err := stmt.ToSql()
+	if err != nil {
+		return errors.Wrapf(err, "building query: %v", args)
+	}
+
+	var n int
+	if err := repo.GetContext(ctx, &n, q, args...); err != nil {
+		return errors.Wrap(err, "looking for assignee")
+	}
+	if n == 0 {
+		return ErrInvalidAssignee
+	}
+
+	return nil
+}
+
+// setLabels replaces the labels of a task. Every label must belong to the project.
+func setLabels(ctx context.Context, repo *database.Repository, pid, tid string, lids []string) error {
+	if len(lids) > 0 {
+		stmt := repo.SQ.Select(
+			"count(*)",
+		).From(
+			"labels",
+		).Where(sq.Eq{"project_id": pid, "label_id": lids})
+
+		q, args, err := stmt.ToSql()
+		if err != nil {
+			return errors.Wrapf(err, "building query: %v", args)
+		}
+
+		var n int
+		if err := repo.GetContext(ctx, &n, q, args...); err != nil {
+			return errors.Wrap(err, "looking for labels")
+		}
+		if n != len(unique(lids)) {
+			return ErrInvalidLabel
+		}
+	}
+
+	del := repo.SQ.Delete("task_labels").Where(sq.Eq{"task_id": tid})
+	if _, err := del.ExecContext(ctx); err != nil {
+		return errors.Wrapf(err, "clearing labels of task %s", tid)
+	}
+
+	for _, lid := range unique(lids) {
+		ins := repo.SQ.Insert(
+			"task_labels",
+		).SetMap(map[string]interface{}{
+			"task_id":  tid,
+			"label_id": lid,
+		})
+
+		if _, err := ins.ExecContext(ctx); err != nil {
+			return errors.Wrapf(err, "labeling task %s", tid)
+		}
+	}
+
+	return nil
+}
+
+func unique(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	out := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+// utc returns t in UTC. Due dates are stored without a time zone, the offset
+// of a date in another zone would otherwise be dropped.
+func utc(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	u := t.UTC()
+	return &u
+}
+
+// SortKeys translates a sort parameter into the keys tasks are listed by.
+// Ties, and tasks without a value for the sorted field, fall back to creation
+// order. The values of a Task for the keys are those of Key.
+func SortKeys(sort string) ([]database.Key, error) {
+	field, desc := sortField(sort)
+
+	col, ok := sortColumns[field]
+	if !ok {
+		return nil, ErrInvalidSort
+	}
+
+	if field == "rank" {
+		return []database.Key{{Expr: "column_id"}, {Expr: col, Desc: desc}, {Expr: "created"}, {Expr: "task_id"}}, nil
+	}
+	return []database.Key{{Expr: col, Desc: desc, Nullable: true}, {Expr: "created"}, {Expr: "task_id"}}, nil
+}
+
+// sortField splits a sort parameter into the sorted field and its direction.
+func sortField(sort string) (string, bool) {
+	if sort == "" {
+		return "rank", false
+	}
+	if strings.HasPrefix(sort, "-") {
+		return sort[1:], true
+	}
+	return sort, false
+}
+
+// Key returns the values of the task for the SortKeys of sort, which must be
+// valid.
+func (t Task) Key(sort string) []interface{} {
+	field, _ := sortField(sort)
+
+	var v interface{}
+	switch field {
+	case "rank":
+		return []interface{}{t.ColumnID, t.Rank, t.Created, t.ID}
+	case "created":
+		v = t.Created
+	case "title":
+		v = t.Title
+	case "dueDate":
+		if t.DueDate != nil {
+			v = *t.DueDate
+		}
+	case "priority":
+		// Positions start at 1 like those of array_position.
+		for i, p := range []string{PriorityLow, PriorityMedium, PriorityHigh, PriorityUrgent} {
+			if t.Priority != nil && *t.Priority == p {
+				v = i + 1
+			}
+		}
+	case "estimate":
+		if t.Estimate != nil {
+			v = *t.Estimate
+		}
+	}
+	return []interface{}{v, t.Created, t.ID}
+}
@@ -0,0 +1,109 @@
+package task
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ivorscott/devpie-client-backend-go/internal/platform/database"
+)
+
+func TestCheckMove(t *testing.T) {
+	dest := []position{{ID: "b", Rank: "i"}, {ID: "c", Rank: "r"}}
+
+	tests := []struct {
+		name string
+		cid  string
+		mt   MoveTask
+		err  error
+	}{
+		{"end of column", "todo", MoveTask{From: "todo", To: "done", Index: 2}, nil},
+		{"current view", "todo", MoveTask{From: "todo", To: "done", TaskIds: []string{"b", "c"}}, nil},
+		{"stale view", "todo", MoveTask{From: "todo", To: "done", TaskIds: []string{"c", "b"}}, ErrOrderChanged},
+		{"stale view of empty column", "todo", MoveTask{From: "todo", To: "done", TaskIds: []string{}}, ErrOrderChanged},
+		{"stale source column", "done", MoveTask{From: "todo", To: "done"}, ErrOrderChanged},
+		{"index out of range", "todo", MoveTask{From: "todo", To: "done", Index: 3}, ErrInvalidIndex},
+	}
+
+	for _, tt := range tests {
+		if err := checkMove(tt.cid, dest, tt.mt); err != tt.err {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestUTC(t *testing.T) {
+	if utc(nil) != nil {
+		t.Error("utc(nil): got a time")
+	}
+
+	due := time.Date(2020, 6, 1, 10, 0, 0, 0, time.FixedZone("", 2*60*60))
+	got := utc(&due)
+	if !got.Equal(due) || got.Location() != time.UTC {
+		t.Errorf("utc(%v): got %v", due, got)
+	}
+}
+
+func TestSortKeys(t *testing.T) {
+	tests := []struct {
+		sort string
+		want []database.Key
+	}{
+		{"", []database.Key{{Expr: "column_id"}, {Expr: "rank"}, {Expr: "created"}, {Expr: "task_id"}}},
+		{"-rank", []database.Key{{Expr: "column_id"}, {Expr: "rank", Desc: true}, {Expr: "created"}, {Expr: "task_id"}}},
+		{"title", []database.Key{{Expr: "title", Nullable: true}, {Expr: "created"}, {Expr: "task_id"}}},
+		{"-dueDate", []database.Key{{Expr: "due_date", Desc: true, Nullable: true}, {Expr: "created"}, {Expr: "task_id"}}},
+	}
+
+	for _, tt := range tests {
+		got, err := SortKeys(tt.sort)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SortKeys(%q): got %v, %v, want %v", tt.sort, got, err, tt.want)
+		}
+	}
+
+	// Only whitelisted fields reach the query.
+	for _, sort := range []string{"color", "-", "--title", "due_date", "title; DROP TABLE tasks"} {
+		if _, err := SortKeys(sort); err != ErrInvalidSort {
+			t.Errorf("SortKeys(%q): got %v, want %v", sort, err, ErrInvalidSort)
+		}
+	}
+}
+
+func TestKey(t *testing.T) {
+	high := PriorityHigh
+	tk := Task{ID: "t1", ColumnID: "c1", Rank: "b", Priority: &high, Created: time.Unix(0, 0)}
+
+	tests := []struct {
+		sort string
+		want []interface{}
+	}{
+		{"", []interface{}{"c1", "b", tk.Created, "t1"}},
+		{"-priority", []interface{}{3, tk.Created, "t1"}},
+		{"dueDate", []interface{}{nil, tk.Created, "t1"}},
+	}
+
+	for _, tt := range tests {
+		if got := tk.Key(tt.sort); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Key(%q): got %v, want %v", tt.sort, got, tt.want)
+		}
+	}
+}
+
+func TestListInvalidFilter(t *testing.T) {
+	// Invalid filters are rejected before the database is queried.
+	repo := &database.Repository{}
+
+	for _, f := range []Filter{
+		{AssigneeID: "jane"},
+		{LabelID: "bug"},
+		{ColumnID: "todo"},
+		{Priority: "critical"},
+		{Sort: "color"},
+	} {
+		if _, err := List(context.Background(), repo, "5cf37266-3473-4006-984f-9325122678b7", f, database.Page{}); err == nil {
+			t.Errorf("filter %+v: got no error", f)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package template
+
+// DefaultID is the template used when a project is created without one.
+const DefaultID = "kanban"
+
+// builtin templates are available to every user and aren't stored.
+var builtin = []Template{
+	{
+		ID:      DefaultID,
+		Name:    "Kanban",
+		Builtin: true,
+		Columns: Columns{
+			{Title: "To Do"},
+			{Title: "In Progress"},
+			{Title: "Review"},
+			{Title: "Done"},
+		},
+	},
+	{
+		ID:      "support-triage",
+		Name:    "Support Triage",
+		Builtin: true,
+		Columns: Columns{
+			{Title: "New"},
+			{Title: "Triaged"},
+			{Title: "Waiting On Customer"},
+			{Title: "Resolved"},
+		},
+	},
+	{
+		ID:      "hiring-pipeline",
+		Name:    "Hiring Pipeline",
+		Builtin: true,
+		Columns: Columns{
+			{Title: "Applied"},
+			{Title: "Phone Screen"},
+			{Title: "Interview"},
+			{Title: "Offer"},
+			{Title: "Hired"},
+		},
+	},
+	{
+		ID:      "release-checklist",
+		Name:    "Release Checklist",
+		Builtin: true,
+		Columns: Columns{
+			{
+				Title: "To Do",
+				Tasks: []Task{
+					{Title: "Freeze the release branch"},
+					{Title: "Update the changelog"},
+					{Title: "Run the full test suite"},
+					{Title: "Tag the release"},
+					{Title: "Announce the release"},
+				},
+			},
+			{Title: "In Progress"},
+			{Title: "Done"},
+		},
+	},
+}
@@ -0,0 +1,58 @@
+package template
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Template describes the columns, and optionally the starter tasks, a new
+// project board is created with. Built-in templates have no owner.
+type Template struct {
+	ID      string    `db:"template_id" json:"id"`
+	UserID  *string   `db:"user_id" json:"userId"`
+	Name    string    `db:"name" json:"name"`
+	Columns Columns   `db:"columns" json:"columns"`
+	Builtin bool      `db:"-" json:"builtin"`
+	Created time.Time `db:"created" json:"created"`
+}
+
+type Column struct {
+	Title string `json:"title" validate:"required,max=36"`
+	Tasks []Task `json:"tasks" validate:"dive"`
+}
+
+type Task struct {
+	Title   string  `json:"title" validate:"required,max=48"`
+	Content *string `json:"content"`
+}
+
+// Columns is stored as a jsonb document.
+type Columns []Column
+
+// Value implements the driver.Valuer interface.
+func (c Columns) Value() (driver.Value, error) {
+	return json.Marshal(c)
+}
+
+// Scan implements the sql.Scanner interface.
+func (c *Columns) Scan(src interface{}) error {
+	b, ok := src.([]byte)
+	if !ok {
+		return errors.Errorf("scanning template columns: unexpected type %T", src)
+	}
+	return json.Unmarshal(b, c)
+}
+
+type NewTemplate struct {
+	Name    string   `json:"name" validate:"required,max=64"`
+	Columns []Column `json:"columns" validate:"required,min=1,dive"`
+}
+
+// SaveProject turns an existing project board into a template.
+type SaveProject struct {
+	Name         string `json:"name" validate:"required,max=64"`
+	IncludeTasks bool   `json:"includeTasks"`
+}
@@ -0,0 +1,123 @@
+package template
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/ivorscott/devpie-client-backend-go/internal/platform/database"
+	"github.com/pkg/errors"
+)
+
+// The Template package shouldn't know anything about http
+// While it may identify common know errors, how to respond is left to the handlers
+var (
+	ErrNotFound  = errors.New("template not found")
+	ErrInvalidID = errors.New("id provided was not a valid template id")
+)
+
+// List returns the built-in templates followed by the user's own templates.
+func List(ctx context.Context, repo *database.Repository, uid string) ([]Template, error) {
+	var ts []Template
+
+	stmt := repo.SQ.Select(
+		"template_id",
+		"user_id",
+		"name",
+		"columns",
+		"created",
+	).From("templates").Where(sq.Eq{"user_id": "?"}).OrderBy("created")
+
+	q, args, err := stmt.ToSql()
+	if err != nil {
+		return nil, errors.Wrapf(err, "building query: %v", args)
+	}
+
+	if err := repo.SelectContext(ctx, &ts, q, uid); err != nil {
+		return nil, errors.Wrap(err, "selecting templates")
+	}
+
+	return append(append([]Template{}, builtin...), ts...), nil
+}
+
+// Retrieve finds a built-in template by its id or one of the user's templates.
+func Retrieve(ctx context.Context, repo *database.Repository, id, uid string) (*Template, error) {
+	for i := range builtin {
+		if builtin[i].ID == id {
+			t := builtin[i]
+			return &t, nil
+		}
+	}
+
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrInvalidID
+	}
+
+	var t Template
+
+	stmt := repo.SQ.Select(
+		"template_id",
+		"user_id",
+		"name",
+		"columns",
+		"created",
+	).From(
+		"templates",
+	).Where(sq.Eq{"template_id": "?", "user_id": "?"})
+
+	q, args, err := stmt.ToSql()
+	if err != nil {
+		return nil, errors.Wrapf(err, "building query: %v", args)
+	}
+
+	if err := repo.GetContext(ctx, &t, q, id, uid); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+// Create adds a new Template owned by the user.
+func Create(ctx context.Context, repo *database.Repository, nt NewTemplate, uid string, now time.Time) (*Template, error) {
+	t := Template{
+		ID:      uuid.New().String(),
+		UserID:  &uid,
+		Name:    nt.Name,
+		Columns: nt.Columns,
+		Created: now.UTC(),
+	}
+
+	stmt := repo.SQ.Insert(
+		"templates",
+	).SetMap(map[string]interface{}{
+		"template_id": t.ID,
+		"user_id":     t.UserID,
+		"name":        t.Name,
+		"columns":     t.Columns,
+		"created":     t.Created,
+	})
+
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return nil, errors.Wrapf(err, "inserting template: %v", nt)
+	}
+
+	return &t, nil
+}
+
+// DeleteAll removes the templates of the user uid.
+func DeleteAll(ctx context.Context, repo *database.Repository, uid string) error {
+	stmt := repo.SQ.Delete(
+		"templates",
+	).Where(sq.Eq{"user_id": uid})
+
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return errors.Wrapf(err, "deleting all templates")
+	}
+
+	return nil
+}
@@ -0,0 +1,74 @@
+package template
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestBuiltin(t *testing.T) {
+	ids := make(map[string]bool)
+	for _, tm := range builtin {
+		if ids[tm.ID] {
+			t.Errorf("template %q: id used twice", tm.ID)
+		}
+		ids[tm.ID] = true
+
+		if !tm.Builtin || tm.UserID != nil {
+			t.Errorf("template %q: got builtin %v and owner %v", tm.ID, tm.Builtin, tm.UserID)
+		}
+		// Built-in templates follow the rules of NewTemplate.
+		if tm.Name == "" || len(tm.Name) > 64 || len(tm.Columns) == 0 {
+			t.Errorf("template %q: invalid name or no columns", tm.ID)
+		}
+		for _, c := range tm.Columns {
+			if c.Title == "" || len(c.Title) > 36 {
+				t.Errorf("template %q: invalid column title %q", tm.ID, c.Title)
+			}
+		}
+	}
+
+	if !ids[DefaultID] {
+		t.Errorf("default template %q is not built in", DefaultID)
+	}
+}
+
+func TestRetrieveBuiltin(t *testing.T) {
+	ctx := context.Background()
+
+	// Built-in templates and invalid ids are resolved without the database.
+	tm, err := Retrieve(ctx, nil, DefaultID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tm.Name = "Changed"
+	if builtin[0].Name == "Changed" {
+		t.Error("retrieved template shares the built-in one")
+	}
+
+	if _, err := Retrieve(ctx, nil, "scrum", ""); err != ErrInvalidID {
+		t.Errorf("unknown id: got %v, want %v", err, ErrInvalidID)
+	}
+}
+
+func TestColumnsValue(t *testing.T) {
+	content := "first"
+	cs := Columns{{Title: "To Do", Tasks: []Task{{Title: "Plan", Content: &content}}}, {Title: "Done"}}
+
+	v, err := cs.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got Columns
+	if err := got.Scan(v); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, cs) {
+		t.Errorf("got %+v, want %+v", got, cs)
+	}
+
+	if err := got.Scan("text"); err == nil {
+		t.Error("scanning a string: got no error")
+	}
+}
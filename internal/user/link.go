@@ -0,0 +1,157 @@
+package user
+
+import (
+	"context"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/ivorscott/devpie-client-backend-go/internal/platform/database"
+	"github.com/pkg/errors"
+)
+
+// Provision creates the user of the Auth0ID aid, along with the link of the
+// user to the account of the identity provider. Provisioning a user that
+// exists returns it, created tells which it was.
+func Provision(ctx context.Context, repo *database.Repository, nu NewUser, aid string, now time.Time) (u *User, created bool, err error) {
+	ctx, err = repo.Begin(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	defer repo.Rollback(ctx)
+
+	nw := User{
+		ID:            uuid.New().String(),
+		Auth0ID:       aid,
+		Email:         nu.Email,
+		EmailVerified: nu.EmailVerified,
+		FirstName:     nu.FirstName,
+		LastName:      nu.LastName,
+		Picture:       nu.Picture,
+		Locale:        nu.Locale,
+		LinkStatus:    LinkPending,
+		Created:       now.UTC(),
+	}
+
+	stmt := repo.SQ.Insert(
+		"users",
+	).SetMap(map[string]interface{}{
+		"user_id":        nw.ID,
+		"auth0_id":       nw.Auth0ID,
+		"email":          nw.Email,
+		"email_verified": nw.EmailVerified,
+		"first_name":     nw.FirstName,
+		"last_name":      nw.LastName,
+		"picture":        nw.Picture,
+		"locale":         nw.Locale,
+		"link_status":    nw.LinkStatus,
+		"created":        nw.Created,
+	}).Suffix("ON CONFLICT (auth0_id) DO NOTHING")
+
+	res, err := stmt.ExecContext(ctx)
+	if err != nil {
+		return nil, false, errors.Wrapf(err, "inserting user: %v", nu)
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return nil, false, errors.Wrap(err, "inserting user")
+	} else if n == 0 {
+		u, err := RetrieveMeBySubject(ctx, repo, aid)
+		if err != nil {
+			return nil, false, err
+		}
+		return u, false, nil
+	}
+
+	link := repo.SQ.Insert(
+		"user_links",
+	).SetMap(map[string]interface{}{
+		"user_id":      nw.ID,
+		"auth0_id":     nw.Auth0ID,
+		"next_attempt": nw.Created,
+		"created":      nw.Created,
+	})
+
+	if _, err := link.ExecContext(ctx); err != nil {
+		return nil, false, errors.Wrapf(err, "inserting link of user %s", nw.ID)
+	}
+
+	if err := repo.Commit(ctx); err != nil {
+		return nil, false, err
+	}
+
+	return &nw, true, nil
+}
+
+// PendingLinks returns up to limit links due at now, the longest waiting
+// first. Links are retrieved without locking them, replicas can attempt the
+// same link.
+func PendingLinks(ctx context.Context, repo *database.Repository, now time.Time, limit int) ([]Link, error) {
+	var links []Link
+
+	stmt := repo.SQ.Select(
+		"user_id",
+		"auth0_id",
+		"attempts",
+		"last_error",
+		"next_attempt",
+		"created",
+	).From(
+		"user_links",
+	).Where(
+		sq.LtOrEq{"next_attempt": now.UTC()},
+	).OrderBy(
+		"next_attempt",
+	).Limit(uint64(limit))
+
+	q, args, err := stmt.ToSql()
+	if err != nil {
+		return nil, errors.Wrapf(err, "building query: %v", args)
+	}
+
+	if err := repo.SelectContext(ctx, &links, q, args...); err != nil {
+		return nil, errors.Wrap(err, "selecting pending links")
+	}
+
+	return links, nil
+}
+
+// Linked records that the link of the user uid succeeded, removing it from
+// the outbox.
+func Linked(ctx context.Context, repo *database.Repository, uid string) error {
+	ctx, err := repo.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer repo.Rollback(ctx)
+
+	del := repo.SQ.Delete("user_links").Where(sq.Eq{"user_id": uid})
+	if _, err := del.ExecContext(ctx); err != nil {
+		return errors.Wrapf(err, "deleting link of user %s", uid)
+	}
+
+	upd := repo.SQ.Update("users").Set("link_status", LinkLinked).Where(sq.Eq{"user_id": uid})
+	if _, err := upd.ExecContext(ctx); err != nil {
+		return errors.Wrapf(err, "updating link status of user %s", uid)
+	}
+
+	return repo.Commit(ctx)
+}
+
+// LinkFailed records a failed attempt to link the user uid, scheduling the
+// next attempt at next.
+func LinkFailed(ctx context.Context, repo *database.Repository, uid, reason string, next time.Time) error {
+	stmt := repo.SQ.Update(
+		"user_links",
+	).SetMap(map[string]interface{}{
+		"attempts":     sq.Expr("attempts + 1"),
+		"last_error":   reason,
+		"next_attempt": next.UTC(),
+	}).Where(sq.Eq{"user_id": uid})
+
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return errors.Wrapf(err, "recording failed link of user %s", uid)
+	}
+
+	return nil
+}
@@ -0,0 +1,174 @@
+package user
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Memory is a Store keeping users in memory.
+type Memory struct {
+	mu    sync.Mutex
+	users map[string]User
+	links map[string]Link
+}
+
+// NewMemory returns an empty Memory store.
+func NewMemory() *Memory {
+	return &Memory{users: make(map[string]User), links: make(map[string]Link)}
+}
+
+func (s *Memory) RetrieveMeById(ctx context.Context, uid string) (*User, error) {
+	if _, err := uuid.Parse(uid); err != nil {
+		return nil, ErrInvalidID
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[uid]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return &u, nil
+}
+
+func (s *Memory) RetrieveMeBySubject(ctx context.Context, aid string) (*User, error) {
+	return s.find(func(u User) bool { return u.Auth0ID == aid })
+}
+
+func (s *Memory) RetrieveByEmail(ctx context.Context, email string) (*User, error) {
+	return s.find(func(u User) bool { return u.Email == email })
+}
+
+func (s *Memory) Create(ctx context.Context, nu NewUser, aid string, now time.Time) (*User, error) {
+	u := User{
+		ID:            uuid.New().String(),
+		Auth0ID:       aid,
+		Email:         nu.Email,
+		EmailVerified: nu.EmailVerified,
+		FirstName:     nu.FirstName,
+		LastName:      nu.LastName,
+		Picture:       nu.Picture,
+		Locale:        nu.Locale,
+		LinkStatus:    LinkLinked,
+		Created:       now.UTC(),
+	}
+
+	s.mu.Lock()
+	s.users[u.ID] = u
+	s.mu.Unlock()
+
+	return &u, nil
+}
+
+func (s *Memory) Update(ctx context.Context, uid string, uu UpdateUser) (*User, error) {
+	if _, err := uuid.Parse(uid); err != nil {
+		return nil, ErrInvalidID
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[uid]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	u = uu.apply(u)
+	s.users[uid] = u
+
+	return &u, nil
+}
+
+func (s *Memory) Provision(ctx context.Context, nu NewUser, aid string, now time.Time) (*User, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, u := range s.users {
+		if u.Auth0ID == aid {
+			return &u, false, nil
+		}
+	}
+
+	u := User{
+		ID:            uuid.New().String(),
+		Auth0ID:       aid,
+		Email:         nu.Email,
+		EmailVerified: nu.EmailVerified,
+		FirstName:     nu.FirstName,
+		LastName:      nu.LastName,
+		Picture:       nu.Picture,
+		Locale:        nu.Locale,
+		LinkStatus:    LinkPending,
+		Created:       now.UTC(),
+	}
+	s.users[u.ID] = u
+	s.links[u.ID] = Link{UserID: u.ID, Auth0ID: aid, NextAttempt: u.Created, Created: u.Created}
+
+	return &u, true, nil
+}
+
+func (s *Memory) PendingLinks(ctx context.Context, now time.Time, limit int) ([]Link, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var links []Link
+	for _, l := range s.links {
+		if !l.NextAttempt.After(now) {
+			links = append(links, l)
+		}
+	}
+
+	sort.Slice(links, func(i, j int) bool { return links[i].NextAttempt.Before(links[j].NextAttempt) })
+	if len(links) > limit {
+		links = links[:limit]
+	}
+
+	return links, nil
+}
+
+func (s *Memory) Linked(ctx context.Context, uid string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.links, uid)
+	if u, ok := s.users[uid]; ok {
+		u.LinkStatus = LinkLinked
+		s.users[uid] = u
+	}
+
+	return nil
+}
+
+func (s *Memory) LinkFailed(ctx context.Context, uid, reason string, next time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if l, ok := s.links[uid]; ok {
+		l.Attempts++
+		l.LastError = &reason
+		l.NextAttempt = next.UTC()
+		s.links[uid] = l
+	}
+
+	return nil
+}
+
+// find returns the first user matching match.
+func (s *Memory) find(match func(User) bool) (*User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, u := range s.users {
+		if match(u) {
+			return &u, nil
+		}
+	}
+
+	return nil, ErrNotFound
+}
@@ -14,9 +14,27 @@ type User struct {
 	LastName      *string   `db:"last_name" json:"lastName"`
 	Picture       *string   `db:"picture" json:"picture"`
 	Locale        *string   `db:"locale" json:"locale"`
+	LinkStatus    string    `db:"link_status" json:"linkStatus"`
 	Created       time.Time `db:"created" json:"created"`
 }
 
+// Statuses of the link of a user to the account of the identity provider.
+const (
+	LinkPending = "pending"
+	LinkLinked  = "linked"
+)
+
+// Link is a link of a user to the account of the identity provider waiting
+// in the outbox until the provider accepts it.
+type Link struct {
+	UserID      string    `db:"user_id" json:"userId"`
+	Auth0ID     string    `db:"auth0_id" json:"auth0Id"`
+	Attempts    int       `db:"attempts" json:"attempts"`
+	LastError   *string   `db:"last_error" json:"lastError"`
+	NextAttempt time.Time `db:"next_attempt" json:"nextAttempt"`
+	Created     time.Time `db:"created" json:"created"`
+}
+
 type NewUser struct {
 	Auth0ID       string  `json:"auth0Id" `
 	Email         string  `json:"email"`
@@ -27,9 +45,28 @@ type NewUser struct {
 	Locale        *string `json:"locale"`
 }
 
+// UpdateUser changes the profile of a user. Omitted fields are left as they
+// are.
 type UpdateUser struct {
-	FirstName *string `json:"firstName"`
-	LastName  *string `json:"lastName"`
-	Picture   *string `json:"picture"`
-	Locale    *string `json:"locale"`
+	FirstName *string `json:"firstName" validate:"omitempty,max=255"`
+	LastName  *string `json:"lastName" validate:"omitempty,max=255"`
+	Picture   *string `json:"picture" validate:"omitempty,url,max=255"`
+	Locale    *string `json:"locale" validate:"omitempty,max=8"`
+}
+
+// apply returns u with the fields of uu that are set.
+func (uu UpdateUser) apply(u User) User {
+	if uu.FirstName != nil {
+		u.FirstName = uu.FirstName
+	}
+	if uu.LastName != nil {
+		u.LastName = uu.LastName
+	}
+	if uu.Picture != nil {
+		u.Picture = uu.Picture
+	}
+	if uu.Locale != nil {
+		u.Locale = uu.Locale
+	}
+	return u
 }
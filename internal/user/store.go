@@ -0,0 +1,71 @@
+package user
+
+import (
+	"context"
+	"time"
+
+	"github.com/ivorscott/devpie-client-backend-go/internal/platform/database"
+)
+
+// Store persists users. Postgres is the implementation used by the API,
+// Memory stands in for it in tests.
+type Store interface {
+	RetrieveMeById(ctx context.Context, uid string) (*User, error)
+	RetrieveMeBySubject(ctx context.Context, aid string) (*User, error)
+	RetrieveByEmail(ctx context.Context, email string) (*User, error)
+	Create(ctx context.Context, nu NewUser, aid string, now time.Time) (*User, error)
+	Update(ctx context.Context, uid string, uu UpdateUser) (*User, error)
+
+	// The outbox of links to the accounts of the identity provider, see
+	// Provision.
+	Provision(ctx context.Context, nu NewUser, aid string, now time.Time) (*User, bool, error)
+	PendingLinks(ctx context.Context, now time.Time, limit int) ([]Link, error)
+	Linked(ctx context.Context, uid string) error
+	LinkFailed(ctx context.Context, uid, reason string, next time.Time) error
+}
+
+// Postgres is the Store backed by the repository's database.
+type Postgres struct {
+	repo *database.Repository
+}
+
+// NewPostgres returns a Store keeping users in the repository's database.
+func NewPostgres(repo *database.Repository) *Postgres {
+	return &Postgres{repo: repo}
+}
+
+func (s *Postgres) RetrieveMeById(ctx context.Context, uid string) (*User, error) {
+	return RetrieveMeById(ctx, s.repo, uid)
+}
+
+func (s *Postgres) RetrieveMeBySubject(ctx context.Context, aid string) (*User, error) {
+	return RetrieveMeBySubject(ctx, s.repo, aid)
+}
+
+func (s *Postgres) RetrieveByEmail(ctx context.Context, email string) (*User, error) {
+	return RetrieveByEmail(ctx, s.repo, email)
+}
+
+func (s *Postgres) Create(ctx context.Context, nu NewUser, aid string, now time.Time) (*User, error) {
+	return Create(ctx, s.repo, nu, aid, now)
+}
+
+func (s *Postgres) Update(ctx context.Context, uid string, uu UpdateUser) (*User, error) {
+	return Update(ctx, s.repo, uid, uu)
+}
+
+func (s *Postgres) Provision(ctx context.Context, nu NewUser, aid string, now time.Time) (*User, bool, error) {
+	return Provision(ctx, s.repo, nu, aid, now)
+}
+
+func (s *Postgres) PendingLinks(ctx context.Context, now time.Time, limit int) ([]Link, error) {
+	return PendingLinks(ctx, s.repo, now, limit)
+}
+
+func (s *Postgres) Linked(ctx context.Context, uid string) error {
+	return Linked(ctx, s.repo, uid)
+}
+
+func (s *Postgres) LinkFailed(ctx context.Context, uid, reason string, next time.Time) error {
+	return LinkFailed(ctx, s.repo, uid, reason, next)
+}
@@ -14,8 +14,8 @@ import (
 // The User package shouldn't know anything about http
 // While it may identify common know errors, how to respond is left to the handlers
 var (
-	ErrNotFound       = errors.New("user not found")
-	ErrInvalidID      = errors.New("id provided was not a valid UUID")
+	ErrNotFound  = errors.New("user not found")
+	ErrInvalidID = errors.New("id provided was not a valid UUID")
 )
 
 // Retrieve finds the User identified by a given Auth0ID.
@@ -35,6 +35,7 @@ func RetrieveMeById(ctx context.Context, repo *database.Repository, uid string)
 		"email_verified",
 		"locale",
 		"picture",
+		"link_status",
 		"created",
 	).From(
 		"users",
@@ -45,7 +46,7 @@ func RetrieveMeById(ctx context.Context, repo *database.Repository, uid string)
 		return nil, errors.Wrapf(err, "building query: %v", args)
 	}
 
-	if err := repo.DB.GetContext(ctx, &u, q, uid); err != nil {
+	if err := repo.GetContext(ctx, &u, q, uid); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, ErrNotFound
 		}
@@ -68,6 +69,7 @@ func RetrieveMeBySubject(ctx context.Context, repo *database.Repository, aid str
 		"email_verified",
 		"locale",
 		"picture",
+		"link_status",
 		"created",
 	).From(
 		"users",
@@ -78,7 +80,41 @@ func RetrieveMeBySubject(ctx context.Context, repo *database.Repository, aid str
 		return nil, errors.Wrapf(err, "building query: %v", args)
 	}
 
-	if err := repo.DB.GetContext(ctx, &u, q, aid); err != nil {
+	if err := repo.GetContext(ctx, &u, q, aid); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &u, nil
+}
+
+// RetrieveByEmail finds the User identified by a given email address.
+func RetrieveByEmail(ctx context.Context, repo *database.Repository, email string) (*User, error) {
+	var u User
+
+	stmt := repo.SQ.Select(
+		"user_id",
+		"auth0_id",
+		"email",
+		"first_name",
+		"last_name",
+		"email_verified",
+		"locale",
+		"picture",
+		"link_status",
+		"created",
+	).From(
+		"users",
+	).Where(sq.Eq{"email": "?"})
+
+	q, args, err := stmt.ToSql()
+	if err != nil {
+		return nil, errors.Wrapf(err, "building query: %v", args)
+	}
+
+	if err := repo.GetContext(ctx, &u, q, email); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, ErrNotFound
 		}
@@ -100,6 +136,7 @@ func Create(ctx context.Context, repo *database.Repository, nu NewUser, aid stri
 		LastName:      nu.LastName,
 		Picture:       nu.Picture,
 		Locale:        nu.Locale,
+		LinkStatus:    LinkLinked,
 		Created:       now.UTC(),
 	}
 
@@ -123,3 +160,51 @@ func Create(ctx context.Context, repo *database.Repository, nu NewUser, aid stri
 
 	return &u, nil
 }
+
+// Update modifies the profile of the User identified by uid and returns it.
+func Update(ctx context.Context, repo *database.Repository, uid string, uu UpdateUser) (*User, error) {
+	u, err := RetrieveMeById(ctx, repo, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	up := uu.apply(*u)
+
+	stmt := repo.SQ.Update(
+		"users",
+	).SetMap(map[string]interface{}{
+		"first_name": up.FirstName,
+		"last_name":  up.LastName,
+		"picture":    up.Picture,
+		"locale":     up.Locale,
+	}).Where(sq.Eq{"user_id": uid})
+
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return nil, errors.Wrapf(err, "updating user %s", uid)
+	}
+
+	return &up, nil
+}
+
+// Delete removes the User identified by uid along with its pending link.
+// Everything else referencing the user must be removed first.
+func Delete(ctx context.Context, repo *database.Repository, uid string) error {
+	if _, err := uuid.Parse(uid); err != nil {
+		return ErrInvalidID
+	}
+
+	stmt := repo.SQ.Delete(
+		"users",
+	).Where(sq.Eq{"user_id": uid})
+
+	res, err := stmt.ExecContext(ctx)
+	if err != nil {
+		return errors.Wrapf(err, "deleting user %s", uid)
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}